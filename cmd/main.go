@@ -5,12 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"gitmerge/internal/git"
+	"gitmerge/internal/session"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type ErrorStr struct {
@@ -271,13 +275,18 @@ func main() {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
-// todo: não deixar global
-var globalControl *git.Control
+// Tempo sem uso depois do qual uma sessão é descartada
+const sessionTTL = 2 * time.Hour
 
-func main() {
+// Cookie que identifica a sessão do navegador
+const sessionCookie = "gitmerge_session"
+
+// sessions guarda o repositório, as branches e as resoluções pendentes de cada
+// pessoa ou workspace usando o servidor
+var sessions = session.NewRegistry(sessionTTL)
 
-	globalControl = new(git.Control)
-	globalControl.Init()
+func main() {
+	sessions.StartExpiration(time.Minute, nil)
 
 	fs := http.FileServer(http.Dir("static"))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
@@ -299,6 +308,15 @@ func main() {
 	http.HandleFunc("/git/branchs", gitBranchHandler)
 	http.HandleFunc("/git/changes", getChanges)
 	http.HandleFunc("/git/diff", getDiff)
+	http.HandleFunc("/git/conflicts", getConflicts)
+	http.HandleFunc("/git/treeconflicts", getTreeConflicts)
+	http.HandleFunc("/git/mergebase", getMergeBase)
+	http.HandleFunc("/git/plan", getMergePlan)
+	http.HandleFunc("/git/resolve", gitResolveHandler)
+	http.HandleFunc("/git/save", gitSaveHandler)
+	http.HandleFunc("/git/rerere", gitRerereHandler)
+	http.HandleFunc("/git/commit", gitCommitHandler)
+	http.HandleFunc("/git/session", gitSessionHandler)
 
 	log.Println("Servidor rodando em http://localhost:8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
@@ -318,11 +336,35 @@ func setError(w http.ResponseWriter, err error) {
 	w.Write(data)
 }
 
-// getChanges retorna a lista de arquivos modificados entre duas branches
-func getChanges(w http.ResponseWriter, r *http.Request) {
-	setJsonHeaders(w)
+// sessionID identifica a sessão da requisição. O parâmetro workspace, com o
+// id de uma sessão existente, permite que várias pessoas compartilhem a mesma
+// sessão; sem ele, vale o cabeçalho X-Session-Id e, por fim, o cookie da
+// sessão do navegador. Os ids são gerados pelo servidor.
+func sessionID(r *http.Request) string {
+	if workspace := r.URL.Query().Get("workspace"); workspace != "" {
+		return workspace
+	}
+
+	if id := r.Header.Get("X-Session-Id"); id != "" {
+		return id
+	}
 
-	if !globalControl.IsInitialized() {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+// currentControl retorna a sessão da requisição e o repositório aberto nela.
+// Se nenhum repositório foi aberto, escreve o erro e retorna ok = false.
+func currentControl(w http.ResponseWriter, r *http.Request) (current *session.Session, control *git.Control, ok bool) {
+	current, found := sessions.Get(sessionID(r))
+	if found {
+		control = current.Control()
+	}
+
+	if control == nil {
 		setError(
 			w,
 			errors.Join(
@@ -330,6 +372,83 @@ func getChanges(w http.ResponseWriter, r *http.Request) {
 				fmt.Errorf("please, use the endpoint /git/branchs?dir=/absolute/path"),
 			),
 		)
+		return nil, nil, false
+	}
+
+	return current, control, true
+}
+
+// autoResolveOptions lê a resolução automática pedida no parâmetro
+// autoresolve. Se o valor for inválido, escreve o erro e retorna false.
+func autoResolveOptions(w http.ResponseWriter, r *http.Request) (git.AutoResolveOptions, bool) {
+	opts, err := git.ParseAutoResolve(r.URL.Query().Get("autoresolve"))
+	if err != nil {
+		setError(w, err)
+		return git.AutoResolveOptions{}, false
+	}
+	return opts, true
+}
+
+// renameOptions lê a detecção de renomeações pedida no parâmetro renames. Se
+// o valor for inválido, escreve o erro e retorna false.
+func renameOptions(w http.ResponseWriter, r *http.Request) (git.RenameOptions, bool) {
+	opts, err := git.ParseRenameOptions(r.URL.Query().Get("renames"))
+	if err != nil {
+		setError(w, err)
+		return git.RenameOptions{}, false
+	}
+	return opts, true
+}
+
+// diffOptions lê as diferenças ignoradas pedidas no parâmetro ignore. Se o
+// valor for inválido, escreve o erro e retorna false.
+func diffOptions(w http.ResponseWriter, r *http.Request) (git.DiffOptions, bool) {
+	opts, err := git.ParseDiffOptions(r.URL.Query().Get("ignore"))
+	if err != nil {
+		setError(w, err)
+		return git.DiffOptions{}, false
+	}
+	return opts, true
+}
+
+// gitSessionHandler retorna o resumo da sessão atual (repositório, branches e
+// arquivos resolvidos aguardando commit). Com DELETE, encerra a sessão.
+//
+//	Exemplo: http://localhost:8080/git/session
+func gitSessionHandler(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	id := sessionID(r)
+	current, found := sessions.Get(id)
+	if !found {
+		setError(w, fmt.Errorf("session not found"))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		sessions.Delete(id)
+		data, _ := json.Marshal(map[string]string{"status": "ok"})
+		_, _ = w.Write(data)
+		return
+	}
+
+	data, _ := json.Marshal(current.Info())
+	_, _ = w.Write(data)
+}
+
+// getChanges retorna a lista de arquivos modificados entre duas branches,
+// incluindo os que têm conflito de árvore (ver /git/treeconflicts).
+// O parâmetro compare, aceito também por /git/diff, /git/conflicts e
+// /git/resolve, escolhe a comparação: tips (padrão), com a ponta da base, ou
+// mergebase, com o ancestral comum. O parâmetro renames, aceito pelos mesmos
+// endpoints e por /git/save, /git/treeconflicts, /git/plan e /git/commit,
+// controla a detecção de renomeações: on (padrão), off, copies e o limiar de
+// similaridade em porcentagem, como 60, separados por vírgula.
+func getChanges(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	current, control, ok := currentControl(w, r)
+	if !ok {
 		return
 	}
 
@@ -345,8 +464,21 @@ func getChanges(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	mode, err := git.ParseCompareMode(r.URL.Query().Get("compare"))
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	renames, ok := renameOptions(w, r)
+	if !ok {
+		return
+	}
+
+	current.SelectBranches(baseBranch, yourBranch)
+
 	// Retorna apenas a lista de arquivos modificados
-	list, err := globalControl.GetModifiedFiles(yourBranch, baseBranch)
+	list, err := control.GetModifiedFiles(yourBranch, baseBranch, mode, renames)
 	if err != nil {
 		setError(w, err)
 		return
@@ -356,16 +488,33 @@ func getChanges(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(data)
 }
 
-// getDiff retorna o diff de um arquivo específico com marcadores de conflito
+// getDiff retorna o diff de um arquivo específico com marcadores de conflito.
+// O parâmetro style escolhe o formato dos marcadores: merge (padrão), diff3 ou zdiff3.
+// O parâmetro autoresolve controla a resolução automática de blocos triviais:
+// on (padrão), off, whitespace ou adjacent, separados por vírgula. Alterações
+// vizinhas dos dois lados só são combinadas com adjacent.
+// O parâmetro ignore lista, separadas por vírgula, as diferenças ignoradas na
+// comparação das linhas: cr-at-eol, space-at-eol, all-space e blank-lines.
+// O parâmetro renames escolhe a detecção de renomeações, como em /git/changes.
+// Arquivos binários não têm diff de texto: a resposta é o JSON de
+// /git/conflicts, com o cabeçalho X-Binary, e a versão é escolhida em /git/resolve.
+// O mesmo JSON vem para os conflitos de árvore (modify/delete, rename/rename...),
+// com o cabeçalho X-Tree-Conflict trazendo o tipo; no add/add o conteúdo
+// também é mesclado e a resposta é o diff, com o mesmo cabeçalho. Submódulos
+// respondem o JSON com o cabeçalho X-Submodule e o commit é escolhido em
+// /git/resolve.
+// Arquivos com modos diferentes nos dois lados (regular, executable ou
+// symlink) trazem os cabeçalhos X-Mode-Ours, X-Mode-Theirs e
+// X-Mode-Resolution; o modo conta como um conflito até ser escolhido em
+// /git/resolve com mode-ours ou mode-theirs.
+// Os cabeçalhos X-Strategy e X-Generated trazem a estratégia de merge e a
+// marcação de arquivo gerado definidas nos .gitattributes.
+//
+//	Exemplo: http://localhost:8080/git/diff?yourBranch=feature&baseBranch=main&file=main.go&style=diff3
+//	Exemplo: http://localhost:8080/git/diff?yourBranch=feature&baseBranch=main&file=main.go&ignore=cr-at-eol,space-at-eol
 func getDiff(w http.ResponseWriter, r *http.Request) {
-	if !globalControl.IsInitialized() {
-		setError(
-			w,
-			errors.Join(
-				fmt.Errorf("no git control found"),
-				fmt.Errorf("please, use the endpoint /git/branchs?dir=/absolute/path"),
-			),
-		)
+	_, control, ok := currentControl(w, r)
+	if !ok {
 		return
 	}
 
@@ -387,22 +536,789 @@ func getDiff(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Obtém o diff do arquivo específico
-	diffMap, err := globalControl.DiffSpecificFile(yourBranch, baseBranch, file)
+	mode, err := git.ParseCompareMode(r.URL.Query().Get("compare"))
 	if err != nil {
 		setError(w, err)
 		return
 	}
 
-	// Retorna o conteúdo do diff como texto simples
-	diff, exists := diffMap[file]
-	if !exists {
-		setError(w, fmt.Errorf("file not found in diff"))
+	style, err := git.ParseConflictStyle(r.URL.Query().Get("style"))
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	auto, ok := autoResolveOptions(w, r)
+	if !ok {
+		return
+	}
+	ignore, ok := diffOptions(w, r)
+	if !ok {
+		return
+	}
+	renames, ok := renameOptions(w, r)
+	if !ok {
+		return
+	}
+
+	// Faz o merge do arquivo específico
+	merge, err := control.MergeFile(yourBranch, baseBranch, file, mode, renames, ignore, auto)
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	// Estratégia e arquivos gerados vêm dos .gitattributes das duas branches
+	w.Header().Set("X-Strategy", merge.Strategy)
+	w.Header().Set("X-Generated", strconv.FormatBool(merge.Generated))
+
+	if merge.Tree != nil {
+		w.Header().Set("X-Tree-Conflict", merge.Tree.Kind)
+	}
+
+	if merge.Submodule != nil {
+		w.Header().Set("X-Submodule", "true")
+	}
+
+	if merge.Mode != nil {
+		w.Header().Set("X-Mode-Ours", merge.Mode.Ours)
+		w.Header().Set("X-Mode-Theirs", merge.Mode.Theirs)
+		w.Header().Set("X-Mode-Resolution", merge.Mode.Resolution)
+	}
+
+	if merge.Binary != nil || merge.Submodule != nil || (merge.Tree != nil && merge.Tree.Kind != git.TreeAddAdd) {
+		setJsonHeaders(w)
+		w.Header().Set("X-Binary", strconv.FormatBool(merge.Binary != nil))
+		w.Header().Set("X-Auto-Resolved", strconv.Itoa(merge.AutoResolved))
+		w.Header().Set("X-Conflicts", strconv.Itoa(merge.Conflicts))
+		data, _ := json.Marshal(merge)
+		_, _ = w.Write(data)
 		return
 	}
 
+	// Retorna o conteúdo do diff como texto simples; as contagens de blocos
+	// resolvidos automaticamente, com resoluções gravadas e pendentes vão
+	// nos cabeçalhos
 	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(diff))
+	w.Header().Set("X-Auto-Resolved", strconv.Itoa(merge.AutoResolved))
+	w.Header().Set("X-Replayed", strconv.Itoa(merge.Replayed))
+	w.Header().Set("X-Conflicts", strconv.Itoa(merge.Conflicts))
+	w.Write([]byte(merge.Render(style)))
+}
+
+// getConflicts retorna o merge de um arquivo como trechos estáveis e blocos em
+// conflito, cada bloco com id, as versões ours/base/theirs e as faixas de linhas.
+// Blocos resolvidos automaticamente vêm com a resolução preenchida e são
+// contados em autoResolved; os parâmetros autoresolve, ignore e renames
+// funcionam como em /git/diff.
+//
+//	Exemplo: http://localhost:8080/git/conflicts?yourBranch=feature&baseBranch=main&file=main.go
+func getConflicts(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	_, control, ok := currentControl(w, r)
+	if !ok {
+		return
+	}
+
+	yourBranch := r.URL.Query().Get("yourBranch")
+	if yourBranch == "" {
+		setError(w, fmt.Errorf("yourBranch not provided"))
+		return
+	}
+
+	baseBranch := r.URL.Query().Get("baseBranch")
+	if baseBranch == "" {
+		setError(w, fmt.Errorf("baseBranch not provided"))
+		return
+	}
+
+	file := r.URL.Query().Get("file")
+	if file == "" {
+		setError(w, fmt.Errorf("file not provided"))
+		return
+	}
+
+	mode, err := git.ParseCompareMode(r.URL.Query().Get("compare"))
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	auto, ok := autoResolveOptions(w, r)
+	if !ok {
+		return
+	}
+	ignore, ok := diffOptions(w, r)
+	if !ok {
+		return
+	}
+	renames, ok := renameOptions(w, r)
+	if !ok {
+		return
+	}
+
+	merge, err := control.MergeFile(yourBranch, baseBranch, file, mode, renames, ignore, auto)
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	data, _ := json.Marshal(merge)
+	_, _ = w.Write(data)
+}
+
+// getTreeConflicts retorna os conflitos de árvore entre duas branches: arquivos
+// alterados de um lado e removidos do outro, criados nos dois lados,
+// renomeados para caminhos diferentes, renomeados de um lado e removidos do
+// outro, e arquivos no lugar de diretórios. Cada conflito traz as resoluções
+// aceitas em choices, escolhidas em /git/resolve.
+//
+//	Exemplo: http://localhost:8080/git/treeconflicts?yourBranch=feature&baseBranch=main
+func getTreeConflicts(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	_, control, ok := currentControl(w, r)
+	if !ok {
+		return
+	}
+
+	yourBranch := r.URL.Query().Get("yourBranch")
+	if yourBranch == "" {
+		setError(w, fmt.Errorf("yourBranch not provided"))
+		return
+	}
+
+	baseBranch := r.URL.Query().Get("baseBranch")
+	if baseBranch == "" {
+		setError(w, fmt.Errorf("baseBranch not provided"))
+		return
+	}
+
+	renames, ok := renameOptions(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := control.TreeConflicts(yourBranch, baseBranch, renames)
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	if list == nil {
+		list = []git.TreeConflict{}
+	}
+
+	data, _ := json.Marshal(list)
+	_, _ = w.Write(data)
+}
+
+// getMergeBase retorna os hashes dos ancestrais comuns entre duas branches.
+// Em históricos cruzados (criss-cross) a lista tem mais de um hash.
+//
+//	Exemplo: http://localhost:8080/git/mergebase?yourBranch=feature&baseBranch=main
+func getMergeBase(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	_, control, ok := currentControl(w, r)
+	if !ok {
+		return
+	}
+
+	yourBranch := r.URL.Query().Get("yourBranch")
+	if yourBranch == "" {
+		setError(w, fmt.Errorf("yourBranch not provided"))
+		return
+	}
+
+	baseBranch := r.URL.Query().Get("baseBranch")
+	if baseBranch == "" {
+		setError(w, fmt.Errorf("baseBranch not provided"))
+		return
+	}
+
+	list, err := control.MergeBase(yourBranch, baseBranch)
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	data, _ := json.Marshal(&list)
+	_, _ = w.Write(data)
+}
+
+// getMergePlan retorna o plano de merge de várias branches de teste sobre uma
+// base: arquivos alterados por branch (matriz branch × arquivo), pares de
+// branches que se sobrepõem e a ordem de merge sugerida.
+//
+//	Exemplo: http://localhost:8080/git/plan?baseBranch=main&branches=feature-a,feature-b,feature-c
+func getMergePlan(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	_, control, ok := currentControl(w, r)
+	if !ok {
+		return
+	}
+
+	baseBranch := r.URL.Query().Get("baseBranch")
+	if baseBranch == "" {
+		setError(w, fmt.Errorf("baseBranch not provided"))
+		return
+	}
+
+	var branches []string
+	for _, name := range strings.Split(r.URL.Query().Get("branches"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			branches = append(branches, name)
+		}
+	}
+	if len(branches) == 0 {
+		setError(w, fmt.Errorf("branches not provided"))
+		return
+	}
+
+	renames, ok := renameOptions(w, r)
+	if !ok {
+		return
+	}
+
+	plan, err := control.PlanOctopusMerge(baseBranch, branches, renames)
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	data, _ := json.Marshal(plan)
+	_, _ = w.Write(data)
+}
+
+// gitResolveHandler aplica no servidor decisões de resolução por bloco de
+// conflito (ids de /git/conflicts) e retorna o arquivo atualizado e quantos
+// conflitos restam. As decisões ficam no histórico do repositório e, quando
+// não resta conflito, o arquivo é guardado para o commit como em /git/save.
+// Com GET, retorna o histórico de decisões do arquivo.
+//
+//	Exemplo: POST http://localhost:8080/git/resolve?yourBranch=feature&baseBranch=main&file=main.go
+//	Corpo:   {"decisions": [{"hunk": "414f5858cf57", "resolution": "both-ours-first"},
+//	          {"hunk": "9c1d2e3f4a5b", "resolution": "custom", "text": "..."},
+//	          {"hunk": "7a8b9c0d1e2f", "resolution": "inline",
+//	           "choices": [{"line": 0, "segment": 3, "side": "theirs"}]}]}
+//	Resoluções: ours, theirs, both-ours-first, both-theirs-first, base, custom, inline
+//
+// Com inline, cada trecho diferente das linhas comparadas em chunk.inline
+// (por palavra em words ou, com "chars": true, por caractere em chars) fica
+// com o lado escolhido; os trechos sem escolha ficam com ours.
+//
+// Em arquivos binários o hunk é ignorado e a resolução, ours ou theirs,
+// escolhe o arquivo inteiro; a resposta não traz content.
+//
+// Em conflitos de árvore o hunk é ignorado (no add/add, precisa vir vazio) e
+// a resolução é uma das tree.choices: keep, delete, ours, theirs ou
+// keep-both. Os arquivos que ficam e os que saem do resultado são guardados
+// para o commit e vêm em files e deleted.
+//
+// Em submódulos o hunk também é ignorado e a resolução escolhe o commit: ours,
+// theirs ou descendant, o commit que descende dos dois lados quando o
+// repositório do submódulo existe localmente (submodule.descendant).
+//
+// Com modos diferentes nos dois lados (mode), mode-ours e mode-theirs
+// escolhem o modo do arquivo, ignorando o hunk; o modo escolhido vale para o
+// commit junto com o conteúdo salvo.
+func gitResolveHandler(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	current, control, ok := currentControl(w, r)
+	if !ok {
+		return
+	}
+
+	file := r.URL.Query().Get("file")
+
+	if r.Method == http.MethodGet {
+		entries, err := control.DecisionLog(file)
+		if err != nil {
+			setError(w, err)
+			return
+		}
+
+		data, _ := json.Marshal(entries)
+		_, _ = w.Write(data)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	yourBranch := r.URL.Query().Get("yourBranch")
+	if yourBranch == "" {
+		setError(w, fmt.Errorf("yourBranch not provided"))
+		return
+	}
+
+	baseBranch := r.URL.Query().Get("baseBranch")
+	if baseBranch == "" {
+		setError(w, fmt.Errorf("baseBranch not provided"))
+		return
+	}
+
+	if file == "" {
+		setError(w, fmt.Errorf("file not provided"))
+		return
+	}
+
+	compare := r.URL.Query().Get("compare")
+	mode, err := git.ParseCompareMode(compare)
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	style, err := git.ParseConflictStyle(r.URL.Query().Get("style"))
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	var payload struct {
+		Decisions []git.HunkDecision `json:"decisions"`
+	}
+
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		setError(w, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+
+	auto, ok := autoResolveOptions(w, r)
+	if !ok {
+		return
+	}
+	ignore, ok := diffOptions(w, r)
+	if !ok {
+		return
+	}
+	renames, ok := renameOptions(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := fileMergeKey(r, control, yourBranch, baseBranch)
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	// Decisões aplicadas antes de uma inválida continuam valendo e vão para o histórico
+	now := time.Now()
+	var entries []git.DecisionLogEntry
+	var applyErr error
+	load := func() (*git.FileMerge, error) {
+		return control.MergeFile(yourBranch, baseBranch, file, mode, renames, ignore, auto)
+	}
+	merge, err := current.UpdateFileMerge(file, key, load, func(merge *git.FileMerge) {
+		for _, decision := range payload.Decisions {
+			if applyErr = merge.Apply(decision); applyErr != nil {
+				return
+			}
+
+			entries = append(entries, git.DecisionLogEntry{
+				Time:         now,
+				Session:      current.ID,
+				YourBranch:   yourBranch,
+				BaseBranch:   baseBranch,
+				File:         file,
+				HunkDecision: decision,
+			})
+		}
+	})
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	if err := control.LogDecisions(entries); err != nil {
+		setError(w, err)
+		return
+	}
+
+	if applyErr != nil {
+		setError(w, applyErr)
+		return
+	}
+
+	if merge.Tree != nil && merge.Tree.Resolution != "" {
+		result, err := control.TreeResult(merge)
+		if err != nil {
+			setError(w, err)
+			return
+		}
+
+		kept := make([]string, 0, len(result.Files)+len(result.Submodules))
+		for name, content := range result.Files {
+			current.SaveResolution(name, content)
+			current.SaveMode(name, result.Modes[name])
+			kept = append(kept, name)
+		}
+		for name, commit := range result.Submodules {
+			current.SaveSubmodule(name, commit)
+			kept = append(kept, name)
+		}
+		for _, name := range result.Deleted {
+			current.SaveDeletion(name)
+		}
+		sort.Strings(kept)
+
+		data, _ := json.Marshal(map[string]any{
+			"file":      file,
+			"conflicts": merge.Conflicts,
+			"saved":     true,
+			"tree":      merge.Tree,
+			"files":     kept,
+			"deleted":   result.Deleted,
+		})
+		_, _ = w.Write(data)
+		return
+	}
+
+	if merge.Tree != nil && merge.Tree.Kind != git.TreeAddAdd {
+		data, _ := json.Marshal(map[string]any{
+			"file":      file,
+			"conflicts": merge.Conflicts,
+			"saved":     false,
+			"tree":      merge.Tree,
+		})
+		_, _ = w.Write(data)
+		return
+	}
+
+	// O modo escolhido vale também para o conteúdo salvo depois pelo editor
+	if merge.Mode != nil {
+		if mode, err := control.FileMode(merge); err == nil {
+			current.SaveMode(file, mode)
+		}
+	}
+
+	if merge.Submodule != nil {
+		saved := merge.Conflicts == 0
+		if saved {
+			commit, err := control.SubmoduleCommit(merge)
+			if err != nil {
+				setError(w, err)
+				return
+			}
+			current.SaveSubmodule(file, commit)
+		}
+
+		data, _ := json.Marshal(map[string]any{
+			"file":      file,
+			"conflicts": merge.Conflicts,
+			"saved":     saved,
+			"submodule": merge.Submodule,
+		})
+		_, _ = w.Write(data)
+		return
+	}
+
+	if merge.Binary != nil {
+		saved := merge.Conflicts == 0
+		if saved {
+			content, err := control.BinaryContent(merge)
+			if err != nil {
+				setError(w, err)
+				return
+			}
+			current.SaveResolution(file, content)
+		}
+
+		data, _ := json.Marshal(map[string]any{
+			"file":      file,
+			"conflicts": merge.Conflicts,
+			"saved":     saved,
+			"binary":    merge.Binary,
+			"mode":      merge.Mode,
+		})
+		_, _ = w.Write(data)
+		return
+	}
+
+	content := merge.Render(style)
+
+	saved := merge.Conflicts == 0
+	if saved {
+		current.SaveResolution(file, content)
+		recordResolutions(control, merge, content)
+	}
+
+	data, _ := json.Marshal(map[string]any{
+		"file":      file,
+		"content":   content,
+		"conflicts": merge.Conflicts,
+		"saved":     saved,
+		"chunks":    merge.Chunks,
+		"mode":      merge.Mode,
+	})
+	_, _ = w.Write(data)
+}
+
+// fileMergeKey identifica a comparação que gerou o merge em resolução de um
+// arquivo: as branches, os commits para onde apontam e os parâmetros
+// compare, autoresolve, ignore e renames. Uma branch que avança gera outra chave.
+func fileMergeKey(r *http.Request, control *git.Control, yourBranch, baseBranch string) (string, error) {
+	yourTip, err := control.ResolveRevision(yourBranch)
+	if err != nil {
+		return "", err
+	}
+	baseTip, err := control.ResolveRevision(baseBranch)
+	if err != nil {
+		return "", err
+	}
+
+	query := r.URL.Query()
+	return strings.Join([]string{
+		yourBranch, yourTip, baseBranch, baseTip, query.Get("compare"), query.Get("autoresolve"), query.Get("ignore"), query.Get("renames"),
+	}, "\x00"), nil
+}
+
+// recordResolutions grava, para reuso (rerere), as resoluções dos blocos em
+// conflito do merge em que o arquivo foi resolvido. Falhas não impedem o
+// salvamento e apenas vão para o log. Retorna quantas resoluções foram
+// gravadas.
+func recordResolutions(control *git.Control, merge *git.FileMerge, content string) int {
+	recorded, err := control.RecordResolutions(merge, content)
+	if err != nil {
+		log.Printf("rerere: %v", err)
+	}
+
+	return recorded
+}
+
+// gitRerereHandler lista as resoluções gravadas para reuso (rerere).
+// Com DELETE, esquece a resolução de um conflito (fingerprint) ou todas as
+// de um arquivo (file).
+//
+//	Exemplo: http://localhost:8080/git/rerere?file=main.go
+//	Exemplo: DELETE http://localhost:8080/git/rerere?fingerprint=3f2a...
+func gitRerereHandler(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	_, control, ok := currentControl(w, r)
+	if !ok {
+		return
+	}
+
+	file := r.URL.Query().Get("file")
+	fingerprint := r.URL.Query().Get("fingerprint")
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := control.RecordedResolutions(file)
+		if err != nil {
+			setError(w, err)
+			return
+		}
+
+		data, _ := json.Marshal(list)
+		_, _ = w.Write(data)
+
+	case http.MethodDelete:
+		var fingerprints []string
+		if fingerprint != "" {
+			fingerprints = append(fingerprints, fingerprint)
+		} else if file != "" {
+			list, err := control.RecordedResolutions(file)
+			if err != nil {
+				setError(w, err)
+				return
+			}
+			for _, recorded := range list {
+				fingerprints = append(fingerprints, recorded.Fingerprint)
+			}
+		} else {
+			setError(w, fmt.Errorf("fingerprint or file not provided"))
+			return
+		}
+
+		for _, fp := range fingerprints {
+			if err := control.ForgetResolution(fp); err != nil {
+				setError(w, err)
+				return
+			}
+		}
+
+		data, _ := json.Marshal(map[string]any{"status": "ok", "forgotten": len(fingerprints)})
+		_, _ = w.Write(data)
+
+	default:
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+	}
+}
+
+// gitSaveHandler guarda o conteúdo resolvido de um arquivo até o commit de merge.
+// As resoluções dos blocos em conflito entre yourBranch e baseBranch (ou as
+// branches selecionadas na sessão) são gravadas para reuso (rerere). Os blocos
+// são os do merge em resolução em /git/resolve com os mesmos parâmetros
+// compare, autoresolve, ignore e renames, como em /git/diff.
+//
+//	Exemplo: POST http://localhost:8080/git/save?file=internal/git/git.go&yourBranch=feature&baseBranch=main
+//	Corpo:   {"content": "..."}
+func gitSaveHandler(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	current, control, ok := currentControl(w, r)
+	if !ok {
+		return
+	}
+
+	file := r.URL.Query().Get("file")
+	if file == "" {
+		setError(w, fmt.Errorf("file not provided"))
+		return
+	}
+
+	var payload struct {
+		Content string `json:"content"`
+	}
+
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		setError(w, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+
+	mode, err := git.ParseCompareMode(r.URL.Query().Get("compare"))
+	if err != nil {
+		setError(w, err)
+		return
+	}
+	ignore, ok := diffOptions(w, r)
+	if !ok {
+		return
+	}
+	renames, ok := renameOptions(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := autoResolveOptions(w, r)
+	if !ok {
+		return
+	}
+
+	pending := current.SaveResolution(file, payload.Content)
+
+	info := current.Info()
+	yourBranch := r.URL.Query().Get("yourBranch")
+	if yourBranch == "" {
+		yourBranch = info.YourBranch
+	}
+	baseBranch := r.URL.Query().Get("baseBranch")
+	if baseBranch == "" {
+		baseBranch = info.BaseBranch
+	}
+
+	recorded := 0
+	if yourBranch != "" && baseBranch != "" {
+		key, err := fileMergeKey(r, control, yourBranch, baseBranch)
+		var merge *git.FileMerge
+		if err == nil {
+			merge, err = current.FileMerge(file, key, func() (*git.FileMerge, error) {
+				return control.MergeFile(yourBranch, baseBranch, file, mode, renames, ignore, auto)
+			})
+		}
+		if err != nil {
+			log.Printf("rerere: %v", err)
+		} else {
+			recorded = recordResolutions(control, merge, payload.Content)
+		}
+	}
+
+	data, _ := json.Marshal(map[string]any{"status": "ok", "files": pending, "recorded": recorded})
+	_, _ = w.Write(data)
+}
+
+// gitCommitHandler grava os arquivos resolvidos e os commits escolhidos para
+// os submódulos em um novo commit de merge, sem os arquivos removidos na
+// resolução de conflitos de árvore.
+// O commit é recusado enquanto algum arquivo tiver marcadores de conflito ou
+// algum conflito não tiver sido resolvido. Os parâmetros ignore, autoresolve
+// e renames funcionam como em /git/diff no merge dos arquivos que não foram
+// resolvidos à mão.
+//
+//	Exemplo: POST http://localhost:8080/git/commit
+//	Corpo:   {"target": "test", "parents": ["test", "feature-a"], "message": "Merge feature-a",
+//	          "author": {"name": "Fulano", "email": "fulano@example.com"}}
+func gitCommitHandler(w http.ResponseWriter, r *http.Request) {
+	setJsonHeaders(w)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	current, control, ok := currentControl(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Target    string        `json:"target"`
+		Parents   []string      `json:"parents"`
+		Message   string        `json:"message"`
+		Author    git.Signature `json:"author"`
+		Committer git.Signature `json:"committer"`
+	}
+
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		setError(w, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+
+	ignore, ok := diffOptions(w, r)
+	if !ok {
+		return
+	}
+	renames, ok := renameOptions(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := autoResolveOptions(w, r)
+	if !ok {
+		return
+	}
+
+	opts := git.MergeCommitOptions{
+		Target:      payload.Target,
+		Parents:     payload.Parents,
+		Files:       current.Resolutions(),
+		Submodules:  current.Submodules(),
+		Deleted:     current.Deletions(),
+		Modes:       current.Modes(),
+		Diff:        ignore,
+		AutoResolve: auto,
+		Renames:     renames,
+		Message:     payload.Message,
+		Author:      payload.Author,
+		Committer:   payload.Committer,
+	}
+
+	hash, err := control.CommitMerge(opts)
+	if err != nil {
+		setError(w, err)
+		return
+	}
+
+	current.ForgetResolutions(opts)
+
+	data, _ := json.Marshal(map[string]string{"status": "ok", "hash": hash})
+	_, _ = w.Write(data)
 }
 
 // gitBranchHandler Retorna a lista de todos os branchs do projeto.
@@ -423,12 +1339,41 @@ func gitBranchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := globalControl.NewRepoLocal(dir); err != nil {
+	id := sessionID(r)
+	current, found := sessions.Get(id)
+	if !found {
+		// Um id informado precisa existir; só o cookie de uma sessão
+		// expirada é trocado por uma sessão nova
+		if cookie, err := r.Cookie(sessionCookie); id != "" && (err != nil || cookie.Value != id) {
+			setError(w, fmt.Errorf("session not found"))
+			return
+		}
+
+		var err error
+		if current, err = sessions.Create(); err != nil {
+			setError(w, err)
+			return
+		}
+
+		// Sessões do navegador são lembradas por cookie; as demais recebem o
+		// id no cabeçalho X-Session-Id
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    current.ID,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		w.Header().Set("X-Session-Id", current.ID)
+	}
+
+	// Abrir o repositório descarta as resoluções pendentes do anterior
+	if err := current.Open(dir); err != nil {
 		setError(w, err)
 		return
 	}
 
-	list, err := globalControl.ListAllBranches()
+	list, err := current.Control().ListAllBranches()
 	if err != nil {
 		setError(w, err)
 		return
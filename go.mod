@@ -2,7 +2,10 @@ module gitmerge
 
 go 1.25
 
-require github.com/go-git/go-git/v5 v5.16.4
+require (
+	github.com/go-git/go-git/v5 v5.16.4
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	dario.cat/mergo v1.0.2 // indirect
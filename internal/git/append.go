@@ -0,0 +1,183 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// StrategyAppend é a estratégia de arquivos em que só se acrescentam linhas,
+// como CHANGELOG.md e listas de migrações: as linhas que os dois lados
+// acrescentaram no mesmo ponto ficam todas no resultado.
+const StrategyAppend = "append"
+
+func init() {
+	RegisterMergeDriver(StrategyAppend, NewAppendDriver(AppendOptions{}))
+}
+
+// AppendOptions configura a ordem das linhas acrescentadas pelos dois lados.
+type AppendOptions struct {
+	// SortKey separa as linhas acrescentadas em entradas: cada entrada começa
+	// em uma linha que casa com a expressão, como o título com a data de uma
+	// versão no CHANGELOG. As entradas são ordenadas pelo primeiro grupo da
+	// expressão, ou pelo trecho casado se ela não tiver grupos.
+	// Nil mantém as linhas de ours antes das de theirs.
+	SortKey *regexp.Regexp
+
+	// Descending ordena as entradas da maior chave para a menor, como as
+	// versões mais recentes no topo de um CHANGELOG.
+	Descending bool
+}
+
+// ParseAppendOptions lê as opções de merge.<nome>.sortkey e
+// merge.<nome>.sortorder ("asc" ou "desc") da configuração do repositório.
+func ParseAppendOptions(sortKey, sortOrder string) (AppendOptions, error) {
+	var opts AppendOptions
+
+	if sortKey != "" {
+		re, err := regexp.Compile(sortKey)
+		if err != nil {
+			return opts, fmt.Errorf("chave de ordenação inválida %s: %w", sortKey, err)
+		}
+		opts.SortKey = re
+	}
+
+	switch sortOrder {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, fmt.Errorf("ordem de ordenação desconhecida: %s", sortOrder)
+	}
+
+	return opts, nil
+}
+
+// NewAppendDriver cria o driver da StrategyAppend. Nos blocos em conflito em
+// que os dois lados só acrescentaram linhas, o resultado tem as linhas dos
+// dois, na ordem definida em opts. Blocos em que algum lado alterou ou
+// removeu linhas existentes continuam em conflito.
+func NewAppendDriver(opts AppendOptions) MergeDriver {
+	return MergeDriverFunc(func(input MergeInput) ([]Chunk, error) {
+		chunks, _ := textMerge(input)
+		for i := range chunks {
+			if chunks[i].Pending() {
+				resolveAppend(&chunks[i], opts)
+			}
+		}
+		return chunks, nil
+	})
+}
+
+// resolveAppend resolve um bloco em conflito em que os dois lados só
+// acrescentaram linhas às da base.
+func resolveAppend(chunk *Chunk, opts AppendOptions) {
+	base := sideLines(chunk.Base)
+
+	oursSlots, ok := insertions(base, sideLines(chunk.Ours))
+	if !ok {
+		return
+	}
+	theirsSlots, ok := insertions(base, sideLines(chunk.Theirs))
+	if !ok {
+		return
+	}
+
+	var lines []string
+	for i := range oursSlots {
+		lines = append(lines, appendLines(oursSlots[i], theirsSlots[i], opts)...)
+		if i < len(base) {
+			lines = append(lines, base[i])
+		}
+	}
+
+	chunk.Lines = lines
+	chunk.Resolution = ResolveAutoAppend
+}
+
+// insertions separa as linhas que side acrescentou às da base: o item i são
+// as linhas acrescentadas antes da linha i da base e o último as do fim.
+// Retorna false se side alterou ou removeu alguma linha da base.
+func insertions(base, side []string) ([][]string, bool) {
+	slots := make([][]string, len(base)+1)
+
+	i := 0
+	for _, line := range side {
+		if i < len(base) && line == base[i] {
+			i++
+			continue
+		}
+		slots[i] = append(slots[i], line)
+	}
+
+	return slots, i == len(base)
+}
+
+// appendLines junta as linhas que os dois lados acrescentaram no mesmo ponto.
+func appendLines(ours, theirs []string, opts AppendOptions) []string {
+	if opts.SortKey == nil {
+		if slices.Equal(ours, theirs) {
+			return ours
+		}
+		return append(append([]string(nil), ours...), theirs...)
+	}
+
+	type entry struct {
+		key   string
+		lines []string
+	}
+
+	var head []string
+	var entries []entry
+	seen := make(map[string]bool)
+	for _, side := range [][]string{ours, theirs} {
+		// Linhas antes da primeira entrada ficam no início, uma vez se os
+		// dois lados acrescentaram as mesmas
+		var lead []string
+		start := len(entries)
+		for _, line := range side {
+			match := opts.SortKey.FindStringSubmatch(line)
+			switch {
+			case match != nil:
+				key := match[0]
+				if len(match) > 1 {
+					key = match[1]
+				}
+				entries = append(entries, entry{key: key, lines: []string{line}})
+			case len(entries) > start:
+				entries[len(entries)-1].lines = append(entries[len(entries)-1].lines, line)
+			default:
+				lead = append(lead, line)
+			}
+		}
+		if !slices.Equal(lead, head) {
+			head = append(head, lead...)
+		}
+
+		// Entradas repetidas nos dois lados aparecem uma vez
+		kept := entries[:start]
+		for _, e := range entries[start:] {
+			text := strings.Join(e.lines, "\n")
+			if !seen[text] {
+				seen[text] = true
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if opts.Descending {
+			return entries[i].key > entries[j].key
+		}
+		return entries[i].key < entries[j].key
+	})
+
+	lines := head
+	for _, e := range entries {
+		lines = append(lines, e.lines...)
+	}
+	return lines
+}
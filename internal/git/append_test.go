@@ -0,0 +1,184 @@
+package git
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestAppendDriver(t *testing.T) {
+	version := regexp.MustCompile(`^## \[(\S+)\]`)
+
+	tests := []struct {
+		name               string
+		opts               AppendOptions
+		base, ours, theirs string
+		want               string
+		conflicts          int
+	}{
+		{
+			name:   "ours primeiro",
+			base:   "# Log\n",
+			ours:   "# Log\n- ours 1\n- ours 2\n",
+			theirs: "# Log\n- theirs\n",
+			want:   "# Log\n- ours 1\n- ours 2\n- theirs\n",
+		},
+		{
+			name:   "mesmas linhas nos dois lados",
+			base:   "a\n",
+			ours:   "a\nx\n",
+			theirs: "a\nx\n",
+			want:   "a\nx\n",
+		},
+		{
+			name:   "acréscimos em pontos diferentes do bloco",
+			base:   "a\nb\n",
+			ours:   "o\na\nb\nO\n",
+			theirs: "t\na\nb\nT\n",
+			want:   "o\nt\na\nb\nO\nT\n",
+		},
+		{
+			name:      "linha da base alterada continua em conflito",
+			base:      "a\nb\n",
+			ours:      "a\nB\nx\n",
+			theirs:    "a\nb\ny\n",
+			want:      "a\n<<<<<<< ours\nB\nx\n=======\nb\ny\n>>>>>>> theirs\n",
+			conflicts: 1,
+		},
+		{
+			name:   "entradas em ordem crescente",
+			opts:   AppendOptions{SortKey: version},
+			base:   "# Log\n",
+			ours:   "# Log\n## [1.3]\n- c\n## [1.1]\n- a\n",
+			theirs: "# Log\n## [1.2]\n- b\n",
+			want:   "# Log\n## [1.1]\n- a\n## [1.2]\n- b\n## [1.3]\n- c\n",
+		},
+		{
+			name:   "entradas em ordem decrescente",
+			opts:   AppendOptions{SortKey: version, Descending: true},
+			base:   "# Log\n\n## [1.0]\n",
+			ours:   "# Log\n\n## [1.1]\n- a\n## [1.0]\n",
+			theirs: "# Log\n\n## [1.2]\n- b\n## [1.0]\n",
+			want:   "# Log\n\n## [1.2]\n- b\n## [1.1]\n- a\n## [1.0]\n",
+		},
+		{
+			name:   "linhas antes da primeira entrada",
+			opts:   AppendOptions{SortKey: version},
+			base:   "x\n",
+			ours:   "x\nnota\n## [2]\n",
+			theirs: "x\nnota\n## [1]\n",
+			want:   "x\nnota\n## [1]\n## [2]\n",
+		},
+		{
+			name:   "chave sem grupo e entrada repetida",
+			opts:   AppendOptions{SortKey: regexp.MustCompile(`^\d+`)},
+			base:   "# x\n",
+			ours:   "# x\n2 b\n1 a\n",
+			theirs: "# x\n1 a\n3 c\n",
+			want:   "# x\n1 a\n2 b\n3 c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := NewAppendDriver(tt.opts).(MergeDriverFunc)
+			merge := driverMerge(t, driver, "CHANGELOG.md", tt.base, tt.ours, tt.theirs)
+			if got := merge.Render(ConflictMerge); got != tt.want {
+				t.Errorf("Render =\n%s\nesperado\n%s", got, tt.want)
+			}
+			if merge.Conflicts != tt.conflicts {
+				t.Errorf("conflitos = %d, esperado %d", merge.Conflicts, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestParseAppendOptions(t *testing.T) {
+	tests := []struct {
+		sortKey, sortOrder string
+		key                string
+		descending         bool
+		fails              bool
+	}{
+		{},
+		{sortKey: `^v(\d+)`, key: `^v(\d+)`},
+		{sortOrder: "asc"},
+		{sortKey: "x", sortOrder: "desc", key: "x", descending: true},
+		{sortKey: "(", fails: true},
+		{sortOrder: "aleatória", fails: true},
+	}
+
+	for _, tt := range tests {
+		opts, err := ParseAppendOptions(tt.sortKey, tt.sortOrder)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("ParseAppendOptions(%q, %q) não retornou erro", tt.sortKey, tt.sortOrder)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseAppendOptions(%q, %q): %v", tt.sortKey, tt.sortOrder, err)
+		}
+
+		key := ""
+		if opts.SortKey != nil {
+			key = opts.SortKey.String()
+		}
+		if key != tt.key || opts.Descending != tt.descending {
+			t.Errorf("ParseAppendOptions(%q, %q) = %q, %v", tt.sortKey, tt.sortOrder, key, opts.Descending)
+		}
+	}
+}
+
+func TestAppendConfig(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"CHANGELOG.md": "# Log\n", "a.log": "x\n", "b.log": "x\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"CHANGELOG.md": "# Log\n## [1.1]\n", "a.log": "x\nfeature\n", "b.log": "x\nfeature\n"})
+	repo.commit("main", map[string]string{"CHANGELOG.md": "# Log\n## [1.2]\n", "a.log": "x\nmain\n", "b.log": "x\nmain\n"})
+
+	cfg, err := repo.repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge := cfg.Raw.Section("merge")
+	merge.Subsection("changelog").
+		SetOption("path", "CHANGELOG.md").
+		SetOption("strategy", StrategyAppend).
+		SetOption("sortkey", `^## \[(\S+)\]`).
+		SetOption("sortorder", "desc")
+	merge.Subsection("logs").SetOption("path", "a.log").SetOption("strategy", StrategyAppend)
+	merge.Subsection("quebrado").SetOption("path", "b.log").SetOption("strategy", StrategyAppend).SetOption("sortorder", "x")
+	if err := repo.repo.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file   string
+		driver string
+		want   string
+		fails  bool
+	}{
+		{"CHANGELOG.md", "changelog", "# Log\n## [1.2]\n## [1.1]\n", false},
+		{"a.log", "logs", "x\nfeature\nmain\n", false},
+		{"b.log", "", "", true},
+	}
+
+	for _, tt := range tests {
+		merge, err := repo.control.MergeFile("feature", "main", tt.file, CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{Disabled: true})
+		if tt.fails {
+			if err == nil {
+				t.Fatalf("MergeFile(%s) aceitou uma configuração inválida", tt.file)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("MergeFile(%s): %v", tt.file, err)
+		}
+		if merge.Strategy != tt.driver {
+			t.Fatalf("MergeFile(%s): Strategy = %q, esperado %q", tt.file, merge.Strategy, tt.driver)
+		}
+		if got := merge.Render(ConflictMerge); got != tt.want || merge.Conflicts != 0 {
+			t.Fatalf("MergeFile(%s) = %q (%d conflitos), esperado %q", tt.file, got, merge.Conflicts, tt.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// attributesFile é o nome dos arquivos de atributos lidos das trees.
+const attributesFile = ".gitattributes"
+
+// builtinAttributes são as macros que o git define sem precisar de
+// .gitattributes.
+var builtinAttributes = []string{"[attr]binary -diff -merge -text"}
+
+// attributes lê os atributos dos .gitattributes de uma tree. Os arquivos são
+// lidos sob demanda, apenas nos diretórios do caminho consultado.
+type attributes struct {
+	tree  *object.Tree
+	files map[string][]gitattributes.MatchAttribute
+}
+
+// newAttributes cria o leitor de atributos de uma tree. Uma tree nil não tem
+// atributos.
+func newAttributes(tree *object.Tree) *attributes {
+	return &attributes{tree: tree, files: make(map[string][]gitattributes.MatchAttribute)}
+}
+
+// match retorna os atributos de um caminho. Os .gitattributes dos diretórios
+// mais próximos do arquivo têm prioridade, como no git.
+func (a *attributes) match(name string) (map[string]gitattributes.Attribute, error) {
+	var stack []gitattributes.MatchAttribute
+	for _, line := range builtinAttributes {
+		macro, err := gitattributes.ParseAttributesLine(line, nil, true)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler atributos padrão: %w", err)
+		}
+		stack = append(stack, macro)
+	}
+
+	dirs := []string{""}
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		dirs = append(dirs, strings.Join(parts[:i], "/"))
+	}
+
+	for _, dir := range dirs {
+		patterns, err := a.patterns(dir)
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, patterns...)
+	}
+
+	// Os padrões são aplicados do menos para o mais prioritário, cada atributo
+	// ficando com o último valor encontrado. Macros, como binary, valem como
+	// se os atributos delas estivessem na linha.
+	macros := make(map[string]gitattributes.MatchAttribute)
+	results := make(map[string]gitattributes.Attribute)
+	for _, entry := range stack {
+		if entry.Pattern == nil {
+			macros[entry.Name] = entry
+			continue
+		}
+		if !entry.Pattern.Match(parts) {
+			continue
+		}
+
+		for _, attr := range entry.Attributes {
+			if macro, ok := macros[attr.Name()]; ok && attr.IsSet() {
+				for _, expanded := range macro.Attributes {
+					results[expanded.Name()] = expanded
+				}
+			}
+			results[attr.Name()] = attr
+		}
+	}
+
+	return results, nil
+}
+
+// patterns lê o .gitattributes de um diretório da tree, vazio se não existir.
+func (a *attributes) patterns(dir string) ([]gitattributes.MatchAttribute, error) {
+	if a.tree == nil {
+		return nil, nil
+	}
+
+	if patterns, ok := a.files[dir]; ok {
+		return patterns, nil
+	}
+
+	fileName := path.Join(dir, attributesFile)
+	file, err := a.tree.File(fileName)
+	if errors.Is(err, object.ErrFileNotFound) {
+		a.files[dir] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %w", fileName, err)
+	}
+
+	content, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao obter conteúdo de %s: %w", fileName, err)
+	}
+
+	var domain []string
+	if dir != "" {
+		domain = strings.Split(dir, "/")
+	}
+
+	// Macros só podem ser definidas no .gitattributes da raiz
+	patterns, err := gitattributes.ReadAttributes(strings.NewReader(content), domain, dir == "")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %w", fileName, err)
+	}
+
+	a.files[dir] = patterns
+	return patterns, nil
+}
+
+// Estratégias de merge aceitas no atributo merge, além de binary e text
+const (
+	// StrategyUnion mantém as linhas dos dois lados nos blocos em conflito,
+	// primeiro as de ours.
+	StrategyUnion = "union"
+
+	// StrategyOurs mantém a versão de ours nos blocos em conflito.
+	StrategyOurs = "ours"
+)
+
+// mergeRules são as regras dos .gitattributes para o merge de um arquivo.
+type mergeRules struct {
+	// binary: binary, -diff, -merge ou merge=binary
+	binary bool
+
+	// strategy é o valor de merge=, como "union" ou "ours"; vazio usa o
+	// merge de texto
+	strategy string
+
+	// text: text, text=auto ou eol definidos; os fins de linha são
+	// normalizados para LF antes do merge
+	text bool
+
+	// generated: linguist-generated, arquivos gerados por ferramentas
+	generated bool
+}
+
+// rulesFor retorna as regras de merge de um caminho. Cada atributo vale como
+// definido na primeira fonte que o especifica, então a ordem das fontes é a
+// de prioridade.
+func rulesFor(name string, sources ...*attributes) (mergeRules, error) {
+	attrs := make(map[string]gitattributes.Attribute)
+	for i := len(sources) - 1; i >= 0; i-- {
+		matched, err := sources[i].match(name)
+		if err != nil {
+			return mergeRules{}, err
+		}
+		for key, attr := range matched {
+			if !attr.IsUnspecified() {
+				attrs[key] = attr
+			}
+		}
+	}
+
+	var rules mergeRules
+
+	if attr, ok := attrs["binary"]; ok && attr.IsSet() {
+		rules.binary = true
+	}
+	if attr, ok := attrs["diff"]; ok && attr.IsUnset() {
+		rules.binary = true
+	}
+
+	if attr, ok := attrs["merge"]; ok {
+		switch {
+		case attr.IsUnset(), attr.Value() == "binary":
+			rules.binary = true
+		case attr.IsValueSet() && attr.Value() != "text":
+			rules.strategy = attr.Value()
+		}
+	}
+
+	if attr, ok := attrs["text"]; ok {
+		rules.text = attr.IsSet() || attr.IsValueSet()
+	} else if attr, ok := attrs["eol"]; ok && attr.IsValueSet() {
+		rules.text = true
+	}
+
+	if attr, ok := attrs["linguist-generated"]; ok {
+		rules.generated = attr.IsSet() || attr.Value() == "true"
+	}
+
+	return rules, nil
+}
+
+// normalizeEOL converte os fins de linha CRLF em LF.
+func normalizeEOL(content string) string {
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
+
+// applyRules marca os arquivos gerados e, em arquivos binários, aplica a
+// estratégia merge=ours, mantendo a versão de ours. Nos demais arquivos a
+// estratégia é aplicada pelo driver de merge. Retorna se resolveu o conflito.
+func (m *FileMerge) applyRules(rules mergeRules) bool {
+	m.Generated = rules.generated
+
+	if m.Binary == nil {
+		return false
+	}
+
+	m.Strategy = rules.strategy
+	if rules.strategy != StrategyOurs || !m.Binary.pending() {
+		return false
+	}
+
+	m.Binary.Resolution = ResolveAutoKeepOurs
+	m.AutoResolved++
+	m.countPending()
+
+	return true
+}
@@ -0,0 +1,181 @@
+package git
+
+import "testing"
+
+// testAttributes cria o leitor de atributos de uma árvore com os arquivos
+// informados.
+func testAttributes(repo *testRepo, files map[string]string) *attributes {
+	repo.t.Helper()
+
+	tree, err := repo.repo.TreeObject(repo.tree(files, ""))
+	if err != nil {
+		repo.t.Fatalf("erro ao ler a árvore: %v", err)
+	}
+	return newAttributes(tree)
+}
+
+func TestRulesFor(t *testing.T) {
+	repo := newTestRepo(t)
+
+	root := testAttributes(repo, map[string]string{
+		".gitattributes": "[attr]gerado linguist-generated -diff\n" +
+			"*.png binary\n" +
+			"*.min.js -diff\n" +
+			"*.lock -merge\n" +
+			"*.bin merge=binary\n" +
+			"CHANGELOG.md merge=union\n" +
+			"*.po merge=ours\n" +
+			"*.txt text\n" +
+			"*.bat eol=crlf\n" +
+			"*.csv text=auto\n" +
+			"*.gen gerado\n" +
+			"api/*.go linguist-generated=true\n" +
+			"*.md merge=text\n",
+		"docs/.gitattributes": "*.txt -text merge=union\n",
+	})
+	other := testAttributes(repo, map[string]string{
+		".gitattributes": "*.po merge=union\n*.cfg merge=ours\n",
+	})
+
+	tests := []struct {
+		name    string
+		sources []*attributes
+		want    mergeRules
+	}{
+		{"a.png", []*attributes{root}, mergeRules{binary: true}},
+		{"dist/app.min.js", []*attributes{root}, mergeRules{binary: true}},
+		{"go.lock", []*attributes{root}, mergeRules{binary: true}},
+		{"x.bin", []*attributes{root}, mergeRules{binary: true}},
+		// *.md merge=text vem depois e prevalece sobre merge=union
+		{"CHANGELOG.md", []*attributes{root}, mergeRules{}},
+		{"pt.po", []*attributes{root}, mergeRules{strategy: StrategyOurs}},
+		{"a.txt", []*attributes{root}, mergeRules{text: true}},
+		{"run.bat", []*attributes{root}, mergeRules{text: true}},
+		{"dados.csv", []*attributes{root}, mergeRules{text: true}},
+		{"docs/a.txt", []*attributes{root}, mergeRules{strategy: StrategyUnion}},
+		{"x.gen", []*attributes{root}, mergeRules{binary: true, generated: true}},
+		{"api/a.go", []*attributes{root}, mergeRules{generated: true}},
+		{"api/sub/a.go", []*attributes{root}, mergeRules{}},
+		{"main.go", []*attributes{root}, mergeRules{}},
+
+		// A primeira fonte que define o atributo prevalece
+		{"pt.po", []*attributes{root, other}, mergeRules{strategy: StrategyOurs}},
+		{"pt.po", []*attributes{other, root}, mergeRules{strategy: StrategyUnion}},
+		{"a.cfg", []*attributes{root, other}, mergeRules{strategy: StrategyOurs}},
+
+		// Sem .gitattributes, nada muda
+		{"a.png", []*attributes{newAttributes(nil)}, mergeRules{}},
+	}
+
+	for _, tt := range tests {
+		got, err := rulesFor(tt.name, tt.sources...)
+		if err != nil {
+			t.Fatalf("rulesFor(%s): %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("rulesFor(%s) = %+v, esperado %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeEOL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"a\r\nb\r\n", "a\nb\n"},
+		{"a\nb", "a\nb"},
+		{"a\rb\r\n", "a\rb\n"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeEOL(tt.in); got != tt.want {
+			t.Errorf("normalizeEOL(%q) = %q, esperado %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	text := newFileMerge("a.txt", ConflictLabels{}, []Chunk{{
+		Conflict: true,
+		Ours:     &HunkSide{Lines: []string{"o"}},
+		Theirs:   &HunkSide{Lines: []string{"t"}},
+	}})
+
+	// Em arquivos de texto a estratégia fica com o driver de merge
+	if text.applyRules(mergeRules{strategy: StrategyOurs, generated: true}) || text.Conflicts != 1 {
+		t.Fatalf("applyRules resolveu um arquivo de texto: %+v", text.Chunks[0])
+	}
+	if !text.Generated {
+		t.Fatalf("linguist-generated não marcou o arquivo")
+	}
+
+	tests := []struct {
+		name     string
+		rules    mergeRules
+		resolved bool
+	}{
+		{"binário", mergeRules{binary: true}, false},
+		{"binário com merge=ours", mergeRules{binary: true, strategy: StrategyOurs}, true},
+		{"binário com merge=union", mergeRules{binary: true, strategy: StrategyUnion}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := &FileMerge{Binary: &BinaryConflict{Ours: &BinarySide{Hash: "o"}, Theirs: &BinarySide{Hash: "t"}}}
+			merge.countPending()
+
+			if resolved := merge.applyRules(tt.rules); resolved != tt.resolved {
+				t.Fatalf("applyRules = %v, esperado %v", resolved, tt.resolved)
+			}
+			if merge.Strategy != tt.rules.strategy {
+				t.Fatalf("Strategy = %q, esperado %q", merge.Strategy, tt.rules.strategy)
+			}
+			if tt.resolved && (merge.Conflicts != 0 || merge.Binary.chosen() != merge.Binary.Ours) {
+				t.Fatalf("o binário não ficou com ours: %+v", merge.Binary)
+			}
+			if !tt.resolved && merge.Conflicts != 1 {
+				t.Fatalf("conflitos = %d, esperado 1", merge.Conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeFileAttributes(t *testing.T) {
+	repo := newTestRepo(t)
+	attrs := "*.log merge=union\n*.txt text\n*.json linguist-generated\n"
+	repo.commit("main", map[string]string{".gitattributes": attrs, "a.log": "1\n", "b.txt": "a\r\nb\r\nc\r\n", "c.json": "{}\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{".gitattributes": attrs, "a.log": "1\nfeature\n", "b.txt": "A\nb\nc\n", "c.json": "{\"a\": 1}\n"})
+	repo.commit("main", map[string]string{".gitattributes": attrs, "a.log": "1\nmain\n", "b.txt": "a\r\nb\r\nC\r\n", "c.json": "{}\n"})
+
+	tests := []struct {
+		file      string
+		want      string
+		generated bool
+	}{
+		{"a.log", "1\nfeature\nmain\n", false},
+		{"b.txt", "A\nb\nC\n", false},
+		{"c.json", "{\"a\": 1}\n", true},
+	}
+
+	for _, tt := range tests {
+		merge, err := repo.control.MergeFile("feature", "main", tt.file, CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+		if err != nil {
+			t.Fatalf("MergeFile(%s): %v", tt.file, err)
+		}
+		if merge.Conflicts != 0 || merge.Generated != tt.generated {
+			t.Fatalf("MergeFile(%s): conflitos = %d, Generated = %v", tt.file, merge.Conflicts, merge.Generated)
+		}
+		if got := merge.Render(ConflictMerge); got != tt.want {
+			t.Fatalf("MergeFile(%s) = %q, esperado %q", tt.file, got, tt.want)
+		}
+	}
+
+	changes, err := repo.control.GetFileChanges("feature", "main", CompareMergeBase, RenameOptions{})
+	if err != nil {
+		t.Fatalf("GetFileChanges: %v", err)
+	}
+	for _, change := range changes {
+		if change.Generated != (change.Path == "c.json") {
+			t.Fatalf("GetFileChanges marcou %s com Generated = %v", change.Path, change.Generated)
+		}
+	}
+}
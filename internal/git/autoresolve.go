@@ -0,0 +1,263 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Resoluções aplicadas pelo AutoResolve
+const (
+	// ResolveAutoOurs: apenas ours alterou o bloco em relação à base.
+	ResolveAutoOurs = "auto-ours"
+
+	// ResolveAutoTheirs: apenas theirs alterou o bloco em relação à base.
+	ResolveAutoTheirs = "auto-theirs"
+
+	// ResolveAutoIdentical: os dois lados chegaram ao mesmo conteúdo.
+	ResolveAutoIdentical = "auto-identical"
+
+	// ResolveAutoCombined: os dois lados alteraram linhas diferentes do
+	// bloco, sem sobreposição, e as duas alterações foram aplicadas. Só é
+	// usada com AutoResolveOptions.Adjacent.
+	ResolveAutoCombined = "auto-combined"
+
+	// ResolveAutoUnion: o arquivo tem merge=union nos .gitattributes e o
+	// bloco ficou com as linhas dos dois lados.
+	ResolveAutoUnion = "auto-union"
+
+	// ResolveAutoKeepOurs: o arquivo tem merge=ours nos .gitattributes e o
+	// bloco ficou com a versão de ours.
+	ResolveAutoKeepOurs = "auto-keep-ours"
+
+	// ResolveAutoImports: os dois lados alteraram os imports de um arquivo
+	// Go e o bloco ficou com a união deles, ordenada.
+	ResolveAutoImports = "auto-imports"
+
+	// ResolveAutoVersion: os dois lados alteraram dependências do go.mod e
+	// o bloco ficou com a maior versão de cada módulo.
+	ResolveAutoVersion = "auto-version"
+
+	// ResolveAutoAppend: o arquivo usa a estratégia append e os dois lados
+	// só acrescentaram linhas, mantidas todas no bloco.
+	ResolveAutoAppend = "auto-append"
+
+	// ResolveAutoDescendant: os dois lados alteraram o commit de um
+	// submódulo e um deles descende do outro, que foi avançado até ele.
+	ResolveAutoDescendant = "auto-descendant"
+)
+
+// AutoResolveOptions configura a resolução automática de blocos triviais.
+type AutoResolveOptions struct {
+	// Disabled deixa todos os blocos em conflito para resolução manual,
+	// inclusive os que têm resolução gravada (rerere).
+	Disabled bool
+
+	// Whitespace considera iguais linhas que diferem apenas em espaços,
+	// como `git merge -Xignore-space-change`.
+	Whitespace bool
+
+	// Adjacent aplica as alterações dos dois lados quando mudam linhas
+	// diferentes do mesmo bloco, mesmo vizinhas. Desligado por padrão, pois
+	// o git trata alterações vizinhas como conflito.
+	Adjacent bool
+}
+
+// ParseAutoResolve converte os nomes usados na API, separados por vírgula,
+// em AutoResolveOptions. Aceita "on", "off", "whitespace" (on também
+// ignorando espaços) e "adjacent" (on também combinando alterações vizinhas);
+// vazio resulta em "on".
+func ParseAutoResolve(names string) (AutoResolveOptions, error) {
+	var opts AutoResolveOptions
+	if names == "" {
+		return opts, nil
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "on":
+		case "off":
+			opts.Disabled = true
+		case "whitespace":
+			opts.Whitespace = true
+		case "adjacent":
+			opts.Adjacent = true
+		default:
+			return AutoResolveOptions{}, fmt.Errorf("modo de resolução automática desconhecido: %s", name)
+		}
+	}
+
+	return opts, nil
+}
+
+// resolveKnown resolve os blocos triviais e depois os que têm resolução
+// gravada de merges anteriores, conforme opts.
+func (e *Control) resolveKnown(merge *FileMerge, opts AutoResolveOptions) error {
+	if opts.Disabled {
+		return nil
+	}
+
+	merge.AutoResolve(opts)
+
+	_, err := e.replayResolutions(merge)
+	return err
+}
+
+// AutoResolve resolve os blocos em conflito que não precisam de uma decisão:
+// só um lado alterou o bloco ou os dois lados fizeram a mesma alteração. Com
+// opts.Adjacent, também os blocos em que os dois lados alteraram linhas
+// diferentes. Retorna quantos blocos resolveu;
+// AutoResolved e Conflicts são atualizados.
+func (m *FileMerge) AutoResolve(opts AutoResolveOptions) int {
+	if opts.Disabled {
+		return 0
+	}
+
+	// O modo do arquivo é resolvido à parte, sem contar como bloco
+	if m.Mode != nil {
+		m.Mode.autoResolve()
+		m.countPending()
+	}
+
+	if m.Binary != nil {
+		if !m.Binary.autoResolve() {
+			return 0
+		}
+		m.AutoResolved++
+		m.countPending()
+		return 1
+	}
+
+	if m.Submodule != nil {
+		if !m.Submodule.autoResolve() {
+			return 0
+		}
+		m.AutoResolved++
+		m.countPending()
+		return 1
+	}
+
+	resolved := 0
+	for i := range m.Chunks {
+		chunk := &m.Chunks[i]
+		if !chunk.Pending() {
+			continue
+		}
+
+		lines, resolution, ok := autoResolveChunk(chunk, opts)
+		if !ok {
+			continue
+		}
+
+		chunk.Resolution = resolution
+		chunk.Lines = append([]string(nil), lines...)
+		resolved++
+	}
+
+	m.AutoResolved += resolved
+	m.countPending()
+
+	return resolved
+}
+
+// autoResolveChunk tenta resolver um bloco em conflito sem intervenção.
+func autoResolveChunk(chunk *Chunk, opts AutoResolveOptions) ([]string, string, bool) {
+	ours, theirs := sideLines(chunk.Ours), sideLines(chunk.Theirs)
+
+	equal := equalLines
+	if opts.Whitespace {
+		equal = equalIgnoringSpace
+	}
+
+	if equal(ours, theirs) {
+		return ours, ResolveAutoIdentical, true
+	}
+
+	// Sem ancestral comum não há como saber qual lado alterou o bloco
+	if chunk.Base == nil {
+		return nil, "", false
+	}
+	base := chunk.Base.Lines
+
+	switch {
+	case equal(ours, base):
+		return theirs, ResolveAutoTheirs, true
+	case equal(theirs, base):
+		return ours, ResolveAutoOurs, true
+	}
+
+	if !opts.Adjacent {
+		return nil, "", false
+	}
+
+	if lines, ok := combineHunks(base, ours, theirs, opts.Whitespace); ok {
+		return lines, ResolveAutoCombined, true
+	}
+
+	return nil, "", false
+}
+
+// combineHunks aplica à base as alterações dos dois lados quando elas não
+// se sobrepõem. Alterações vizinhas são aceitas; inserções no mesmo ponto
+// ou alterações nas mesmas linhas da base não. Com ignoreSpace, alterações
+// apenas de espaços não contam como alteração.
+func combineHunks(base, ours, theirs []string, ignoreSpace bool) ([]string, bool) {
+	normalize := func(lines []string) []string { return lines }
+	if ignoreSpace {
+		normalize = normalizeSpace
+	}
+
+	var hunks []sideHunk
+	for _, h := range diffHunks(normalize(base), normalize(ours)) {
+		hunks = append(hunks, sideHunk{diffHunk: h, ours: true})
+	}
+	for _, h := range diffHunks(normalize(base), normalize(theirs)) {
+		hunks = append(hunks, sideHunk{diffHunk: h, ours: false})
+	}
+
+	sort.SliceStable(hunks, func(i, j int) bool {
+		return hunks[i].baseStart < hunks[j].baseStart
+	})
+
+	end := -1
+	for i, h := range hunks {
+		if i > 0 && (h.baseStart == hunks[i-1].baseStart || h.baseStart < end) {
+			return nil, false
+		}
+		if h.baseEnd > end {
+			end = h.baseEnd
+		}
+	}
+
+	var lines []string
+	baseIdx := 0
+	for _, h := range hunks {
+		side := theirs
+		if h.ours {
+			side = ours
+		}
+
+		lines = append(lines, base[baseIdx:h.baseStart]...)
+		lines = append(lines, side[h.sideStart:h.sideEnd]...)
+		baseIdx = h.baseEnd
+	}
+	lines = append(lines, base[baseIdx:]...)
+
+	return lines, true
+}
+
+// equalIgnoringSpace compara linhas ignorando diferenças na quantidade de
+// espaços e os espaços nas pontas.
+func equalIgnoringSpace(a, b []string) bool {
+	return equalLines(normalizeSpace(a), normalizeSpace(b))
+}
+
+// normalizeSpace reduz cada sequência de espaços a um espaço e remove os
+// espaços nas pontas das linhas.
+func normalizeSpace(lines []string) []string {
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return normalized
+}
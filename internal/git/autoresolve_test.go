@@ -0,0 +1,182 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAutoResolve(t *testing.T) {
+	lines := func(s ...string) *HunkSide { return &HunkSide{Lines: s} }
+
+	tests := []struct {
+		name       string
+		ours       *HunkSide
+		base       *HunkSide
+		theirs     *HunkSide
+		opts       AutoResolveOptions
+		resolution string
+		want       []string
+	}{
+		{"apenas ours alterou", lines("x"), lines("b"), lines("b"), AutoResolveOptions{}, ResolveAutoOurs, []string{"x"}},
+		{"apenas theirs alterou", lines("b"), lines("b"), lines("y"), AutoResolveOptions{}, ResolveAutoTheirs, []string{"y"}},
+		{"alterações idênticas", lines("x"), lines("b"), lines("x"), AutoResolveOptions{}, ResolveAutoIdentical, []string{"x"}},
+		{"idênticas sem base", lines("x"), nil, lines("x"), AutoResolveOptions{}, ResolveAutoIdentical, []string{"x"}},
+		{"sem base", lines("x"), nil, lines("y"), AutoResolveOptions{}, "", nil},
+		{"mesma linha alterada", lines("x"), lines("b"), lines("y"), AutoResolveOptions{}, "", nil},
+		{
+			"linhas diferentes, sem Adjacent",
+			lines("X", "b", "c"), lines("a", "b", "c"), lines("a", "b", "Y"),
+			AutoResolveOptions{}, "", nil,
+		},
+		{
+			"linhas diferentes, com Adjacent",
+			lines("X", "b", "c"), lines("a", "b", "c"), lines("a", "b", "Y"),
+			AutoResolveOptions{Adjacent: true}, ResolveAutoCombined, []string{"X", "b", "Y"},
+		},
+		{
+			"linhas vizinhas, com Adjacent",
+			lines("X", "b"), lines("a", "b"), lines("a", "Y"),
+			AutoResolveOptions{Adjacent: true}, ResolveAutoCombined, []string{"X", "Y"},
+		},
+		{
+			"inserções no mesmo ponto",
+			lines("a", "x", "b"), lines("a", "b"), lines("a", "y", "b"),
+			AutoResolveOptions{}, "", nil,
+		},
+		{"só espaços, sem Whitespace", lines("x  y"), lines("b"), lines("x y"), AutoResolveOptions{}, "", nil},
+		{"só espaços, com Whitespace", lines("x  y"), lines("b"), lines("x y"), AutoResolveOptions{Whitespace: true}, ResolveAutoIdentical, []string{"x  y"}},
+		{"base com espaços, com Whitespace", lines(" b"), lines("b"), lines("y"), AutoResolveOptions{Whitespace: true}, ResolveAutoTheirs, []string{"y"}},
+		{"desligado", lines("x"), lines("b"), lines("b"), AutoResolveOptions{Disabled: true}, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := newFileMerge("a.txt", ConflictLabels{}, []Chunk{
+				{Conflict: true, Ours: tt.ours, Base: tt.base, Theirs: tt.theirs},
+			})
+
+			resolved := merge.AutoResolve(tt.opts)
+			chunk := merge.Chunks[0]
+
+			if tt.resolution == "" {
+				if resolved != 0 || merge.Conflicts != 1 || chunk.Resolution != "" {
+					t.Fatalf("bloco resolvido como %q: %q", chunk.Resolution, chunk.Lines)
+				}
+				return
+			}
+
+			if resolved != 1 || merge.AutoResolved != 1 || merge.Conflicts != 0 {
+				t.Fatalf("resolvidos = %d, AutoResolved = %d, conflitos = %d", resolved, merge.AutoResolved, merge.Conflicts)
+			}
+			if chunk.Resolution != tt.resolution || !reflect.DeepEqual(chunk.Lines, tt.want) {
+				t.Fatalf("resolução = %q %q, esperado %q %q", chunk.Resolution, chunk.Lines, tt.resolution, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeFileAutoResolveOptions(t *testing.T) {
+	repo := newDiskTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": "a\nb\nc\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"a.txt": "a\nx  y\nc\n"})
+	repo.commit("main", map[string]string{"a.txt": "a\nx y\nc\n"})
+
+	// As opções de uma chamada não valem para as seguintes
+	for _, tt := range []struct {
+		opts      AutoResolveOptions
+		conflicts int
+	}{
+		{AutoResolveOptions{Whitespace: true}, 0},
+		{AutoResolveOptions{}, 1},
+		{AutoResolveOptions{Whitespace: true}, 0},
+		{AutoResolveOptions{Disabled: true, Whitespace: true}, 1},
+	} {
+		merge, err := repo.control.MergeFile("feature", "main", "a.txt", CompareMergeBase, RenameOptions{}, DiffOptions{}, tt.opts)
+		if err != nil {
+			t.Fatalf("MergeFile: %v", err)
+		}
+		if merge.Conflicts != tt.conflicts || merge.AutoResolved != 1-tt.conflicts {
+			t.Fatalf("MergeFile com %+v: conflitos = %d, AutoResolved = %d", tt.opts, merge.Conflicts, merge.AutoResolved)
+		}
+	}
+}
+
+func TestAutoResolveKeepsDecisions(t *testing.T) {
+	merge := newFileMerge("a.txt", ConflictLabels{}, []Chunk{{
+		Conflict: true,
+		Ours:     &HunkSide{Lines: []string{"x"}},
+		Base:     &HunkSide{Lines: []string{"b"}},
+		Theirs:   &HunkSide{Lines: []string{"b"}},
+	}})
+
+	if err := merge.Apply(HunkDecision{Hunk: merge.Chunks[0].ID, Resolution: ResolveTheirs}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if resolved := merge.AutoResolve(AutoResolveOptions{}); resolved != 0 || merge.Chunks[0].Resolution != ResolveTheirs {
+		t.Fatalf("AutoResolve alterou uma decisão manual: %+v", merge.Chunks[0])
+	}
+}
+
+func TestParseAutoResolve(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  AutoResolveOptions
+		fails bool
+	}{
+		{name: "", want: AutoResolveOptions{}},
+		{name: "on", want: AutoResolveOptions{}},
+		{name: "off", want: AutoResolveOptions{Disabled: true}},
+		{name: "whitespace", want: AutoResolveOptions{Whitespace: true}},
+		{name: "adjacent", want: AutoResolveOptions{Adjacent: true}},
+		{name: "whitespace, adjacent", want: AutoResolveOptions{Whitespace: true, Adjacent: true}},
+		{name: "on,off", want: AutoResolveOptions{Disabled: true}},
+		{name: "sempre", fails: true},
+		{name: "whitespace,sempre", fails: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAutoResolve(tt.name)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("ParseAutoResolve(%q) não retornou erro", tt.name)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseAutoResolve(%q) = %+v, %v; esperado %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestCombineHunks(t *testing.T) {
+	split := func(s string) []string { return splitLines(s) }
+
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		ignoreSpace        bool
+		want               string
+		ok                 bool
+	}{
+		{"linhas vizinhas", "a\nb", "A\nb", "a\nB", false, "A\nB", true},
+		{"linhas distantes", "a\nb\nc\nd", "A\nb\nc\nd", "a\nb\nc\nD", false, "A\nb\nc\nD", true},
+		{"remoção e alteração vizinhas", "a\nb\nc", "b\nc", "a\nb\nC", false, "b\nC", true},
+		{"mesma linha", "a\nb", "A\nb", "x\nb", false, "", false},
+		{"inserção no mesmo ponto", "a\nb", "a\nx\nb", "a\ny\nb", false, "", false},
+		{"sobreposição", "a\nb\nc", "A\nB\nc", "a\nX\nC", false, "", false},
+		{"espaços ignorados", "a\nb", "a \nB", "A\nb", true, "A\nB", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := combineHunks(split(tt.base), split(tt.ours), split(tt.theirs), tt.ignoreSpace)
+			if ok != tt.ok {
+				t.Fatalf("combineHunks = %q, %v; esperado %v", got, ok, tt.ok)
+			}
+			if ok && joinLines(got) != tt.want {
+				t.Fatalf("combineHunks = %q, esperado %q", joinLines(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// binarySniffLen é quantos bytes do início do conteúdo são examinados em
+// busca de um byte NUL, o mesmo limite usado pelo git.
+const binarySniffLen = 8000
+
+// BinarySide identifica a versão de um lado de um arquivo binário.
+type BinarySide struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// BinaryConflict descreve o merge de um arquivo binário. Binários não são
+// mesclados linha a linha: o resultado é uma das versões inteiras, escolhida
+// em Resolution.
+type BinaryConflict struct {
+	Ours *BinarySide `json:"ours"`
+
+	// Base é nil quando não existe ancestral comum ou o arquivo não existia nele.
+	Base *BinarySide `json:"base,omitempty"`
+
+	// Theirs é nil quando o arquivo não existe na branch base.
+	Theirs *BinarySide `json:"theirs,omitempty"`
+
+	Resolution string `json:"resolution,omitempty"`
+
+	// ancestor informa se existe ancestral comum, para distinguir um arquivo
+	// ausente na base de uma base desconhecida
+	ancestor bool
+}
+
+// BinaryFileError indica que o arquivo é binário e não tem diff de texto.
+// Merge traz as versões dos dois lados para a escolha de uma delas.
+type BinaryFileError struct {
+	Merge *FileMerge
+}
+
+func (e *BinaryFileError) Error() string {
+	return fmt.Sprintf("o arquivo %s é binário e não tem diff de texto: escolha ours ou theirs", e.Merge.Path)
+}
+
+// isBinaryContent informa se o conteúdo é binário: como no git, se houver
+// um byte NUL no início do conteúdo.
+func isBinaryContent(content string) bool {
+	if len(content) > binarySniffLen {
+		content = content[:binarySniffLen]
+	}
+	return strings.IndexByte(content, 0) >= 0
+}
+
+// newBinarySide identifica a versão de um arquivo, nil se o arquivo não existir.
+func newBinarySide(file *object.File) *BinarySide {
+	if file == nil {
+		return nil
+	}
+	return &BinarySide{Hash: file.Hash.String(), Size: file.Size}
+}
+
+// binaryMerge monta o merge de um arquivo binário a partir das versões dos
+// dois lados e do primeiro ancestral comum.
+func (cmp *comparison) binaryMerge(fileName, oldPath string, targetFile, baseFile *object.File) (*FileMerge, error) {
+	binary := &BinaryConflict{
+		Ours:     newBinarySide(targetFile),
+		Theirs:   newBinarySide(baseFile),
+		ancestor: len(cmp.ancestors) > 0,
+	}
+
+	if binary.ancestor {
+		tree, err := cmp.ancestors[0].Tree()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao obter árvore do commit %s: %w", cmp.ancestors[0].Hash, err)
+		}
+
+		file, err := tree.File(oldPath)
+		if err != nil && !errors.Is(err, object.ErrFileNotFound) {
+			return nil, fmt.Errorf("erro ao ler arquivo %s no commit %s: %w", oldPath, cmp.ancestors[0].Hash, err)
+		}
+		binary.Base = newBinarySide(file)
+	}
+
+	merge := &FileMerge{Path: fileName, Labels: cmp.labels(), Binary: binary}
+	merge.countPending()
+
+	return merge, nil
+}
+
+// pending informa se ainda falta escolher a versão do arquivo.
+func (b *BinaryConflict) pending() bool {
+	return b.Resolution == ""
+}
+
+// chosen retorna a versão escolhida, nil enquanto o conflito estiver pendente.
+func (b *BinaryConflict) chosen() *BinarySide {
+	switch b.Resolution {
+	case ResolveOurs, ResolveAutoOurs, ResolveAutoIdentical, ResolveAutoKeepOurs:
+		return b.Ours
+	case ResolveTheirs, ResolveAutoTheirs:
+		return b.Theirs
+	}
+	return nil
+}
+
+// apply escolhe a versão de um dos lados.
+func (b *BinaryConflict) apply(resolution string) error {
+	switch resolution {
+	case ResolveOurs:
+	case ResolveTheirs:
+		if b.Theirs == nil {
+			return fmt.Errorf("o arquivo não existe na branch base: escolha ours")
+		}
+	default:
+		return fmt.Errorf("arquivos binários aceitam apenas as resoluções ours e theirs: %s", resolution)
+	}
+
+	b.Resolution = resolution
+	return nil
+}
+
+// autoResolve escolhe a versão quando os dois lados são iguais ou apenas um
+// deles alterou o arquivo. Retorna se resolveu.
+func (b *BinaryConflict) autoResolve() bool {
+	if !b.pending() {
+		return false
+	}
+
+	switch {
+	case sameBinary(b.Ours, b.Theirs):
+		b.Resolution = ResolveAutoIdentical
+	case !b.ancestor:
+		// Sem ancestral comum não há como saber qual lado alterou o arquivo
+		return false
+	case sameBinary(b.Ours, b.Base) && b.Theirs != nil:
+		b.Resolution = ResolveAutoTheirs
+	case sameBinary(b.Theirs, b.Base):
+		b.Resolution = ResolveAutoOurs
+	default:
+		return false
+	}
+
+	return true
+}
+
+// sameBinary informa se duas versões têm o mesmo conteúdo; duas versões
+// ausentes são iguais.
+func sameBinary(a, b *BinarySide) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Hash == b.Hash
+}
+
+// BinaryContent retorna o conteúdo da versão escolhida de um arquivo binário
+// já resolvido.
+func (e *Control) BinaryContent(merge *FileMerge) (string, error) {
+	if merge.Binary == nil {
+		return "", fmt.Errorf("o arquivo %s não é binário", merge.Path)
+	}
+
+	side := merge.Binary.chosen()
+	if side == nil {
+		return "", fmt.Errorf("o arquivo binário %s ainda não foi resolvido", merge.Path)
+	}
+
+	return e.blobContent(plumbing.NewHash(side.Hash))
+}
@@ -0,0 +1,181 @@
+package git
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsBinaryContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"texto", "linha\n", false},
+		{"vazio", "", false},
+		{"NUL no início", "\x00\x01\x02", true},
+		{"NUL no limite", strings.Repeat("a", binarySniffLen-1) + "\x00", true},
+		{"NUL depois do limite", strings.Repeat("a", binarySniffLen) + "\x00", false},
+	}
+
+	for _, tt := range tests {
+		if got := isBinaryContent(tt.content); got != tt.want {
+			t.Errorf("%s: isBinaryContent = %v, esperado %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// binaryRepo monta main e feature a partir de um commit com x.bin e y.dat,
+// que .gitattributes marca como binário. As versões de cada branch são
+// informadas em files.
+func binaryRepo(t *testing.T, ours, theirs map[string]string) *testRepo {
+	repo := newTestRepo(t)
+	base := map[string]string{".gitattributes": "*.dat binary\n", "x.bin": "base\x00", "y.dat": "base\n"}
+	repo.commit("main", base)
+	repo.setBranch("feature", repo.tip("main"))
+
+	side := func(branch string, changes map[string]string) {
+		files := make(map[string]string)
+		for name, content := range base {
+			files[name] = content
+		}
+		for name, content := range changes {
+			if content == "" {
+				delete(files, name)
+				continue
+			}
+			files[name] = content
+		}
+		repo.commit(branch, files)
+	}
+	side("feature", ours)
+	side("main", theirs)
+
+	return repo
+}
+
+func TestBinaryMerge(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		mode       CompareMode
+		ours       map[string]string
+		theirs     map[string]string
+		resolution string
+		want       string
+	}{
+		{"os dois lados alteraram", "x.bin", CompareMergeBase, map[string]string{"x.bin": "ours\x00"}, map[string]string{"x.bin": "theirs\x00"}, "", ""},
+		{"só ours alterou", "x.bin", CompareMergeBase, map[string]string{"x.bin": "ours\x00"}, nil, ResolveAutoOurs, "ours\x00"},
+		{"só theirs alterou", "x.bin", CompareTips, nil, map[string]string{"x.bin": "theirs\x00"}, ResolveAutoTheirs, "theirs\x00"},
+		{"mesma alteração", "x.bin", CompareMergeBase, map[string]string{"x.bin": "novo\x00"}, map[string]string{"x.bin": "novo\x00"}, ResolveAutoIdentical, "novo\x00"},
+		{"binário por atributo", "y.dat", CompareMergeBase, map[string]string{"y.dat": "ours\n"}, map[string]string{"y.dat": "theirs\n"}, "", ""},
+		{"atributo só em theirs", "y.dat", CompareMergeBase, map[string]string{".gitattributes": "\n", "y.dat": "ours\n"}, nil, ResolveAutoOurs, "ours\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := binaryRepo(t, tt.ours, tt.theirs)
+
+			merge, err := repo.control.MergeFile("feature", "main", tt.file, tt.mode, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+			if err != nil {
+				t.Fatalf("MergeFile: %v", err)
+			}
+			if merge.Binary == nil || len(merge.Chunks) != 0 {
+				t.Fatalf("MergeFile não tratou %s como binário: %+v", tt.file, merge)
+			}
+			if merge.Binary.Resolution != tt.resolution {
+				t.Fatalf("resolução = %q, esperado %q", merge.Binary.Resolution, tt.resolution)
+			}
+
+			if tt.resolution == "" {
+				if merge.Conflicts != 1 {
+					t.Fatalf("conflitos = %d, esperado 1", merge.Conflicts)
+				}
+				if _, err := repo.control.BinaryContent(merge); err == nil {
+					t.Fatalf("BinaryContent de um conflito pendente não retornou erro")
+				}
+				return
+			}
+
+			content, err := repo.control.BinaryContent(merge)
+			if err != nil || content != tt.want {
+				t.Fatalf("BinaryContent = %q, %v; esperado %q", content, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinaryApply(t *testing.T) {
+	repo := binaryRepo(t, map[string]string{"x.bin": "ours\x00"}, map[string]string{"x.bin": "theirs\x00"})
+
+	_, err := repo.control.DiffSpecificFile("feature", "main", "x.bin", CompareMergeBase, RenameOptions{}, ConflictMerge, DiffOptions{}, AutoResolveOptions{})
+	var binaryErr *BinaryFileError
+	if !errors.As(err, &binaryErr) || binaryErr.Merge.Binary == nil {
+		t.Fatalf("DiffSpecificFile = %v, esperado BinaryFileError", err)
+	}
+
+	merge := binaryErr.Merge
+	if merge.Binary.Base == nil || merge.Binary.Ours.Size != 5 {
+		t.Fatalf("versões inesperadas: %+v", merge.Binary)
+	}
+
+	for _, resolution := range []string{ResolveBase, ResolveBothOursFirst, ResolveCustom} {
+		if err := merge.Apply(HunkDecision{Resolution: resolution}); err == nil {
+			t.Fatalf("Apply(%s) aceito em arquivo binário", resolution)
+		}
+	}
+	if merge.Conflicts != 1 {
+		t.Fatalf("conflitos = %d depois de decisões recusadas", merge.Conflicts)
+	}
+
+	// O bloco é ignorado: a decisão vale para o arquivo inteiro
+	if err := merge.Apply(HunkDecision{Hunk: "qualquer", Resolution: ResolveTheirs}); err != nil {
+		t.Fatalf("Apply(theirs): %v", err)
+	}
+	if content, err := repo.control.BinaryContent(merge); err != nil || content != "theirs\x00" {
+		t.Fatalf("BinaryContent = %q, %v", content, err)
+	}
+	if merge.Conflicts != 0 || merge.Render(ConflictMerge) != "" {
+		t.Fatalf("conflitos = %d, Render = %q", merge.Conflicts, merge.Render(ConflictMerge))
+	}
+}
+
+func TestBinaryModifyDelete(t *testing.T) {
+	repo := binaryRepo(t, map[string]string{"x.bin": "ours\x00"}, map[string]string{"x.bin": ""})
+
+	// Um binário alterado de um lado e removido do outro é conflito de árvore
+	merge, err := repo.control.MergeFile("feature", "main", "x.bin", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	if merge.Tree == nil || merge.Tree.Kind != TreeModifyDelete || merge.Conflicts != 1 {
+		t.Fatalf("MergeFile = %+v, esperado um conflito modify/delete", merge.Tree)
+	}
+}
+
+func TestCommitMergeBinary(t *testing.T) {
+	repo := binaryRepo(t, map[string]string{"x.bin": "ours\x00", "y.dat": "ours\n"}, map[string]string{"x.bin": "theirs\x00", "y.dat": "theirs\n"})
+	author := Signature{Name: "Teste", Email: "teste@example.com"}
+
+	_, err := repo.control.CommitMerge(MergeCommitOptions{
+		Target: "feature", Parents: []string{"feature", "main"}, Message: "m", Author: author,
+	})
+
+	var unresolved *UnresolvedError
+	if !errors.As(err, &unresolved) || !reflect.DeepEqual(unresolved.Files, []string{"x.bin", "y.dat"}) {
+		t.Fatalf("CommitMerge = %v, esperado conflitos em x.bin e y.dat", err)
+	}
+
+	_, err = repo.control.CommitMerge(MergeCommitOptions{
+		Target: "feature", Parents: []string{"feature", "main"}, Message: "m", Author: author,
+		Files: map[string]string{"x.bin": "theirs\x00", "y.dat": "ours\n"},
+	})
+	if err != nil {
+		t.Fatalf("CommitMerge com as escolhas: %v", err)
+	}
+	if files := repo.files(repo.tip("feature")); files["x.bin"] != "theirs\x00" || files["y.dat"] != "ours\n" {
+		t.Fatalf("árvore do merge = %q", files)
+	}
+}
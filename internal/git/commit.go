@@ -0,0 +1,744 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Signature identifica autor ou committer de um commit.
+type Signature struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// MergeCommitOptions configura a gravação de um commit de merge.
+type MergeCommitOptions struct {
+	// Target é a branch que recebe o commit. Se não existir, é criada.
+	Target string
+
+	// Parents são as revisões mescladas (branches, tags, hashes...), na ordem
+	// dos pais do commit.
+	// O primeiro pai é o "ours" e precisa haver pelo menos dois.
+	Parents []string
+
+	// Files tem o conteúdo resolvido dos arquivos, indexado pelo caminho.
+	Files map[string]string
+
+	// Submodules tem o commit escolhido para os submódulos, indexado pelo
+	// caminho (ver SubmoduleCommit).
+	Submodules map[string]string
+
+	// Deleted são os caminhos removidos do resultado, como os conflitos de
+	// árvore resolvidos com ResolveDelete (ver TreeResult).
+	Deleted []string
+
+	// Diff define as diferenças ignoradas no merge dos arquivos alterados
+	// pelos dois lados que não estão em Files.
+	Diff DiffOptions
+
+	// AutoResolve define a resolução automática dos blocos desses arquivos.
+	AutoResolve AutoResolveOptions
+
+	// Renames define a detecção de renomeações nos conflitos de árvore.
+	Renames RenameOptions
+
+	// Modes tem o modo escolhido ("regular", "executable" ou "symlink") para
+	// os arquivos com modos diferentes nos dois lados, indexado pelo caminho
+	// (ver FileMode).
+	Modes map[string]string
+
+	Message string
+
+	// Author é obrigatório, a menos que o repositório tenha user.name e
+	// user.email configurados. Committer vazio usa o Author.
+	Author    Signature
+	Committer Signature
+}
+
+// UnresolvedError indica que o merge ainda tem arquivos com conflito.
+type UnresolvedError struct {
+	Files []string
+}
+
+func (e *UnresolvedError) Error() string {
+	return fmt.Sprintf("existem arquivos com conflitos não resolvidos: %s", strings.Join(e.Files, ", "))
+}
+
+// CommitMerge grava um commit de merge com dois ou mais pais na branch alvo.
+// A árvore do commit parte do primeiro pai; alterações feitas pelos demais pais
+// desde o ancestral comum são aplicadas quando não conflitam, os arquivos em
+// opts.Files e os submódulos de opts.Submodules substituem o resultado e os
+// caminhos de opts.Deleted saem dele. Conflitos de árvore precisam ter algum
+// dos caminhos envolvidos em Files, Submodules ou Deleted. Submódulos
+// alterados pelos dois lados só se resolvem sozinhos quando um lado descende
+// do outro no repositório local do submódulo. O modo dos arquivos segue o
+// lado que o alterou; se os dois lados o alteraram, vale o de opts.Modes.
+// Se algum arquivo resolvido ainda tiver
+// marcadores de conflito, ou se algum conflito não tiver sido resolvido, nada é
+// gravado e o erro é do tipo *UnresolvedError.
+// Se a branch alvo estiver em checkout, o diretório de trabalho não é atualizado.
+// Retorna o hash do novo commit.
+func (e *Control) CommitMerge(opts MergeCommitOptions) (string, error) {
+	if e.repository == nil {
+		return "", fmt.Errorf("repositório não inicializado")
+	}
+
+	if opts.Target == "" {
+		return "", fmt.Errorf("branch alvo não informada")
+	}
+
+	if len(opts.Parents) < 2 {
+		return "", fmt.Errorf("um commit de merge precisa de pelo menos dois pais")
+	}
+
+	if strings.TrimSpace(opts.Message) == "" {
+		return "", fmt.Errorf("mensagem do commit não informada")
+	}
+
+	if err := checkCommitPaths(opts); err != nil {
+		return "", err
+	}
+
+	// Recusa arquivos que ainda têm marcadores de conflito
+	var unresolved []string
+	for name, content := range opts.Files {
+		if hasConflictMarkers(content) {
+			unresolved = append(unresolved, name)
+		}
+	}
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return "", &UnresolvedError{Files: unresolved}
+	}
+
+	modes := make(map[string]filemode.FileMode, len(opts.Modes))
+	for name, mode := range opts.Modes {
+		parsed, err := parseModeName(mode)
+		if err != nil {
+			return "", fmt.Errorf("arquivo %s: %w", name, err)
+		}
+		modes[name] = parsed
+	}
+
+	author, committer, err := e.signatures(opts.Author, opts.Committer)
+	if err != nil {
+		return "", err
+	}
+
+	parents := make([]*object.Commit, 0, len(opts.Parents))
+	for _, name := range opts.Parents {
+		commit, err := e.resolveCommit(name)
+		if err != nil {
+			return "", err
+		}
+		parents = append(parents, commit)
+	}
+
+	storage := e.repository.Storer
+
+	// A branch alvo só avança: a ponta atual precisa ser um dos pais ou
+	// estar no histórico de algum deles
+	refName := plumbing.NewBranchReferenceName(opts.Target)
+	current, err := storage.Reference(refName)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return "", fmt.Errorf("erro ao ler a branch %s: %w", opts.Target, err)
+	}
+	if current != nil {
+		if err := e.checkFastForward(opts.Target, current.Hash(), parents); err != nil {
+			return "", err
+		}
+	}
+
+	entries, err := e.mergeTrees(parents, opts.Files, opts.Submodules, opts.Deleted, modes, opts.Diff, opts.AutoResolve, opts.Renames)
+	if err != nil {
+		return "", err
+	}
+
+	// Grava os arquivos resolvidos
+	for name, content := range opts.Files {
+		hash, err := writeBlob(storage, content)
+		if err != nil {
+			return "", err
+		}
+
+		entry := entries[name]
+		if entry.Mode == filemode.Empty || entry.Mode == filemode.Submodule {
+			entry.Mode = filemode.Regular
+		}
+		entry.Name = path.Base(name)
+		entry.Hash = hash
+		entries[name] = entry
+	}
+
+	// Aplica os modos escolhidos
+	for name, mode := range modes {
+		if entry, ok := entries[name]; ok && entry.Mode != filemode.Submodule {
+			entry.Mode = mode
+			entries[name] = entry
+		}
+	}
+
+	// Aponta os submódulos para os commits escolhidos
+	for name, commit := range opts.Submodules {
+		entries[name] = object.TreeEntry{Name: path.Base(name), Mode: filemode.Submodule, Hash: plumbing.NewHash(commit)}
+	}
+
+	// Os caminhos informados podem colidir com os que vêm dos pais
+	names := make(map[string]bool, len(entries))
+	for name := range entries {
+		names[name] = true
+	}
+	if err := checkDirCollisions(names); err != nil {
+		return "", err
+	}
+
+	treeHash, err := writeTree(storage, entries)
+	if err != nil {
+		return "", err
+	}
+
+	commit := &object.Commit{
+		Author:    author,
+		Committer: committer,
+		Message:   opts.Message,
+		TreeHash:  treeHash,
+	}
+	for _, parent := range parents {
+		commit.ParentHashes = append(commit.ParentHashes, parent.Hash)
+	}
+
+	obj := storage.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return "", fmt.Errorf("erro ao codificar commit: %w", err)
+	}
+
+	commitHash, err := storage.SetEncodedObject(obj)
+	if err != nil {
+		return "", fmt.Errorf("erro ao gravar commit: %w", err)
+	}
+
+	// A branch só é atualizada se ainda apontar para a ponta verificada;
+	// outro commit gravado no meio tempo faz este falhar
+	ref := plumbing.NewHashReference(refName, commitHash)
+	if err := storage.CheckAndSetReference(ref, current); err != nil {
+		return "", fmt.Errorf("erro ao atualizar a branch %s: %w", opts.Target, err)
+	}
+
+	return commitHash.String(), nil
+}
+
+// checkCommitPaths confere os caminhos de Files, Submodules, Deleted e
+// Modes: precisam ser relativos à raiz do repositório, sem segmentos vazios,
+// "." ou "..", e um caminho gravado não pode ser o diretório de outro. Os
+// commits de Submodules precisam ser hashes completos.
+func checkCommitPaths(opts MergeCommitOptions) error {
+	written := make(map[string]bool, len(opts.Files)+len(opts.Submodules))
+	for name := range opts.Files {
+		written[name] = true
+	}
+	for name, commit := range opts.Submodules {
+		if !plumbing.IsHash(commit) {
+			return fmt.Errorf("submódulo %s: commit inválido %q, esperado o hash completo", name, commit)
+		}
+		written[name] = true
+	}
+
+	names := make([]string, 0, len(written)+len(opts.Deleted)+len(opts.Modes))
+	for name := range written {
+		names = append(names, name)
+	}
+	names = append(names, opts.Deleted...)
+	for name := range opts.Modes {
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		if err := checkTreePath(name); err != nil {
+			return err
+		}
+	}
+
+	return checkDirCollisions(written)
+}
+
+// checkDirCollisions recusa um caminho que esteja dentro de outro que é um
+// arquivo, como "a" e "a/b": na árvore, "a" não pode ser as duas coisas.
+func checkDirCollisions(names map[string]bool) error {
+	for name := range names {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if names[dir] {
+				return fmt.Errorf("caminho %s é um arquivo e também o diretório de %s", dir, name)
+			}
+		}
+	}
+	return nil
+}
+
+// checkTreePath confere se o caminho pode ser gravado em uma árvore do git.
+func checkTreePath(name string) error {
+	if name == "" {
+		return fmt.Errorf("caminho vazio")
+	}
+	if strings.HasPrefix(name, "/") {
+		return fmt.Errorf("caminho absoluto: %s", name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		switch segment {
+		case "", ".", "..":
+			return fmt.Errorf("caminho inválido: %s", name)
+		}
+	}
+	return nil
+}
+
+// checkFastForward confirma que a ponta atual da branch alvo é um dos pais do
+// merge ou ancestral de algum deles, para que o commit não descarte histórico.
+func (e *Control) checkFastForward(target string, tip plumbing.Hash, parents []*object.Commit) error {
+	tipCommit, err := e.repository.CommitObject(tip)
+	if err != nil {
+		return fmt.Errorf("erro ao ler a ponta da branch %s: %w", target, err)
+	}
+
+	for _, parent := range parents {
+		if parent.Hash == tip {
+			return nil
+		}
+		ok, err := tipCommit.IsAncestor(parent)
+		if err != nil {
+			return fmt.Errorf("erro ao percorrer o histórico de %s: %w", parent.Hash, err)
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("a branch %s aponta para %s, que não está no histórico dos pais do merge", target, tip)
+}
+
+// signatures completa autor e committer com a data atual, usando user.name e
+// user.email da configuração do git quando o autor não é informado.
+func (e *Control) signatures(author, committer Signature) (object.Signature, object.Signature, error) {
+	if author.Name == "" || author.Email == "" {
+		cfg, err := e.repository.ConfigScoped(config.GlobalScope)
+		if err == nil {
+			if author.Name == "" {
+				author.Name = cfg.User.Name
+			}
+			if author.Email == "" {
+				author.Email = cfg.User.Email
+			}
+		}
+	}
+
+	if author.Name == "" || author.Email == "" {
+		return object.Signature{}, object.Signature{}, fmt.Errorf("autor do commit não informado e user.name/user.email não configurados")
+	}
+
+	if committer.Name == "" {
+		committer.Name = author.Name
+	}
+	if committer.Email == "" {
+		committer.Email = author.Email
+	}
+
+	now := time.Now()
+	return object.Signature{Name: author.Name, Email: author.Email, When: now},
+		object.Signature{Name: committer.Name, Email: committer.Email, When: now},
+		nil
+}
+
+// mergeTrees monta as entradas (caminho -> entrada da árvore) do resultado do
+// merge. Parte da árvore do primeiro pai e aplica, pai a pai, as alterações
+// feitas desde o ancestral comum. Arquivos alterados pelos dois lados passam
+// pelo merge de três vias; os que continuam em conflito, e os conflitos de
+// árvore, precisam estar em resolved, em submodules ou em deleted, senão o
+// merge é recusado; arquivos com o modo alterado pelos dois lados precisam
+// estar em modes. Os caminhos de deleted saem do resultado. diff define as
+// diferenças ignoradas no merge do conteúdo, auto a resolução automática e
+// renames a detecção de renomeações nos conflitos de árvore.
+func (e *Control) mergeTrees(parents []*object.Commit, resolved, submodules map[string]string, deleted []string, modes map[string]filemode.FileMode, diff DiffOptions, auto AutoResolveOptions, renames RenameOptions) (map[string]object.TreeEntry, error) {
+	first := parents[0]
+
+	firstTree, err := first.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao obter árvore do commit %s: %w", first.Hash, err)
+	}
+
+	entries, err := flattenTree(firstTree)
+	if err != nil {
+		return nil, err
+	}
+
+	firstAttrs := newAttributes(firstTree)
+
+	removed := make(map[string]bool, len(deleted))
+	for _, name := range deleted {
+		removed[name] = true
+	}
+	decided := func(name string) bool {
+		_, isFile := resolved[name]
+		_, isSubmodule := submodules[name]
+		return isFile || isSubmodule || removed[name]
+	}
+
+	var unresolved []string
+	sims := e.newSimilarityCache()
+
+	for _, parent := range parents[1:] {
+		parentTree, err := parent.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao obter árvore do commit %s: %w", parent.Hash, err)
+		}
+
+		ancestors, err := mergeBases(first, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		parentAttrs := newAttributes(parentTree)
+
+		// Conflitos de árvore só passam com alguma decisão sobre os caminhos
+		// envolvidos; o add/add passa pelo merge do conteúdo abaixo
+		conflicts, err := e.treeConflicts(firstTree, parentTree, ancestors, "", "", renames, sims)
+		if err != nil {
+			return nil, err
+		}
+		for _, conflict := range conflicts {
+			if conflict.Kind == TreeAddAdd || slices.ContainsFunc(conflict.paths(), decided) {
+				continue
+			}
+			unresolved = append(unresolved, conflict.Path)
+		}
+
+		var changes object.Changes
+		ancestorEntries := make(map[string]object.TreeEntry)
+
+		if len(ancestors) == 0 {
+			// Sem histórico comum, tudo na árvore do pai é alteração
+			changes, err = (&object.Tree{}).Diff(parentTree)
+			if err != nil {
+				return nil, fmt.Errorf("erro ao comparar árvores: %w", err)
+			}
+		} else {
+			changes, err = changesSinceAncestors(ancestors, parentTree)
+			if err != nil {
+				return nil, err
+			}
+
+			ancestorTree, err := ancestors[0].Tree()
+			if err != nil {
+				return nil, fmt.Errorf("erro ao obter árvore do commit %s: %w", ancestors[0].Hash, err)
+			}
+
+			if ancestorEntries, err = flattenTree(ancestorTree); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, change := range changes {
+			name := changePath(change)
+
+			current, inCurrent := entries[name]
+			original, inOriginal := ancestorEntries[name]
+			theirs := change.To.TreeEntry
+			inTheirs := change.To.Name != ""
+
+			// Modo de um arquivo alterado pelos dois lados; o escolhido em
+			// modes vale quando os dois alteraram o modo
+			mergedMode := func() (filemode.FileMode, bool) {
+				if mode, ok := modes[name]; ok {
+					return mode, true
+				}
+				return mergeModes(original.Mode, current.Mode, theirs.Mode, inOriginal)
+			}
+
+			if decided(name) {
+				// O conteúdo já foi decidido, mas o modo ainda segue os dois lados
+				if _, isFile := resolved[name]; isFile && inCurrent && inTheirs && current.Mode != filemode.Submodule {
+					mode, ok := mergedMode()
+					if !ok {
+						unresolved = append(unresolved, name)
+						continue
+					}
+					current.Mode = mode
+					entries[name] = current
+				}
+				continue
+			}
+
+			// O lado atual não mexeu no arquivo: vale a versão do pai
+			if inCurrent == inOriginal && (!inCurrent || sameEntry(current, original)) {
+				if inTheirs {
+					entries[name] = theirs
+				} else {
+					delete(entries, name)
+				}
+				continue
+			}
+
+			// Os dois lados chegaram ao mesmo resultado
+			if inCurrent == inTheirs && (!inCurrent || sameEntry(current, theirs)) {
+				continue
+			}
+
+			// Os dois lados alteraram: tenta o merge do conteúdo
+			if !inCurrent || !inTheirs {
+				unresolved = append(unresolved, name)
+				continue
+			}
+
+			// Submódulos não têm conteúdo: só avançam quando um lado
+			// descende do outro
+			if current.Mode == filemode.Submodule || theirs.Mode == filemode.Submodule {
+				entry, ok := e.mergeGitlinks(name, current, theirs)
+				if !ok {
+					unresolved = append(unresolved, name)
+					continue
+				}
+				entries[name] = entry
+				continue
+			}
+
+			mode, ok := mergedMode()
+			if !ok {
+				unresolved = append(unresolved, name)
+				continue
+			}
+
+			rules, err := rulesFor(name, firstAttrs, parentAttrs)
+			if err != nil {
+				return nil, err
+			}
+
+			merged, ok, err := e.mergeEntries(ancestors, name, current, theirs, rules, diff, auto)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				unresolved = append(unresolved, name)
+				continue
+			}
+
+			hash, err := writeBlob(e.repository.Storer, merged)
+			if err != nil {
+				return nil, err
+			}
+			current.Hash = hash
+			current.Mode = mode
+			entries[name] = current
+		}
+	}
+
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return nil, &UnresolvedError{Files: slices.Compact(unresolved)}
+	}
+
+	for name := range removed {
+		delete(entries, name)
+	}
+
+	return entries, nil
+}
+
+// mergeEntries faz o merge de três vias de duas versões de um arquivo com o
+// driver de merge do arquivo, seguindo as regras dos .gitattributes. Retorna false se o merge tiver
+// conflitos. Arquivos binários, marcados nas regras ou com byte NUL no
+// conteúdo, alterados pelos dois lados são conflito, a menos que a
+// estratégia seja merge=ours.
+func (e *Control) mergeEntries(ancestors []*object.Commit, name string, ours, theirs object.TreeEntry, rules mergeRules, diff DiffOptions, auto AutoResolveOptions) (string, bool, error) {
+
+	oursContent, err := e.blobContent(ours.Hash)
+	if err != nil {
+		return "", false, err
+	}
+
+	theirsContent, err := e.blobContent(theirs.Hash)
+	if err != nil {
+		return "", false, err
+	}
+
+	if rules.binary || isBinaryContent(oursContent) || isBinaryContent(theirsContent) {
+		return oursContent, rules.strategy == StrategyOurs, nil
+	}
+
+	base, err := ancestorContent(ancestors, name)
+	if err != nil {
+		return "", false, err
+	}
+
+	if rules.text {
+		base, oursContent, theirsContent = normalizeEOL(base), normalizeEOL(oursContent), normalizeEOL(theirsContent)
+	}
+
+	chunks, _, err := e.mergeWithDriver(MergeInput{
+		Path:    name,
+		Base:    base,
+		Ours:    oursContent,
+		Theirs:  theirsContent,
+		HasBase: len(ancestors) > 0,
+		Options: diff,
+	}, rules)
+	if err != nil {
+		return "", false, err
+	}
+
+	merge := newFileMerge(name, defaultLabels, chunks)
+	if err := e.resolveKnown(merge, auto); err != nil {
+		return "", false, err
+	}
+
+	return merge.Render(ConflictMerge), merge.Conflicts == 0, nil
+}
+
+// blobContent lê o conteúdo de um blob.
+func (e *Control) blobContent(hash plumbing.Hash) (string, error) {
+	blob, err := e.repository.BlobObject(hash)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler blob %s: %w", hash, err)
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler blob %s: %w", hash, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler blob %s: %w", hash, err)
+	}
+
+	return string(data), nil
+}
+
+// sameEntry informa se duas entradas de árvore têm o mesmo conteúdo e modo.
+func sameEntry(a, b object.TreeEntry) bool {
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
+
+// flattenTree retorna todas as entradas que não são diretórios, indexadas
+// pelo caminho completo.
+func flattenTree(tree *object.Tree) (map[string]object.TreeEntry, error) {
+	entries := make(map[string]object.TreeEntry)
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao percorrer árvore %s: %w", tree.Hash, err)
+		}
+
+		if entry.Mode == filemode.Dir {
+			continue
+		}
+		entries[name] = entry
+	}
+
+	return entries, nil
+}
+
+// writeBlob grava um conteúdo como blob e retorna seu hash.
+func writeBlob(storage storer.EncodedObjectStorer, content string) (plumbing.Hash, error) {
+	obj := storage.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(content)))
+
+	writer, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("erro ao criar blob: %w", err)
+	}
+
+	if _, err := writer.Write([]byte(content)); err != nil {
+		_ = writer.Close()
+		return plumbing.ZeroHash, fmt.Errorf("erro ao escrever blob: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("erro ao escrever blob: %w", err)
+	}
+
+	hash, err := storage.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("erro ao gravar blob: %w", err)
+	}
+
+	return hash, nil
+}
+
+// writeTree grava a hierarquia de árvores a partir das entradas indexadas pelo
+// caminho completo e retorna o hash da árvore raiz.
+func writeTree(storage storer.EncodedObjectStorer, entries map[string]object.TreeEntry) (plumbing.Hash, error) {
+	files := make(map[string]object.TreeEntry)
+	dirs := make(map[string]map[string]object.TreeEntry)
+
+	for name, entry := range entries {
+		dir, rest, nested := strings.Cut(name, "/")
+		if !nested {
+			entry.Name = name
+			files[name] = entry
+			continue
+		}
+
+		if dirs[dir] == nil {
+			dirs[dir] = make(map[string]object.TreeEntry)
+		}
+		dirs[dir][rest] = entry
+	}
+
+	tree := &object.Tree{}
+
+	for _, entry := range files {
+		tree.Entries = append(tree.Entries, entry)
+	}
+
+	for name, children := range dirs {
+		hash, err := writeTree(storage, children)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// O git ordena as entradas pelo nome, tratando diretórios como "nome/"
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return treeSortKey(tree.Entries[i]) < treeSortKey(tree.Entries[j])
+	})
+
+	obj := storage.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("erro ao codificar árvore: %w", err)
+	}
+
+	hash, err := storage.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("erro ao gravar árvore: %w", err)
+	}
+
+	return hash, nil
+}
+
+// treeSortKey retorna a chave de ordenação de uma entrada de árvore.
+func treeSortKey(entry object.TreeEntry) string {
+	if entry.Mode == filemode.Dir {
+		return entry.Name + "/"
+	}
+	return entry.Name
+}
@@ -0,0 +1,258 @@
+package git
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// mergeRepo monta duas branches a partir de main: feature altera a.txt e
+// conflict.txt, main altera b.txt e conflict.txt.
+func mergeRepo(t *testing.T) *testRepo {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": "a\n", "b.txt": "b\n", "conflict.txt": "base\n", "dir/c.txt": "c\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"a.txt": "feature\n", "b.txt": "b\n", "conflict.txt": "feature\n", "dir/c.txt": "c\n"})
+	repo.commit("main", map[string]string{"a.txt": "a\n", "b.txt": "main\n", "conflict.txt": "main\n", "dir/c.txt": "c\n"})
+	return repo
+}
+
+func TestCommitMerge(t *testing.T) {
+	repo := mergeRepo(t)
+	feature, main := repo.tip("feature"), repo.tip("main")
+
+	hash, err := repo.control.CommitMerge(MergeCommitOptions{
+		Target:  "feature",
+		Parents: []string{"feature", "main"},
+		Files:   map[string]string{"conflict.txt": "resolvido\n", "dir/novo.txt": "novo\n"},
+		Message: "merge de main",
+		Author:  Signature{Name: "Teste", Email: "teste@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("CommitMerge: %v", err)
+	}
+
+	commitHash := plumbing.NewHash(hash)
+	if tip := repo.tip("feature"); tip != commitHash {
+		t.Fatalf("feature aponta para %s, esperado %s", tip, commitHash)
+	}
+
+	commit, err := repo.repo.CommitObject(commitHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []plumbing.Hash{feature, main}; !reflect.DeepEqual(commit.ParentHashes, want) {
+		t.Fatalf("pais = %v, esperado %v", commit.ParentHashes, want)
+	}
+	if commit.Author.Name != "Teste" || commit.Committer.Email != "teste@example.com" {
+		t.Fatalf("autor = %v, committer = %v", commit.Author, commit.Committer)
+	}
+
+	want := map[string]string{
+		"a.txt":        "feature\n",
+		"b.txt":        "main\n",
+		"conflict.txt": "resolvido\n",
+		"dir/c.txt":    "c\n",
+		"dir/novo.txt": "novo\n",
+	}
+	if got := repo.files(commitHash); !reflect.DeepEqual(got, want) {
+		t.Fatalf("arquivos = %v, esperado %v", got, want)
+	}
+}
+
+func TestCommitMergeErrors(t *testing.T) {
+	author := Signature{Name: "Teste", Email: "teste@example.com"}
+
+	tests := []struct {
+		name       string
+		opts       MergeCommitOptions
+		unresolved []string
+	}{
+		{
+			name: "sem branch alvo",
+			opts: MergeCommitOptions{Parents: []string{"feature", "main"}, Message: "m", Author: author},
+		},
+		{
+			name: "um só pai",
+			opts: MergeCommitOptions{Target: "feature", Parents: []string{"feature"}, Message: "m", Author: author},
+		},
+		{
+			name: "sem mensagem",
+			opts: MergeCommitOptions{Target: "feature", Parents: []string{"feature", "main"}, Message: " ", Author: author},
+		},
+		{
+			name: "pai inexistente",
+			opts: MergeCommitOptions{Target: "feature", Parents: []string{"feature", "outra"}, Message: "m", Author: author},
+		},
+		{
+			name: "conflito sem resolução",
+			opts: MergeCommitOptions{
+				Target: "feature", Parents: []string{"feature", "main"}, Message: "m", Author: author,
+			},
+			unresolved: []string{"conflict.txt"},
+		},
+		{
+			name: "resolução com marcadores",
+			opts: MergeCommitOptions{
+				Target: "feature", Parents: []string{"feature", "main"}, Message: "m", Author: author,
+				Files: map[string]string{"conflict.txt": "<<<<<<< HEAD\nfeature\n=======\nmain\n>>>>>>> branch\n"},
+			},
+			unresolved: []string{"conflict.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mergeRepo(t)
+			tip := repo.tip("feature")
+
+			_, err := repo.control.CommitMerge(tt.opts)
+			if err == nil {
+				t.Fatalf("CommitMerge não retornou erro")
+			}
+
+			var unresolved *UnresolvedError
+			if tt.unresolved != nil && (!errors.As(err, &unresolved) || !reflect.DeepEqual(unresolved.Files, tt.unresolved)) {
+				t.Fatalf("CommitMerge = %v, esperado conflitos em %v", err, tt.unresolved)
+			}
+
+			if repo.tip("feature") != tip {
+				t.Fatalf("a branch feature foi alterada")
+			}
+		})
+	}
+}
+
+func TestCommitMergePaths(t *testing.T) {
+	resolved := map[string]string{"conflict.txt": "resolvido\n"}
+	with := func(name, content string) map[string]string {
+		files := map[string]string{name: content}
+		for name, content := range resolved {
+			files[name] = content
+		}
+		return files
+	}
+
+	tests := []struct {
+		name       string
+		files      map[string]string
+		submodules map[string]string
+		deleted    []string
+		want       string
+	}{
+		{name: "caminho vazio", files: with("", "x\n"), want: "caminho vazio"},
+		{name: "caminho absoluto", files: with("/a.txt", "x\n"), want: "caminho absoluto"},
+		{name: "segmento vazio", files: with("dir//a.txt", "x\n"), want: "caminho inválido"},
+		{name: "barra no fim", files: with("dir/", "x\n"), want: "caminho inválido"},
+		{name: "segmento ponto", files: with("./a.txt", "x\n"), want: "caminho inválido"},
+		{name: "segmento ponto ponto", files: with("dir/../a.txt", "x\n"), want: "caminho inválido"},
+		{name: "remoção inválida", files: resolved, deleted: []string{"../b.txt"}, want: "caminho inválido"},
+		{name: "submódulo inválido", files: resolved, submodules: map[string]string{"/sub": strings.Repeat("a", 40)}, want: "caminho absoluto"},
+		{name: "hash abreviado", files: resolved, submodules: map[string]string{"sub": "abc123"}, want: "commit inválido"},
+		{name: "hash fora de hexadecimal", files: resolved, submodules: map[string]string{"sub": strings.Repeat("g", 40)}, want: "commit inválido"},
+		{name: "arquivo e diretório", files: with("novo", "x\n"), submodules: map[string]string{"novo/sub": strings.Repeat("a", 40)}, want: "também o diretório"},
+		{name: "arquivo dentro de arquivo", files: with("a.txt/x", "x\n"), want: "também o diretório"},
+		{name: "arquivo no lugar de diretório", files: with("dir", "x\n"), want: "também o diretório"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mergeRepo(t)
+			tip := repo.tip("feature")
+
+			_, err := repo.control.CommitMerge(MergeCommitOptions{
+				Target:     "feature",
+				Parents:    []string{"feature", "main"},
+				Files:      tt.files,
+				Submodules: tt.submodules,
+				Deleted:    tt.deleted,
+				Message:    "m",
+				Author:     Signature{Name: "Teste", Email: "teste@example.com"},
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("CommitMerge = %v, esperado erro com %q", err, tt.want)
+			}
+			if repo.tip("feature") != tip {
+				t.Fatalf("a branch feature foi alterada")
+			}
+		})
+	}
+}
+
+func TestCommitMergeFastForward(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		fails  bool
+	}{
+		{name: "ponta é um dos pais", target: "feature"},
+		{name: "ponta no histórico de um pai", target: "antiga"},
+		{name: "branch nova", target: "nova"},
+		{name: "ponta fora do histórico", target: "release", fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mergeRepo(t)
+
+			feature, err := repo.repo.CommitObject(repo.tip("feature"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			repo.setBranch("antiga", feature.ParentHashes[0])
+			repo.setBranch("release", repo.tip("main"))
+			release := repo.commit("release", map[string]string{"a.txt": "release\n"})
+
+			hash, err := repo.control.CommitMerge(MergeCommitOptions{
+				Target:  tt.target,
+				Parents: []string{"feature", "main"},
+				Files:   map[string]string{"conflict.txt": "resolvido\n"},
+				Message: "merge de main",
+				Author:  Signature{Name: "Teste", Email: "teste@example.com"},
+			})
+			if tt.fails {
+				if err == nil {
+					t.Fatalf("CommitMerge não retornou erro")
+				}
+				if tip := repo.tip(tt.target); tip != release {
+					t.Fatalf("%s aponta para %s, esperado %s", tt.target, tip, release)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CommitMerge: %v", err)
+			}
+			if tip := repo.tip(tt.target); tip.String() != hash {
+				t.Fatalf("%s aponta para %s, esperado %s", tt.target, tip, hash)
+			}
+		})
+	}
+}
+
+func TestHasConflictMarkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"sem marcadores", "a\nb\n", false},
+		{"conflito", "<<<<<<< ours\na\n=======\nb\n>>>>>>> theirs\n", true},
+		{"conflito diff3", "<<<<<<< ours\na\n||||||| base\nc\n=======\nb\n>>>>>>> theirs\n", true},
+		{"marcadores sem rótulo", "<<<<<<<\na\n=======\nb\n>>>>>>>\n", true},
+		{"título sublinhado", "Título\n=======\n\ntexto\n", false},
+		{"marcadores fora de ordem", ">>>>>>> theirs\n=======\n<<<<<<< ours\n", false},
+		{"conflito sem fim", "<<<<<<< ours\na\n=======\nb\n", false},
+		{"marcador no meio da linha", "x <<<<<<< ours\n=======\n>>>>>>> theirs\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasConflictMarkers(tt.content); got != tt.want {
+				t.Fatalf("hasConflictMarkers = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
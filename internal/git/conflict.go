@@ -0,0 +1,327 @@
+package git
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ConflictLabels são os nomes exibidos nos marcadores de conflito,
+// como "feature (1a2b3c4)".
+type ConflictLabels struct {
+	Ours   string `json:"ours"`
+	Base   string `json:"base"`
+	Theirs string `json:"theirs"`
+}
+
+// HunkSide é a versão de um lado (ours, base ou theirs) de um bloco em conflito.
+type HunkSide struct {
+	Lines []string `json:"lines"`
+
+	// Start é a primeira linha do trecho na versão do lado, contando a partir
+	// de 1. Com Count zero, o trecho vazio fica logo antes de Start.
+	Start int `json:"start"`
+	Count int `json:"count"`
+}
+
+// Chunk é um trecho do resultado do merge. Trechos estáveis têm apenas Lines;
+// blocos em conflito têm ID e as versões Ours, Base e Theirs.
+// Base é nil quando não existe ancestral comum. Um bloco em conflito já
+// resolvido tem Resolution preenchido e o resultado em Lines.
+type Chunk struct {
+	ID         string    `json:"id,omitempty"`
+	Conflict   bool      `json:"conflict"`
+	Resolution string    `json:"resolution,omitempty"`
+	Lines      []string  `json:"lines,omitempty"`
+	Ours       *HunkSide `json:"ours,omitempty"`
+	Base       *HunkSide `json:"base,omitempty"`
+	Theirs     *HunkSide `json:"theirs,omitempty"`
+
+	// Note explica a resolução feita pelo driver de merge, como as versões
+	// escolhidas no go.mod.
+	Note string `json:"note,omitempty"`
+
+	// Inline compara as linhas de Ours e de Theirs que se substituem, por
+	// palavra e por caractere, para destacar as diferenças dentro das linhas
+	// e permitir escolher o lado de cada trecho (ResolveInline).
+	Inline []InlineDiff `json:"inline,omitempty"`
+}
+
+// FileMerge descreve o resultado do merge de um arquivo como uma sequência de
+// trechos estáveis e blocos em conflito. O texto com marcadores é gerado a
+// partir dele por Render.
+// Arquivos binários não têm Chunks: as versões dos dois lados ficam em Binary
+// e o conflito, se houver, conta como um só. O mesmo vale para os conflitos
+// de árvore em Tree, exceto add/add, que também tem o merge do conteúdo, e
+// para os submódulos em Submodule. Modos diferentes nos dois lados
+// (regular, executável ou link simbólico) ficam em Mode e contam como mais
+// um conflito enquanto não forem escolhidos.
+type FileMerge struct {
+	Path      string             `json:"path"`
+	Labels    ConflictLabels     `json:"labels"`
+	Chunks    []Chunk            `json:"chunks"`
+	Binary    *BinaryConflict    `json:"binary,omitempty"`
+	Tree      *TreeConflict      `json:"tree,omitempty"`
+	Submodule *SubmoduleConflict `json:"submodule,omitempty"`
+	Mode      *ModeConflict      `json:"mode,omitempty"`
+	Conflicts int                `json:"conflicts"`
+
+	// Strategy é o driver de merge usado (union, ours ou outro registrado),
+	// escolhido pelo merge=<nome> dos .gitattributes ou pelo caminho do
+	// arquivo; vazio para o merge de texto.
+	Strategy string `json:"strategy,omitempty"`
+
+	// Generated marca arquivos gerados (linguist-generated), que costumam
+	// ser gerados de novo em vez de mesclados à mão.
+	Generated bool `json:"generated,omitempty"`
+
+	// AutoResolved é a quantidade de blocos resolvidos por AutoResolve.
+	AutoResolved int `json:"autoResolved"`
+
+	// Replayed é a quantidade de blocos resolvidos com resoluções gravadas
+	// de merges anteriores (rerere).
+	Replayed int `json:"replayed"`
+}
+
+// newHunkSide cria a versão de um lado a partir das linhas e da posição,
+// a partir de zero, onde elas começam.
+func newHunkSide(lines []string, start int) *HunkSide {
+	return &HunkSide{Lines: lines, Start: start + 1, Count: len(lines)}
+}
+
+// newFileMerge monta o modelo e atribui os ids dos blocos em conflito.
+// O id vem do conteúdo do bloco, então o mesmo conflito mantém o id entre
+// requisições; blocos idênticos no mesmo arquivo recebem um sufixo.
+// Blocos que já vêm resolvidos, pelo driver de merge, contam em AutoResolved.
+func newFileMerge(path string, labels ConflictLabels, chunks []Chunk) *FileMerge {
+	merge := &FileMerge{Path: path, Labels: labels, Chunks: chunks}
+
+	seen := make(map[string]int)
+	for i := range merge.Chunks {
+		chunk := &merge.Chunks[i]
+		if !chunk.Conflict {
+			continue
+		}
+
+		id := hunkID(chunk)
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			id = fmt.Sprintf("%s-%d", id, n)
+		}
+		chunk.ID = id
+		chunk.Inline = inlineDiffs(sideLines(chunk.Ours), sideLines(chunk.Theirs))
+
+		if !chunk.Pending() {
+			merge.AutoResolved++
+		}
+	}
+	merge.countPending()
+
+	return merge
+}
+
+// Clone retorna uma cópia do merge que pode ser resolvida sem alterar o
+// original. As versões dos lados dos blocos são compartilhadas, pois Apply
+// não as altera.
+func (m *FileMerge) Clone() *FileMerge {
+	clone := *m
+	clone.Chunks = append([]Chunk(nil), m.Chunks...)
+
+	if m.Binary != nil {
+		binary := *m.Binary
+		clone.Binary = &binary
+	}
+	if m.Tree != nil {
+		tree := *m.Tree
+		clone.Tree = &tree
+	}
+	if m.Submodule != nil {
+		submodule := *m.Submodule
+		clone.Submodule = &submodule
+	}
+	if m.Mode != nil {
+		mode := *m.Mode
+		clone.Mode = &mode
+	}
+
+	return &clone
+}
+
+// Pending informa se o trecho é um conflito ainda não resolvido.
+func (c *Chunk) Pending() bool {
+	return c.Conflict && c.Resolution == ""
+}
+
+// countPending atualiza Conflicts com a quantidade de conflitos pendentes.
+// Um conflito de árvore resolvido decide o arquivo inteiro e dispensa os blocos.
+func (m *FileMerge) countPending() {
+	m.Conflicts = 0
+	if m.Tree != nil {
+		if m.Tree.Resolution != "" {
+			return
+		}
+		if m.Tree.pending() {
+			m.Conflicts++
+		}
+	}
+	if m.Binary != nil && m.Binary.pending() {
+		m.Conflicts++
+	}
+	if m.Submodule != nil && m.Submodule.pending() {
+		m.Conflicts++
+	}
+	if m.Mode != nil && m.Mode.pending() {
+		m.Conflicts++
+	}
+	for i := range m.Chunks {
+		if m.Chunks[i].Pending() {
+			m.Conflicts++
+		}
+	}
+}
+
+// hunkID calcula o id de um bloco em conflito a partir das três versões.
+func hunkID(chunk *Chunk) string {
+	hash := sha1.New()
+	for _, side := range []*HunkSide{chunk.Ours, chunk.Base, chunk.Theirs} {
+		if side != nil {
+			hash.Write([]byte(joinLines(side.Lines)))
+		}
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// Render escreve o arquivo no formato de conflito do git, no estilo informado.
+// Arquivos binários, submódulos e conflitos de árvore sem merge de conteúdo
+// não têm representação em texto e resultam em vazio.
+func (m *FileMerge) Render(style ConflictStyle) string {
+	var lines []string
+
+	for _, c := range m.Chunks {
+		if !c.Pending() {
+			lines = append(lines, c.Lines...)
+			continue
+		}
+
+		ours, theirs := sideLines(c.Ours), sideLines(c.Theirs)
+		var suffix []string
+
+		if style == ConflictZdiff3 {
+			// Linhas iguais nas pontas dos dois lados ficam fora do conflito
+			prefix := commonPrefix(ours, theirs)
+			lines = append(lines, ours[:prefix]...)
+			ours, theirs = ours[prefix:], theirs[prefix:]
+
+			n := commonSuffix(ours, theirs)
+			suffix = ours[len(ours)-n:]
+			ours, theirs = ours[:len(ours)-n], theirs[:len(theirs)-n]
+		}
+
+		lines = append(lines, markerOurs+" "+m.Labels.Ours)
+		lines = append(lines, ours...)
+		if style != ConflictMerge {
+			lines = append(lines, markerBase+" "+m.Labels.Base)
+			lines = append(lines, sideLines(c.Base)...)
+		}
+		lines = append(lines, markerSep)
+		lines = append(lines, theirs...)
+		lines = append(lines, markerTheirs+" "+m.Labels.Theirs)
+		lines = append(lines, suffix...)
+	}
+
+	return joinLines(lines)
+}
+
+// Hunk retorna o bloco em conflito com o id informado.
+func (m *FileMerge) Hunk(id string) (*Chunk, error) {
+	for i := range m.Chunks {
+		if m.Chunks[i].Conflict && strings.EqualFold(m.Chunks[i].ID, id) {
+			return &m.Chunks[i], nil
+		}
+	}
+	return nil, fmt.Errorf("bloco de conflito %s não encontrado em %s", id, m.Path)
+}
+
+// sideLines retorna as linhas de um lado, vazio se o lado não existir.
+func sideLines(side *HunkSide) []string {
+	if side == nil {
+		return nil
+	}
+	return side.Lines
+}
+
+// parseConflictMarkers lê um conteúdo com marcadores de conflito do git (nos
+// estilos merge e diff3) e o converte em trechos estáveis e blocos em conflito.
+// É o inverso de Render; as posições dos lados são contadas no próprio conteúdo.
+func parseConflictMarkers(content string) []Chunk {
+	var chunks []Chunk
+	var stable []string
+	var conflict *Chunk
+	var section *[]string
+	var oursMarker, baseMarker string
+	oursPos, basePos, theirsPos := 0, 0, 0
+
+	flush := func() {
+		if len(stable) > 0 {
+			chunks = append(chunks, Chunk{Lines: stable})
+			stable = nil
+		}
+	}
+
+	for _, line := range splitLines(content) {
+		switch {
+		case conflict == nil && isConflictMarker(line, markerOurs):
+			flush()
+			conflict = &Chunk{Conflict: true, Ours: newHunkSide(nil, oursPos), Theirs: newHunkSide(nil, theirsPos)}
+			section = &conflict.Ours.Lines
+			oursMarker = line
+		case conflict != nil && section == &conflict.Ours.Lines && isConflictMarker(line, markerBase):
+			conflict.Base = newHunkSide(nil, basePos)
+			section = &conflict.Base.Lines
+			baseMarker = line
+		case conflict != nil && section != &conflict.Theirs.Lines && line == markerSep:
+			section = &conflict.Theirs.Lines
+		case conflict != nil && section == &conflict.Theirs.Lines && isConflictMarker(line, markerTheirs):
+			for _, side := range []*HunkSide{conflict.Ours, conflict.Base, conflict.Theirs} {
+				if side != nil {
+					side.Count = len(side.Lines)
+				}
+			}
+			oursPos += conflict.Ours.Count
+			theirsPos += conflict.Theirs.Count
+			if conflict.Base != nil {
+				basePos += conflict.Base.Count
+			}
+
+			chunks = append(chunks, *conflict)
+			conflict, section = nil, nil
+		case conflict != nil:
+			*section = append(*section, line)
+		default:
+			stable = append(stable, line)
+			oursPos++
+			basePos++
+			theirsPos++
+		}
+	}
+
+	// Um bloco sem o marcador final não é conflito: as linhas ficam como
+	// estão, com os marcadores originais
+	if conflict != nil {
+		stable = append(stable, oursMarker)
+		stable = append(stable, conflict.Ours.Lines...)
+		if conflict.Base != nil {
+			stable = append(stable, baseMarker)
+			stable = append(stable, conflict.Base.Lines...)
+		}
+		if section == &conflict.Theirs.Lines {
+			stable = append(stable, markerSep)
+			stable = append(stable, conflict.Theirs.Lines...)
+		}
+	}
+	flush()
+
+	return chunks
+}
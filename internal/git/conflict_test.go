@@ -0,0 +1,143 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeLinesSides(t *testing.T) {
+	base := splitLines("a\nb\nc\nd")
+	ours := splitLines("a\nO1\nO2\nc\nd")
+	theirs := splitLines("a\nT\nc\nd")
+
+	chunks := mergeLines(base, ours, theirs, DiffOptions{})
+	if len(chunks) != 3 || !chunks[1].Conflict {
+		t.Fatalf("mergeLines = %+v, esperado um conflito entre dois trechos estáveis", chunks)
+	}
+
+	conflict := chunks[1]
+	tests := []struct {
+		name string
+		side *HunkSide
+		want HunkSide
+	}{
+		{"ours", conflict.Ours, HunkSide{Lines: []string{"O1", "O2"}, Start: 2, Count: 2}},
+		{"base", conflict.Base, HunkSide{Lines: []string{"b"}, Start: 2, Count: 1}},
+		{"theirs", conflict.Theirs, HunkSide{Lines: []string{"T"}, Start: 2, Count: 1}},
+	}
+
+	for _, tt := range tests {
+		if !reflect.DeepEqual(*tt.side, tt.want) {
+			t.Errorf("%s = %+v, esperado %+v", tt.name, *tt.side, tt.want)
+		}
+	}
+}
+
+func TestFileMergeHunks(t *testing.T) {
+	conflict := func(ours, theirs string) Chunk {
+		return Chunk{
+			Conflict: true,
+			Ours:     &HunkSide{Lines: []string{ours}},
+			Base:     &HunkSide{Lines: []string{"base"}},
+			Theirs:   &HunkSide{Lines: []string{theirs}},
+		}
+	}
+
+	merge := newFileMerge("a.txt", ConflictLabels{Ours: "ours", Base: "base", Theirs: "theirs"}, []Chunk{
+		conflict("o", "t"),
+		{Lines: []string{"meio"}},
+		conflict("o", "t"),
+		conflict("x", "y"),
+	})
+
+	if merge.Conflicts != 3 {
+		t.Fatalf("conflitos = %d, esperado 3", merge.Conflicts)
+	}
+
+	first, second, third := merge.Chunks[0].ID, merge.Chunks[2].ID, merge.Chunks[3].ID
+	if len(first) != 12 || second != first+"-2" || third == first {
+		t.Fatalf("ids = %q, %q, %q", first, second, third)
+	}
+
+	// O id depende só do conteúdo do bloco
+	again := newFileMerge("b.txt", ConflictLabels{}, []Chunk{conflict("o", "t")})
+	if again.Chunks[0].ID != first {
+		t.Fatalf("id = %q, esperado %q", again.Chunks[0].ID, first)
+	}
+
+	tests := []struct {
+		id   string
+		want *Chunk
+	}{
+		{first, &merge.Chunks[0]},
+		{strings.ToUpper(second), &merge.Chunks[2]},
+		{third, &merge.Chunks[3]},
+		{"inexistente", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := merge.Hunk(tt.id)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("Hunk(%q) não retornou erro", tt.id)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Hunk(%q) = %p, %v; esperado %p", tt.id, got, err, tt.want)
+		}
+	}
+}
+
+func TestFileMergeClone(t *testing.T) {
+	merge := newFileMerge("a.txt", ConflictLabels{}, []Chunk{{
+		Conflict: true,
+		Ours:     &HunkSide{Lines: []string{"o"}},
+		Theirs:   &HunkSide{Lines: []string{"t"}},
+	}})
+	merge.Binary = &BinaryConflict{}
+	merge.Tree = &TreeConflict{Kind: TreeModifyDelete}
+	merge.Submodule = &SubmoduleConflict{Ours: "a", Theirs: "b"}
+	merge.Mode = &ModeConflict{Ours: ModeRegular, Theirs: ModeExecutable}
+
+	clone := merge.Clone()
+	clone.Chunks[0].Resolution = ResolveOurs
+	clone.Binary.Resolution = ResolveOurs
+	clone.Tree.Resolution = ResolveDelete
+	clone.Submodule.Resolution = ResolveOurs
+	clone.Mode.Resolution = ResolveModeOurs
+
+	if merge.Chunks[0].Resolution != "" || merge.Binary.Resolution != "" || merge.Tree.Resolution != "" ||
+		merge.Submodule.Resolution != "" || merge.Mode.Resolution != "" {
+		t.Fatalf("a cópia alterou o original: %+v", merge)
+	}
+	if clone.Chunks[0].Ours != merge.Chunks[0].Ours {
+		t.Fatalf("as versões dos lados não são compartilhadas")
+	}
+}
+
+func TestMergeFile(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": "um\ndois\ntrês\nquatro\ncinco\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"a.txt": "UM\ndois\ntrês\nquatro\nfeature\n"})
+	repo.commit("main", map[string]string{"a.txt": "um\ndois\ntrês\nquatro\nmain\n"})
+
+	merge, err := repo.control.MergeFile("feature", "main", "a.txt", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{Disabled: true})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+
+	if merge.Conflicts != 1 || merge.Path != "a.txt" {
+		t.Fatalf("MergeFile = %+v", merge)
+	}
+
+	want := "UM\ndois\ntrês\nquatro\n<<<<<<< " + merge.Labels.Ours + "\nfeature\n=======\nmain\n>>>>>>> " + merge.Labels.Theirs + "\n"
+	if got := merge.Render(ConflictMerge); got != want {
+		t.Fatalf("Render =\n%s\nesperado\n%s", got, want)
+	}
+	if !strings.HasPrefix(merge.Labels.Ours, "feature (") || !strings.HasPrefix(merge.Labels.Theirs, "main (") {
+		t.Fatalf("rótulos inesperados: %+v", merge.Labels)
+	}
+}
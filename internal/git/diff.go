@@ -0,0 +1,235 @@
+package git
+
+import "strings"
+
+// lineMatch representa um par de linhas iguais entre duas versões de um arquivo.
+// a é o índice da linha na primeira versão e b o índice na segunda.
+type lineMatch struct {
+	a int
+	b int
+}
+
+// diffHunk representa um trecho alterado entre a versão base e um dos lados.
+// As linhas base[baseStart:baseEnd] foram trocadas por side[sideStart:sideEnd].
+type diffHunk struct {
+	baseStart int
+	baseEnd   int
+	sideStart int
+	sideEnd   int
+}
+
+// splitLines divide o conteúdo em linhas usando "\n" como separador.
+// A junção do resultado com "\n" reproduz exatamente o conteúdo original.
+func splitLines(content string) []string {
+	return strings.Split(content, "\n")
+}
+
+// joinLines é o inverso de splitLines.
+func joinLines(lines []string) string {
+	return strings.Join(lines, "\n")
+}
+
+// diffLines calcula a maior subsequência comum entre a e b usando o algoritmo
+// de Myers (O((N+M)D)) e retorna os pares de linhas iguais em ordem crescente.
+func diffLines(a, b []string) []lineMatch {
+	// Converte as linhas em inteiros para acelerar as comparações
+	ids := make(map[string]int)
+	toIDs := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	x := toIDs(a)
+	y := toIDs(b)
+
+	// Remove prefixo e sufixo comuns, que são a maior parte dos arquivos reais
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var matches []lineMatch
+	for i := 0; i < prefix; i++ {
+		matches = append(matches, lineMatch{a: i, b: i})
+	}
+
+	middle := myers(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])
+	for _, m := range middle {
+		matches = append(matches, lineMatch{a: m.a + prefix, b: m.b + prefix})
+	}
+
+	for i := 0; i < suffix; i++ {
+		matches = append(matches, lineMatch{a: len(x) - suffix + i, b: len(y) - suffix + i})
+	}
+
+	return matches
+}
+
+// myers implementa a versão de espaço linear do algoritmo de Myers: encontra
+// a "cobra do meio" do caminho de edição, avançando do início e do fim ao
+// mesmo tempo, e resolve recursivamente os trechos antes e depois dela. A
+// memória usada é O(N+M), independente da quantidade de diferenças.
+func myers(x, y []int) []lineMatch {
+	n, m := len(x), len(y)
+	if n == 0 || m == 0 {
+		return nil
+	}
+
+	s := &myersState{
+		x:      x,
+		y:      y,
+		offset: n + m + 1,
+		vf:     make([]int, 2*(n+m)+3),
+		vb:     make([]int, 2*(n+m)+3),
+	}
+	s.compare(0, n, 0, m)
+
+	return s.matches
+}
+
+// myersState guarda as sequências, os vetores de cada direção, reaproveitados
+// entre as chamadas, e os pares de linhas iguais em ordem crescente.
+type myersState struct {
+	x, y    []int
+	offset  int
+	vf, vb  []int
+	matches []lineMatch
+}
+
+// compare encontra as linhas iguais entre x[a0:a1] e y[b0:b1].
+func (s *myersState) compare(a0, a1, b0, b1 int) {
+	// Prefixo e sufixo comuns entram direto
+	for a0 < a1 && b0 < b1 && s.x[a0] == s.y[b0] {
+		s.matches = append(s.matches, lineMatch{a: a0, b: b0})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1-suffix > a0 && b1-suffix > b0 && s.x[a1-suffix-1] == s.y[b1-suffix-1] {
+		suffix++
+	}
+	a1 -= suffix
+	b1 -= suffix
+
+	if a0 < a1 && b0 < b1 {
+		xs, ys, xe, ye := s.middleSnake(a0, a1, b0, b1)
+		s.compare(a0, xs, b0, ys)
+		for i := 0; xs+i < xe; i++ {
+			s.matches = append(s.matches, lineMatch{a: xs + i, b: ys + i})
+		}
+		s.compare(xe, a1, ye, b1)
+	}
+
+	for i := 0; i < suffix; i++ {
+		s.matches = append(s.matches, lineMatch{a: a1 + i, b: b1 + i})
+	}
+}
+
+// middleSnake retorna o início e o fim, em posições absolutas, da cobra do
+// meio do caminho de edição mais curto entre x[a0:a1] e y[b0:b1]. Os dois
+// trechos precisam ser não vazios e começar e terminar com linhas diferentes.
+func (s *myersState) middleSnake(a0, a1, b0, b1 int) (xs, ys, xe, ye int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	off := s.offset
+	vf, vb := s.vf, s.vb
+
+	// vb guarda quanto o caminho de trás para frente avançou em x, a partir
+	// do fim; a diagonal k do caminho para frente é a delta-k do de trás
+	vf[off+1], vb[off+1] = 0, 0
+	for d := 0; d <= (n+m+1)/2; d++ {
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && vf[off+k-1] < vf[off+k+1]) {
+				i = vf[off+k+1]
+			} else {
+				i = vf[off+k-1] + 1
+			}
+			j := i - k
+			startI, startJ := i, j
+			for i < n && j < m && s.x[a0+i] == s.y[b0+j] {
+				i++
+				j++
+			}
+			vf[off+k] = i
+
+			if kb := delta - k; odd && kb >= -(d-1) && kb <= d-1 && i+vb[off+kb] >= n {
+				return a0 + startI, b0 + startJ, a0 + i, b0 + j
+			}
+		}
+
+		for kb := -d; kb <= d; kb += 2 {
+			var u int
+			if kb == -d || (kb != d && vb[off+kb-1] < vb[off+kb+1]) {
+				u = vb[off+kb+1]
+			} else {
+				u = vb[off+kb-1] + 1
+			}
+			w := u - kb
+			startU, startW := u, w
+			for u < n && w < m && s.x[a1-u-1] == s.y[b1-w-1] {
+				u++
+				w++
+			}
+			vb[off+kb] = u
+
+			if k := delta - kb; !odd && k >= -d && k <= d && u+vf[off+k] >= n {
+				return a1 - u, b1 - w, a1 - startU, b1 - startW
+			}
+		}
+	}
+
+	// Inalcançável: os caminhos sempre se encontram até (n+m+1)/2
+	return a0, b0, a0, b0
+}
+
+// diffHunks converte os pares de linhas iguais em trechos alterados entre
+// base e side.
+func diffHunks(base, side []string) []diffHunk {
+	matches := diffLines(base, side)
+
+	var hunks []diffHunk
+	bi, si := 0, 0
+
+	// Sentinela no fim dos dois arquivos para fechar o último trecho
+	matches = append(matches, lineMatch{a: len(base), b: len(side)})
+	for _, m := range matches {
+		if m.a > bi || m.b > si {
+			hunks = append(hunks, diffHunk{
+				baseStart: bi,
+				baseEnd:   m.a,
+				sideStart: si,
+				sideEnd:   m.b,
+			})
+		}
+		bi = m.a + 1
+		si = m.b + 1
+	}
+
+	return hunks
+}
+
+// equalLines informa se dois slices de linhas são idênticos.
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,321 @@
+package git
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// lcsLength calcula o tamanho da maior subsequência comum por programação
+// dinâmica, para conferir o resultado de diffLines.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// checkMatches confere que os pares são linhas iguais, em ordem crescente nos
+// dois lados, e formam uma subsequência comum máxima.
+func checkMatches(t *testing.T, a, b []string, matches []lineMatch) {
+	t.Helper()
+
+	last := lineMatch{a: -1, b: -1}
+	for _, m := range matches {
+		if m.a <= last.a || m.b <= last.b {
+			t.Fatalf("pares fora de ordem: %v", matches)
+		}
+		if a[m.a] != b[m.b] {
+			t.Fatalf("par %v liga linhas diferentes: %q e %q", m, a[m.a], b[m.b])
+		}
+		last = m
+	}
+
+	if want := lcsLength(a, b); len(matches) != want {
+		t.Fatalf("diffLines encontrou %d linhas iguais, esperado %d", len(matches), want)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"iguais", "a\nb\nc", "a\nb\nc"},
+		{"vazios", "", ""},
+		{"primeiro vazio", "", "a\nb"},
+		{"segundo vazio", "a\nb", ""},
+		{"inserção no meio", "a\nc", "a\nb\nc"},
+		{"remoção no início", "a\nb\nc", "b\nc"},
+		{"troca no fim", "a\nb\nc", "a\nb\nd"},
+		{"tudo diferente", "a\nb\nc", "x\ny\nz"},
+		{"linhas repetidas", "a\nb\na\nb\na", "b\na\nb\na\nb"},
+		{"blocos trocados", "a\nb\nc\nd\ne\nf", "d\ne\nf\na\nb\nc"},
+		{"abcabba", "a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := splitLines(tt.a), splitLines(tt.b)
+			checkMatches(t, a, b, diffLines(a, b))
+		})
+	}
+}
+
+func TestDiffLinesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		checkMatches(t, a, b, diffLines(a, b))
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	// Arquivos sem nenhuma linha em comum são o pior caso do algoritmo
+	a, b := make([]string, 5000), make([]string, 5000)
+	for i := range a {
+		a[i] = "a" + strings.Repeat("x", i%7)
+		b[i] = "b" + strings.Repeat("y", i%5)
+	}
+
+	if matches := diffLines(a, b); len(matches) != 0 {
+		t.Fatalf("diffLines encontrou %d linhas iguais, esperado 0", len(matches))
+	}
+}
+
+func TestDiffLinesInterleaved(t *testing.T) {
+	// Arquivos grandes com muitas diferenças: metade das linhas de cada lado
+	// só existe nele, e a outra metade é comum aos dois, na mesma ordem
+	const n = 10000
+	a, b := make([]string, 0, 2*n), make([]string, 0, 2*n)
+	for i := 0; i < n; i++ {
+		common := fmt.Sprintf("comum %d", i)
+		a = append(a, common, fmt.Sprintf("a %d", i))
+		b = append(b, fmt.Sprintf("b %d", i), common)
+	}
+
+	matches := diffLines(a, b)
+	if len(matches) != n {
+		t.Fatalf("diffLines encontrou %d linhas iguais, esperado %d", len(matches), n)
+	}
+	for i, m := range matches {
+		if m.a != 2*i || m.b != 2*i+1 {
+			t.Fatalf("par %d = %v, esperado {%d %d}", i, m, 2*i, 2*i+1)
+		}
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	tests := []struct {
+		name       string
+		base, side string
+		want       []diffHunk
+	}{
+		{"sem alterações", "a\nb", "a\nb", nil},
+		{"inserção", "a\nc", "a\nb\nc", []diffHunk{{1, 1, 1, 2}}},
+		{"remoção", "a\nb\nc", "a\nc", []diffHunk{{1, 2, 1, 1}}},
+		{"troca", "a\nb\nc", "a\nx\nc", []diffHunk{{1, 2, 1, 2}}},
+		{"dois trechos", "a\nb\nc\nd", "x\nb\nc\ny", []diffHunk{{0, 1, 0, 1}, {3, 4, 3, 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffHunks(splitLines(tt.base), splitLines(tt.side))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("diffHunks = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeContents(t *testing.T) {
+	labels := ConflictLabels{Ours: "ours", Base: "base", Theirs: "theirs"}
+
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		style              ConflictStyle
+		want               string
+		conflicts          int
+	}{
+		{
+			name: "só ours alterou",
+			base: "a\nb\nc", ours: "a\nB\nc", theirs: "a\nb\nc",
+			want: "a\nB\nc",
+		},
+		{
+			name: "só theirs alterou",
+			base: "a\nb\nc", ours: "a\nb\nc", theirs: "a\nb\nC",
+			want: "a\nb\nC",
+		},
+		{
+			name: "alterações em trechos distantes",
+			base: "a\nb\nc\nd\ne", ours: "A\nb\nc\nd\ne", theirs: "a\nb\nc\nd\nE",
+			want: "A\nb\nc\nd\nE",
+		},
+		{
+			name: "mesma alteração nos dois lados",
+			base: "a\nb\nc", ours: "a\nX\nc", theirs: "a\nX\nc",
+			want: "a\nX\nc",
+		},
+		{
+			name: "conflito",
+			base: "a\nb\nc", ours: "a\nO\nc", theirs: "a\nT\nc",
+			want:      "a\n<<<<<<< ours\nO\n=======\nT\n>>>>>>> theirs\nc",
+			conflicts: 1,
+		},
+		{
+			name: "conflito em diff3",
+			base: "a\nb\nc", ours: "a\nO\nc", theirs: "a\nT\nc",
+			style:     ConflictDiff3,
+			want:      "a\n<<<<<<< ours\nO\n||||||| base\nb\n=======\nT\n>>>>>>> theirs\nc",
+			conflicts: 1,
+		},
+		{
+			name: "zdiff3 tira as linhas iguais das pontas",
+			base: "a\nb\nc", ours: "a\nx\nO\ny\nc", theirs: "a\nx\nT\ny\nc",
+			style:     ConflictZdiff3,
+			want:      "a\nx\n<<<<<<< ours\nO\n||||||| base\nb\n=======\nT\n>>>>>>> theirs\ny\nc",
+			conflicts: 1,
+		},
+		{
+			name: "remoção e alteração da mesma linha",
+			base: "a\nb\nc", ours: "a\nc", theirs: "a\nB\nc",
+			want:      "a\n<<<<<<< ours\n=======\nB\n>>>>>>> theirs\nc",
+			conflicts: 1,
+		},
+		{
+			name: "inserções no mesmo ponto",
+			base: "a\nc", ours: "a\nO\nc", theirs: "a\nT\nc",
+			want:      "a\n<<<<<<< ours\nO\n=======\nT\n>>>>>>> theirs\nc",
+			conflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := mergeContents(tt.base, tt.ours, tt.theirs, tt.style, labels)
+			if got != tt.want {
+				t.Errorf("mergeContents =\n%s\nesperado\n%s", got, tt.want)
+			}
+			if conflicts != tt.conflicts {
+				t.Errorf("conflitos = %d, esperado %d", conflicts, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestDiffSpecificFile(t *testing.T) {
+	repo := newTestRepo(t)
+	fork := repo.commit("main", map[string]string{"a.txt": "um\ndois\ntrês\nquatro\ncinco\n"})
+	repo.setBranch("feature", fork)
+	feature := repo.commit("feature", map[string]string{"a.txt": "UM\ndois\ntrês\nquatro\nfeature\n"})
+	main := repo.commit("main", map[string]string{"a.txt": "um\ndois\ntrês\nquatro\nmain\n"})
+
+	short := func(hash plumbing.Hash) string { return hash.String()[:7] }
+
+	tests := []struct {
+		style ConflictStyle
+		want  string
+	}{
+		{ConflictMerge, "UM\ndois\ntrês\nquatro\n<<<<<<< feature (" + short(feature) + ")\nfeature\n=======\nmain\n>>>>>>> main (" + short(main) + ")\n"},
+		{ConflictDiff3, "UM\ndois\ntrês\nquatro\n<<<<<<< feature (" + short(feature) + ")\nfeature\n||||||| merge-base (" + short(fork) + ")\ncinco\n=======\nmain\n>>>>>>> main (" + short(main) + ")\n"},
+	}
+
+	for _, tt := range tests {
+		diff, err := repo.control.DiffSpecificFile("feature", "main", "a.txt", CompareMergeBase, RenameOptions{}, tt.style, DiffOptions{}, AutoResolveOptions{Disabled: true})
+		if err != nil {
+			t.Fatalf("DiffSpecificFile: %v", err)
+		}
+		if got := diff["a.txt"]; got != tt.want {
+			t.Errorf("DiffSpecificFile =\n%s\nesperado\n%s", got, tt.want)
+		}
+	}
+}
+
+func TestDiffOutputWithBranch(t *testing.T) {
+	repo := newTestRepo(t)
+	main := repo.commit("main", map[string]string{
+		".gitattributes": "*.log merge=union\n",
+		"a.txt":          "um\ndois\ntrês\n",
+		"b.log":          "um\nmain\n",
+		"c.bin":          "a\x00b",
+		"igual.txt":      "igual\n",
+	})
+
+	output := t.TempDir()
+	for name, content := range map[string]string{
+		"a.txt":     "um\nDOIS\ntrês\n",
+		"b.log":     "um\noutput\n",
+		"c.bin":     "a\x00c",
+		"igual.txt": "igual\n",
+		"novo.txt":  "só no output\n",
+	} {
+		if err := os.WriteFile(filepath.Join(output, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diffs, err := repo.control.DiffOutputWithBranch("main", output, DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffOutputWithBranch: %v", err)
+	}
+
+	// b.log passa pelo driver union e não tem conflito
+	label := "main (" + main.String()[:7] + ")"
+	want := map[string]string{
+		"a.txt": "um\n<<<<<<< output\nDOIS\n=======\ndois\n>>>>>>> " + label + "\ntrês\n",
+		"b.log": "um\noutput\nmain\n",
+		"c.bin": "arquivos binários diferentes",
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("DiffOutputWithBranch = %q, esperado %q", diffs, want)
+	}
+}
+
+func TestParseConflictStyle(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  ConflictStyle
+		fails bool
+	}{
+		{name: "", want: ConflictMerge},
+		{name: "merge", want: ConflictMerge},
+		{name: "diff3", want: ConflictDiff3},
+		{name: "zdiff3", want: ConflictZdiff3},
+		{name: "outro", fails: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseConflictStyle(tt.name)
+		if (err != nil) != tt.fails || got != tt.want {
+			t.Errorf("ParseConflictStyle(%q) = %v, %v", tt.name, got, err)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/format/config"
+)
+
+// MergeInput são as três versões de um arquivo entregues a um MergeDriver.
+type MergeInput struct {
+	Path   string
+	Base   string
+	Ours   string
+	Theirs string
+
+	// HasBase é false quando não existe ancestral comum; Base fica vazio.
+	HasBase bool
+
+	// Options são as diferenças ignoradas ao comparar linhas (ver DiffOptions).
+	Options DiffOptions
+
+	// WorkDir é o diretório de trabalho do repositório, vazio se não houver.
+	WorkDir string
+}
+
+// MergeDriver faz o merge de um tipo de arquivo. O resultado são os trechos
+// estáveis e os blocos em conflito, como os de mergeLines; blocos que o driver
+// resolveu sozinho vêm com Resolution preenchido.
+type MergeDriver interface {
+	Merge(input MergeInput) ([]Chunk, error)
+}
+
+// MergeDriverFunc permite usar uma função como MergeDriver.
+type MergeDriverFunc func(input MergeInput) ([]Chunk, error)
+
+func (f MergeDriverFunc) Merge(input MergeInput) ([]Chunk, error) {
+	return f(input)
+}
+
+// DefaultDriver é o driver usado quando nenhum outro se aplica: o merge de
+// três vias linha a linha.
+const DefaultDriver = "text"
+
+// driverPattern associa um padrão de caminho a um driver.
+type driverPattern struct {
+	pattern string
+	name    string
+}
+
+// drivers guarda os drivers registrados por nome e os padrões de caminho que
+// os selecionam.
+var drivers = struct {
+	sync.RWMutex
+	byName   map[string]MergeDriver
+	patterns []driverPattern
+}{byName: make(map[string]MergeDriver)}
+
+func init() {
+	RegisterMergeDriver(DefaultDriver, MergeDriverFunc(textMerge))
+	RegisterMergeDriver(StrategyUnion, MergeDriverFunc(unionMerge))
+	RegisterMergeDriver(StrategyOurs, MergeDriverFunc(oursMerge))
+}
+
+// RegisterMergeDriver registra um driver com o nome usado em merge=<nome> nos
+// .gitattributes e em RegisterMergeDriverPattern. Registrar de novo um nome
+// substitui o driver anterior.
+func RegisterMergeDriver(name string, driver MergeDriver) {
+	drivers.Lock()
+	defer drivers.Unlock()
+
+	drivers.byName[name] = driver
+}
+
+// RegisterMergeDriverPattern faz os caminhos que casam com pattern usarem o
+// driver informado quando os .gitattributes não definem um. Padrões sem "/"
+// são comparados com o nome do arquivo, como "*.json"; os demais com o caminho
+// inteiro. Vale o primeiro padrão registrado que casar.
+func RegisterMergeDriverPattern(pattern, name string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("padrão inválido %s: %w", pattern, err)
+	}
+
+	drivers.Lock()
+	defer drivers.Unlock()
+
+	drivers.patterns = append(drivers.patterns, driverPattern{pattern: pattern, name: name})
+	return nil
+}
+
+// registeredDriver retorna o driver registrado com o nome informado.
+func registeredDriver(name string) (MergeDriver, bool) {
+	drivers.RLock()
+	defer drivers.RUnlock()
+
+	driver, ok := drivers.byName[name]
+	return driver, ok
+}
+
+// patternDriver retorna o nome do driver do primeiro padrão que casa com o
+// caminho, vazio se nenhum casar.
+func patternDriver(name string) string {
+	drivers.RLock()
+	defer drivers.RUnlock()
+
+	for _, p := range drivers.patterns {
+		if matchPath(p.pattern, name) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+// matchPath informa se o caminho casa com o padrão. Padrões sem "/" são
+// comparados com o nome do arquivo; os demais com o caminho inteiro.
+func matchPath(pattern, name string) bool {
+	target := name
+	if !strings.Contains(pattern, "/") {
+		target = path.Base(name)
+	}
+	ok, _ := path.Match(pattern, target)
+	return ok
+}
+
+// mergeDriver escolhe o driver de um arquivo: o de merge=<nome> nos
+// .gitattributes; depois o da seção merge.<nome> da configuração do
+// repositório cujo merge.<nome>.path casa com o caminho; depois o do padrão de
+// caminho registrado; por fim o DefaultDriver. Cada nome vale como driver
+// registrado ou configurado no repositório (configuredDriver). Um
+// merge=<nome> sem driver conhecido usa o DefaultDriver, como no git.
+// Retorna o driver e o nome dele.
+func (e *Control) mergeDriver(fileName string, rules mergeRules) (MergeDriver, string, error) {
+	cfg, err := e.repository.Config()
+	if err != nil {
+		return nil, "", fmt.Errorf("erro ao ler configuração do repositório: %w", err)
+	}
+	merge := cfg.Raw.Section("merge")
+
+	configured := ""
+	for _, sub := range merge.Subsections {
+		for _, pattern := range sub.OptionAll("path") {
+			if configured == "" && matchPath(pattern, fileName) {
+				configured = sub.Name
+			}
+		}
+	}
+
+	for _, name := range []string{rules.strategy, configured, patternDriver(fileName)} {
+		if name == "" {
+			continue
+		}
+
+		if driver, ok := registeredDriver(name); ok {
+			return driver, name, nil
+		}
+
+		driver, err := configuredDriver(merge.Subsection(name))
+		if err != nil {
+			return nil, "", err
+		}
+		if driver != nil {
+			return driver, name, nil
+		}
+	}
+
+	driver, _ := registeredDriver(DefaultDriver)
+	return driver, DefaultDriver, nil
+}
+
+// configuredDriver retorna o driver de uma seção merge.<nome> da configuração
+// do repositório: o comando externo de merge.<nome>.driver ou, com
+// merge.<nome>.strategy = append, a StrategyAppend com as opções
+// merge.<nome>.sortkey e merge.<nome>.sortorder. Nil se a seção não definir
+// um driver.
+func configuredDriver(sub *config.Subsection) (MergeDriver, error) {
+	if command := sub.Option("driver"); command != "" {
+		return NewCommandDriver(command), nil
+	}
+
+	switch strategy := sub.Option("strategy"); strategy {
+	case "":
+		return nil, nil
+	case StrategyAppend:
+		opts, err := ParseAppendOptions(sub.Option("sortkey"), sub.Option("sortorder"))
+		if err != nil {
+			return nil, fmt.Errorf("erro na configuração merge.%s: %w", sub.Name, err)
+		}
+		return NewAppendDriver(opts), nil
+	default:
+		return nil, fmt.Errorf("estratégia desconhecida em merge.%s.strategy: %s", sub.Name, strategy)
+	}
+}
+
+// mergeWithDriver faz o merge de um arquivo com o driver escolhido para ele.
+// Retorna os trechos e o nome do driver.
+func (e *Control) mergeWithDriver(input MergeInput, rules mergeRules) ([]Chunk, string, error) {
+	driver, name, err := e.mergeDriver(input.Path, rules)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if input.WorkDir == "" {
+		if worktree, err := e.repository.Worktree(); err == nil {
+			input.WorkDir = worktree.Filesystem.Root()
+		}
+	}
+
+	chunks, err := driver.Merge(input)
+	if err != nil {
+		return nil, "", fmt.Errorf("erro no driver de merge %s para %s: %w", name, input.Path, err)
+	}
+
+	return chunks, name, nil
+}
+
+// textMerge é o DefaultDriver: merge de três vias linha a linha, ou diff de
+// duas vias quando não existe ancestral comum.
+func textMerge(input MergeInput) ([]Chunk, error) {
+	ours, theirs := splitLines(input.Ours), splitLines(input.Theirs)
+	if !input.HasBase {
+		return diffChunks(ours, theirs, input.Options), nil
+	}
+	return mergeLines(splitLines(input.Base), ours, theirs, input.Options), nil
+}
+
+// unionMerge é o driver de merge=union: os blocos em conflito ficam com as
+// linhas dos dois lados, primeiro as de ours.
+func unionMerge(input MergeInput) ([]Chunk, error) {
+	chunks, _ := textMerge(input)
+	for i := range chunks {
+		if chunks[i].Pending() {
+			chunks[i].Resolution = ResolveAutoUnion
+			chunks[i].Lines = append(append([]string(nil), sideLines(chunks[i].Ours)...), sideLines(chunks[i].Theirs)...)
+		}
+	}
+	return chunks, nil
+}
+
+// oursMerge é o driver de merge=ours: os blocos em conflito ficam com a
+// versão de ours.
+func oursMerge(input MergeInput) ([]Chunk, error) {
+	chunks, _ := textMerge(input)
+	for i := range chunks {
+		if chunks[i].Pending() {
+			chunks[i].Resolution = ResolveAutoKeepOurs
+			chunks[i].Lines = append([]string(nil), sideLines(chunks[i].Ours)...)
+		}
+	}
+	return chunks, nil
+}
+
+// commandTimeout é o tempo máximo de execução de um driver externo.
+var commandTimeout = time.Minute
+
+// commandDriver executa um comando externo, como os drivers configurados em
+// merge.<nome>.driver no git.
+type commandDriver struct {
+	command string
+}
+
+// NewCommandDriver cria um driver que executa um comando externo no
+// diretório de trabalho do repositório, via sh. No comando, %O, %A e %B são
+// trocados pelos arquivos temporários com as versões base, ours e theirs,
+// %L pelo tamanho dos marcadores de conflito e %P pelo caminho do arquivo.
+// O comando grava o resultado no arquivo de %A e termina com status zero se
+// não restarem conflitos; os marcadores deixados no resultado viram blocos
+// em conflito.
+func NewCommandDriver(command string) MergeDriver {
+	return &commandDriver{command: command}
+}
+
+func (d *commandDriver) Merge(input MergeInput) ([]Chunk, error) {
+	dir, err := os.MkdirTemp("", "gitmerge-driver-")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório temporário: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{"%O": input.Base, "%A": input.Ours, "%B": input.Theirs}
+	paths := make(map[string]string, len(files))
+	for placeholder, content := range files {
+		name := dir + "/" + strings.TrimPrefix(placeholder, "%")
+		if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+			return nil, fmt.Errorf("erro ao gravar arquivo temporário: %w", err)
+		}
+		paths[placeholder] = name
+	}
+
+	command := strings.NewReplacer(
+		"%O", shellQuote(paths["%O"]),
+		"%A", shellQuote(paths["%A"]),
+		"%B", shellQuote(paths["%B"]),
+		"%L", fmt.Sprint(len(markerOurs)),
+		"%P", shellQuote(input.Path),
+	).Replace(d.command)
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = input.WorkDir
+	output, runErr := cmd.CombinedOutput()
+
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("o comando %s excedeu o tempo limite de %s", d.command, commandTimeout)
+	}
+
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return nil, fmt.Errorf("erro ao executar %s: %w", d.command, runErr)
+	}
+
+	// Como no sh, 126 e 127 indicam que o comando não pôde ser executado
+	if exitErr != nil && (exitErr.ExitCode() == 126 || exitErr.ExitCode() == 127) {
+		return nil, fmt.Errorf("erro ao executar %s: %s", d.command, strings.TrimSpace(string(output)))
+	}
+
+	result, err := os.ReadFile(paths["%A"])
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler o resultado do driver: %w", err)
+	}
+
+	chunks := parseConflictMarkers(string(result))
+	if exitErr != nil && countConflicts(chunks) == 0 {
+		// O comando indicou conflito sem deixar marcadores: o arquivo
+		// inteiro fica em conflito
+		return fileConflict(input), nil
+	}
+
+	return chunks, nil
+}
+
+// fileConflict retorna o arquivo inteiro como um único bloco em conflito.
+// As linhas finais iguais nas três versões, como a quebra de linha do fim do
+// arquivo, ficam fora do bloco.
+func fileConflict(input MergeInput) []Chunk {
+	ours, theirs := splitLines(input.Ours), splitLines(input.Theirs)
+	base := splitLines(input.Base)
+
+	n := commonSuffix(ours, theirs)
+	if input.HasBase {
+		n = min(n, commonSuffix(ours, base))
+	}
+	suffix := ours[len(ours)-n:]
+
+	chunk := Chunk{
+		Conflict: true,
+		Ours:     newHunkSide(ours[:len(ours)-n], 0),
+		Theirs:   newHunkSide(theirs[:len(theirs)-n], 0),
+	}
+	if input.HasBase {
+		chunk.Base = newHunkSide(base[:len(base)-n], 0)
+	}
+
+	chunks := []Chunk{chunk}
+	if n > 0 {
+		chunks = append(chunks, Chunk{Lines: suffix})
+	}
+	return chunks
+}
+
+// countConflicts conta os blocos em conflito pendentes.
+func countConflicts(chunks []Chunk) int {
+	n := 0
+	for i := range chunks {
+		if chunks[i].Pending() {
+			n++
+		}
+	}
+	return n
+}
+
+// shellQuote protege um argumento para o sh.
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
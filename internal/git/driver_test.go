@@ -0,0 +1,305 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chunkText resume os trechos de um merge para comparação: linhas estáveis
+// como estão e blocos em conflito como "<ours|base|theirs>".
+func chunkText(chunks []Chunk) []string {
+	var text []string
+	for _, chunk := range chunks {
+		if !chunk.Pending() {
+			text = append(text, chunk.Lines...)
+			continue
+		}
+		block := "<" + strings.Join(sideLines(chunk.Ours), ",")
+		if chunk.Base != nil {
+			block += "|" + strings.Join(chunk.Base.Lines, ",")
+		}
+		text = append(text, block+"|"+strings.Join(sideLines(chunk.Theirs), ",")+">")
+	}
+	return text
+}
+
+func TestParseConflictMarkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"sem conflitos", "a\nb\n", []string{"a", "b", ""}},
+		{
+			"estilo merge",
+			"a\n<<<<<<< ours\no1\no2\n=======\nt\n>>>>>>> theirs\nb",
+			[]string{"a", "<o1,o2|t>", "b"},
+		},
+		{
+			"estilo diff3",
+			"<<<<<<< ours\no\n||||||| base\nb\n=======\nt\n>>>>>>> theirs\nfim",
+			[]string{"<o|b|t>", "fim"},
+		},
+		{
+			"lado vazio",
+			"<<<<<<< ours\n=======\nt\n>>>>>>> theirs",
+			[]string{"<|t>"},
+		},
+		{
+			"separador fora de conflito",
+			"a\n=======\nb",
+			[]string{"a", "=======", "b"},
+		},
+		{
+			"marcadores sem rótulo",
+			"a\n<<<<<<<\no\n|||||||\nb\n=======\nt\n>>>>>>>\nc",
+			[]string{"a", "<o|b|t>", "c"},
+		},
+		{
+			"rótulo colado ao marcador não é marcador",
+			"<<<<<<<<\no\n=======\nt\n>>>>>>>>",
+			[]string{"<<<<<<<<", "o", "=======", "t", ">>>>>>>>"},
+		},
+		{
+			"bloco sem o marcador final",
+			"a\n<<<<<<< HEAD\no\n||||||| base comum\nb\n=======\nt",
+			[]string{"a", "<<<<<<< HEAD", "o", "||||||| base comum", "b", "=======", "t"},
+		},
+		{
+			"bloco sem rótulo e sem o marcador final",
+			"<<<<<<<\no",
+			[]string{"<<<<<<<", "o"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkText(parseConflictMarkers(tt.content)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseConflictMarkers = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConflictMarkersPositions(t *testing.T) {
+	content := "a\n<<<<<<< ours\no1\no2\n||||||| base\nb\n=======\nt\n>>>>>>> theirs\nc\n<<<<<<< ours\nx\n||||||| base\nz\n=======\ny\n>>>>>>> theirs"
+	chunks := parseConflictMarkers(content)
+	if len(chunks) != 4 {
+		t.Fatalf("parseConflictMarkers = %+v", chunks)
+	}
+
+	// As posições são contadas em cada lado, sem os marcadores
+	second := chunks[3]
+	if second.Ours.Start != 5 || second.Theirs.Start != 4 || second.Ours.Count != 1 {
+		t.Fatalf("posições do segundo bloco: ours %+v, theirs %+v", *second.Ours, *second.Theirs)
+	}
+
+	// Render desfaz o parse
+	merge := newFileMerge("a.txt", ConflictLabels{Ours: "ours", Base: "base", Theirs: "theirs"}, chunks)
+	if got := merge.Render(ConflictDiff3); got != content {
+		t.Fatalf("Render = %q, esperado %q", got, content)
+	}
+}
+
+func TestBuiltinDrivers(t *testing.T) {
+	input := MergeInput{Base: "a\nb\nc\n", Ours: "a\nO\nc\n", Theirs: "a\nT\nc\n", HasBase: true}
+
+	tests := []struct {
+		name       string
+		driver     MergeDriverFunc
+		want       []string
+		resolution string
+	}{
+		{DefaultDriver, textMerge, []string{"a", "<O|b|T>", "c", ""}, ""},
+		{StrategyUnion, unionMerge, []string{"a", "O", "T", "c", ""}, ResolveAutoUnion},
+		{StrategyOurs, oursMerge, []string{"a", "O", "c", ""}, ResolveAutoKeepOurs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := tt.driver(input)
+			if err != nil {
+				t.Fatalf("Merge: %v", err)
+			}
+			if got := chunkText(chunks); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Merge = %q, esperado %q", got, tt.want)
+			}
+			if chunks[1].Resolution != tt.resolution {
+				t.Fatalf("resolução = %q, esperado %q", chunks[1].Resolution, tt.resolution)
+			}
+		})
+	}
+
+	// Sem ancestral comum, o texto vira um diff de duas vias
+	chunks, _ := textMerge(MergeInput{Ours: "a\nO\n", Theirs: "a\nT\n"})
+	if got := chunkText(chunks); !reflect.DeepEqual(got, []string{"a", "<O|T>", ""}) {
+		t.Fatalf("textMerge sem base = %q", got)
+	}
+}
+
+func TestCommandDriver(t *testing.T) {
+	input := MergeInput{Path: "dir/it's.txt", Base: "a\nb\n", Ours: "a\nO\n", Theirs: "a\nT\n", HasBase: true, WorkDir: t.TempDir()}
+
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		fails   bool
+	}{
+		{"resultado de theirs", "cat %B > %A", []string{"a", "T", ""}, false},
+		{"três versões", "cat %O %B >> %A", []string{"a", "O", "a", "b", "a", "T", ""}, false},
+		{"caminho e marcadores", "echo %P %L > %A", []string{"dir/it's.txt 7", ""}, false},
+		{"diretório de trabalho", "test -z \"$(ls)\" && cat %B > %A", []string{"a", "T", ""}, false},
+		{
+			"marcadores no resultado", "printf '<<<<<<< a\\nx\\n=======\\ny\\n>>>>>>> b\\n' > %A; exit 1",
+			[]string{"<x|y>", ""}, false,
+		},
+		{"status de conflito sem marcadores", "exit 1", []string{"<a,O|a,b|a,T>", ""}, false},
+		{"comando inexistente", "comando-que-nao-existe %A", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := NewCommandDriver(tt.command).Merge(input)
+			if tt.fails {
+				if err == nil {
+					t.Fatalf("Merge não retornou erro")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Merge: %v", err)
+			}
+			if got := chunkText(chunks); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Merge = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandDriverTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { commandTimeout = timeout }(commandTimeout)
+	commandTimeout = 100 * time.Millisecond
+
+	_, err := NewCommandDriver("exec sleep 5").Merge(MergeInput{Ours: "a\n", WorkDir: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "tempo limite") {
+		t.Fatalf("Merge = %v, esperado erro de tempo limite", err)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+	}
+
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, esperado %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMergeDriverPrecedence(t *testing.T) {
+	named := func(name string) MergeDriverFunc {
+		return func(input MergeInput) ([]Chunk, error) {
+			return []Chunk{{Lines: []string{name}}}, nil
+		}
+	}
+	RegisterMergeDriver("teste-atributo", named("atributo"))
+	RegisterMergeDriver("teste-padrao", named("padrao"))
+	if err := RegisterMergeDriverPattern("*.precedencia", "teste-padrao"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterMergeDriverPattern("*.caminho", "teste-padrao"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterMergeDriverPattern("[", "teste-padrao"); err == nil {
+		t.Fatalf("RegisterMergeDriverPattern aceitou um padrão inválido")
+	}
+
+	repo := newTestRepo(t)
+	cfg, err := repo.repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Raw.Section("merge").Subsection("teste-config").SetOption("driver", "cat %B > %A")
+	cfg.Raw.Section("merge").Subsection("teste-caminho").
+		SetOption("path", "docs/*.caminho").
+		SetOption("strategy", StrategyAppend)
+	if err := repo.repo.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		strategy string
+		want     string
+	}{
+		{"atributo registrado", "a.precedencia", "teste-atributo", "teste-atributo"},
+		{"atributo configurado", "a.precedencia", "teste-config", "teste-config"},
+		{"atributo desconhecido usa o padrão", "a.precedencia", "desconhecido", "teste-padrao"},
+		{"padrão", "dir/b.precedencia", "", "teste-padrao"},
+		{"caminho configurado", "docs/a.caminho", "", "teste-caminho"},
+		{"atributo antes do caminho configurado", "docs/a.caminho", "teste-atributo", "teste-atributo"},
+		{"caminho configurado que não casa", "a.caminho", "", "teste-padrao"},
+		{"nenhum", "a.txt", "desconhecido", DefaultDriver},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, name, err := repo.control.mergeDriver(tt.file, mergeRules{strategy: tt.strategy})
+			if err != nil {
+				t.Fatalf("mergeDriver: %v", err)
+			}
+			if name != tt.want {
+				t.Fatalf("mergeDriver = %s, esperado %s", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeFileCommandDriver(t *testing.T) {
+	repo := newTestRepo(t)
+	cfg, err := repo.repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Raw.Section("merge").Subsection("theirs").SetOption("driver", "cat %B > %A")
+	cfg.Raw.Section("merge").Subsection("falha").SetOption("driver", "exit 1")
+	if err := repo.repo.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := "a.txt merge=theirs\nb.txt merge=falha\n"
+	repo.commit("main", map[string]string{".gitattributes": attrs, "a.txt": "1\n", "b.txt": "1\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{".gitattributes": attrs, "a.txt": "feature\n", "b.txt": "feature\n"})
+	repo.commit("main", map[string]string{".gitattributes": attrs, "a.txt": "main\n", "b.txt": "main\n"})
+
+	tests := []struct {
+		file      string
+		want      string
+		conflicts int
+	}{
+		{"a.txt", "main\n", 0},
+		{"b.txt", "", 1},
+	}
+
+	for _, tt := range tests {
+		merge, err := repo.control.MergeFile("feature", "main", tt.file, CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{Disabled: true})
+		if err != nil {
+			t.Fatalf("MergeFile(%s): %v", tt.file, err)
+		}
+		if merge.Conflicts != tt.conflicts {
+			t.Fatalf("MergeFile(%s): conflitos = %d, esperado %d", tt.file, merge.Conflicts, tt.conflicts)
+		}
+		if tt.conflicts == 0 && merge.Render(ConflictMerge) != tt.want {
+			t.Fatalf("MergeFile(%s) = %q, esperado %q", tt.file, merge.Render(ConflictMerge), tt.want)
+		}
+	}
+}
@@ -6,17 +6,28 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/utils/merkletrie"
 )
 
 // FileChange representa uma mudança em um arquivo
 type FileChange struct {
-	Path   string // Caminho do arquivo
-	Action string // "added", "modified", ou "deleted"
+	Path       string // Caminho do arquivo
+	Action     string // "added", "modified", "mode-changed", "deleted", "renamed", "copied" ou "conflict"
+	OldPath    string // Caminho de origem, para "renamed" e "copied"
+	Similarity int    // Similaridade com a origem em porcentagem, para "renamed" e "copied"
+	Generated  bool   // Arquivo gerado (linguist-generated nos .gitattributes)
+	Conflict   string // Tipo do conflito de árvore com a base, como "modify/delete"
+	Submodule  bool   // Submódulo (gitlink): o caminho guarda um commit de outro repositório
+	OldCommit  string // Commit do submódulo antes da alteração, para Submodule
+	NewCommit  string // Commit do submódulo depois da alteração, para Submodule
+	OldMode    string // Modo antes da alteração ("regular", "executable" ou "symlink"), quando o modo mudou
+	NewMode    string // Modo depois da alteração, quando o modo mudou
 }
 
 type Control struct {
@@ -167,51 +178,18 @@ func (e *Control) ListRemoteBranches() ([]string, error) {
 
 // GetModifiedFiles retorna todos os arquivos modificados ou adicionados em uma branch
 // comparando com uma branch base.
+// mode define se a comparação é feita com a ponta da base ou com o ancestral comum
+// e renames a detecção de renomeações e cópias.
 // Retorna um slice de strings com os caminhos dos arquivos e um erro, se houver.
-func (e *Control) GetModifiedFiles(branchName, branchBase string) ([]string, error) {
-	// Obtém a referência da branch alvo
-	targetRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchName)), true)
+func (e *Control) GetModifiedFiles(branchName, branchBase string, mode CompareMode, renames RenameOptions) ([]string, error) {
+	cmp, err := e.compareBranches(branchName, branchBase, mode, renames)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", branchName, err)
-	}
-
-	// Obtém a referência da branch base
-	baseRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchBase)), true)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", branchBase, err)
-	}
-
-	// Obtém os commits
-	targetCommit, err := e.repository.CommitObject(targetRef.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", branchName, err)
-	}
-
-	baseCommit, err := e.repository.CommitObject(baseRef.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", branchBase, err)
-	}
-
-	// Obtém as árvores de arquivos
-	targetTree, err := targetCommit.Tree()
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchName, err)
-	}
-
-	baseTree, err := baseCommit.Tree()
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchBase, err)
-	}
-
-	// Compara as árvores e obtém as diferenças
-	changes, err := targetTree.Diff(baseTree)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao comparar árvores: %w", err)
+		return nil, err
 	}
 
 	var modifiedFiles []string
 
-	for _, change := range changes {
+	for _, change := range cmp.changes {
 		action, err := change.Action()
 		if err != nil {
 			continue
@@ -226,23 +204,36 @@ func (e *Control) GetModifiedFiles(branchName, branchBase string) ([]string, err
 		}
 	}
 
+	// Inclui os caminhos com conflito de árvore, mesmo os removidos na sua branch
+	conflicts, err := e.comparisonConflicts(cmp)
+	if err != nil {
+		return nil, err
+	}
+	for _, conflict := range conflicts {
+		if !slices.Contains(modifiedFiles, conflict.Path) {
+			modifiedFiles = append(modifiedFiles, conflict.Path)
+		}
+	}
+
 	return modifiedFiles, nil
 }
 
 // DiffOutputWithBranch compara os arquivos da pasta output com a versão
-// dos mesmos arquivos na branch informada.
+// dos mesmos arquivos na branch informada. Diferenças ignoradas por opts
+// não geram diff, e os fins de linha de arquivos
+// com text ou eol nos .gitattributes da branch são normalizados. Arquivos
+// binários diferentes aparecem com uma mensagem no lugar do diff. O diff
+// passa pelo driver de merge do arquivo, como em MergeFile.
 // Retorna um map onde a chave é o caminho do arquivo e o valor é o diff.
-func (e *Control) DiffOutputWithBranch(branchName, outputDir string) (map[string]string, error) {
-	// Obtém a referência da branch
-	ref, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchName)), true)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", branchName, err)
+func (e *Control) DiffOutputWithBranch(branchName, outputDir string, opts DiffOptions) (map[string]string, error) {
+	if e.repository == nil {
+		return nil, fmt.Errorf("repositório não inicializado")
 	}
 
 	// Obtém o commit e a tree da branch
-	commit, err := e.repository.CommitObject(ref.Hash())
+	commit, err := e.resolveCommit(branchName)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", branchName, err)
+		return nil, err
 	}
 
 	tree, err := commit.Tree()
@@ -251,6 +242,7 @@ func (e *Control) DiffOutputWithBranch(branchName, outputDir string) (map[string
 	}
 
 	diffs := make(map[string]string)
+	attrs := newAttributes(tree)
 
 	// Percorre os arquivos da pasta output
 	err = filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
@@ -300,8 +292,35 @@ func (e *Control) DiffOutputWithBranch(branchName, outputDir string) (map[string
 			return nil
 		}
 
-		// Gera o diff entre os dois conteúdos
-		diff := generateDiff(branchContent, string(localContent))
+		rules, err := rulesFor(relPath, attrs)
+		if err != nil {
+			return err
+		}
+
+		if rules.binary || isBinaryContent(branchContent) || isBinaryContent(string(localContent)) {
+			diffs[relPath] = "arquivos binários diferentes"
+			return nil
+		}
+
+		local := string(localContent)
+		if rules.text {
+			local, branchContent = normalizeEOL(local), normalizeEOL(branchContent)
+		}
+
+		if opts.equal(splitLines(local), splitLines(branchContent)) {
+			return nil
+		}
+
+		// Gera o diff com o mesmo merge de MergeFile, sem ancestral comum
+		chunks, _, err := e.mergeWithDriver(MergeInput{Path: relPath, Ours: local, Theirs: branchContent, Options: opts}, rules)
+		if err != nil {
+			return err
+		}
+		diff := newFileMerge(relPath, ConflictLabels{
+			Ours:   "output",
+			Base:   "sem ancestral comum",
+			Theirs: revisionLabel(branchName, commit),
+		}, chunks).Render(ConflictMerge)
 		diffs[relPath] = diff
 
 		return nil
@@ -314,111 +333,20 @@ func (e *Control) DiffOutputWithBranch(branchName, outputDir string) (map[string
 	return diffs, nil
 }
 
-// generateDiff gera um diff linha por linha entre duas versões de um arquivo.
-// branchContent é a versão da branch (antigO), localContent é a versão do output (novo).
-// generateDiff gera um arquivo no formato de conflito git,
-// compatível com o Diff Editor do frontend.
-// branchContent = versão na branch remota (theirs)
-// localContent  = versão no output local (ours / HEAD)
-//func generateDiff(branchContent, localContent []byte) string {
-//	branchLines := strings.Split(string(branchContent), "\n")
-//	localLines := strings.Split(string(localContent), "\n")
-//
-//	var result strings.Builder
-//
-//	maxLen := len(branchLines)
-//	if len(localLines) > maxLen {
-//		maxLen = len(localLines)
-//	}
-//
-//	for i := 0; i < maxLen; i++ {
-//		branchLine := ""
-//		localLine := ""
-//
-//		if i < len(branchLines) {
-//			branchLine = branchLines[i]
-//		}
-//		if i < len(localLines) {
-//			localLine = localLines[i]
-//		}
-//
-//		switch {
-//		case i >= len(branchLines):
-//			// Linha só existe no local (adicionada no output)
-//			result.WriteString(localLine + "\n")
-//
-//		case i >= len(localLines):
-//			// Linha só existe na branch (removida no output)
-//			result.WriteString(branchLine + "\n")
-//
-//		case branchLine == localLine:
-//			// Linha igual nas duas versões
-//			result.WriteString(localLine + "\n")
-//
-//		default:
-//			// Linha diferente — gera bloco de conflito
-//			result.WriteString("<<<<<<< HEAD\n")
-//			result.WriteString(localLine + "\n")
-//			result.WriteString("=======\n")
-//			result.WriteString(branchLine + "\n")
-//			result.WriteString(">>>>>>> branch\n")
-//		}
-//	}
-//
-//	return result.String()
-//}
-
 // GetAllChangedFiles retorna todos os arquivos que sofreram qualquer tipo de mudança
 // (adicionados, modificados ou removidos) em uma branch comparando com uma branch base.
+// mode define se a comparação é feita com a ponta da base ou com o ancestral comum
+// e renames a detecção de renomeações e cópias.
 // Retorna um slice de strings com os caminhos dos arquivos e um erro, se houver.
-func (e *Control) GetAllChangedFiles(branchName, baseBranch string) ([]string, error) {
-	if e.repository == nil {
-		return nil, fmt.Errorf("repositório não inicializado")
-	}
-
-	// Obtém a referência da branch alvo
-	targetRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchName)), true)
+func (e *Control) GetAllChangedFiles(branchName, baseBranch string, mode CompareMode, renames RenameOptions) ([]string, error) {
+	cmp, err := e.compareBranches(branchName, baseBranch, mode, renames)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", branchName, err)
-	}
-
-	// Obtém a referência da branch base
-	baseRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", baseBranch)), true)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", baseBranch, err)
-	}
-
-	// Obtém os commits
-	targetCommit, err := e.repository.CommitObject(targetRef.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", branchName, err)
-	}
-
-	baseCommit, err := e.repository.CommitObject(baseRef.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", baseBranch, err)
-	}
-
-	// Obtém as árvores de arquivos
-	targetTree, err := targetCommit.Tree()
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchName, err)
-	}
-
-	baseTree, err := baseCommit.Tree()
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", baseBranch, err)
-	}
-
-	// Compara as árvores e obtém as diferenças
-	changes, err := targetTree.Diff(baseTree)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao comparar árvores: %w", err)
+		return nil, err
 	}
 
 	var changedFiles []string
 
-	for _, change := range changes {
+	for _, change := range cmp.changes {
 		action, err := change.Action()
 		if err != nil {
 			continue
@@ -430,6 +358,11 @@ func (e *Control) GetAllChangedFiles(branchName, baseBranch string) ([]string, e
 			changedFiles = append(changedFiles, change.To.Name)
 		case merkletrie.Modify:
 			changedFiles = append(changedFiles, change.To.Name)
+
+			// Numa renomeação o caminho antigo também deixou de existir
+			if info, ok := cmp.renames[change]; ok && !info.copy {
+				changedFiles = append(changedFiles, change.From.Name)
+			}
 		case merkletrie.Delete:
 			changedFiles = append(changedFiles, change.From.Name)
 		}
@@ -440,55 +373,18 @@ func (e *Control) GetAllChangedFiles(branchName, baseBranch string) ([]string, e
 
 // GetFileChanges retorna informações detalhadas sobre todos os arquivos alterados
 // em uma branch comparando com uma branch base.
+// mode define se a comparação é feita com a ponta da base ou com o ancestral comum
+// e renames a detecção de renomeações e cópias.
 // Retorna um slice de FileChange com detalhes de cada alteração e um erro, se houver.
-func (e *Control) GetFileChanges(branchName, baseBranch string) ([]FileChange, error) {
-	if e.repository == nil {
-		return nil, fmt.Errorf("repositório não inicializado")
-	}
-
-	// Obtém a referência da branch alvo
-	targetRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchName)), true)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", branchName, err)
-	}
-
-	// Obtém a referência da branch base
-	baseRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", baseBranch)), true)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", baseBranch, err)
-	}
-
-	// Obtém os commits
-	targetCommit, err := e.repository.CommitObject(targetRef.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", branchName, err)
-	}
-
-	baseCommit, err := e.repository.CommitObject(baseRef.Hash())
+func (e *Control) GetFileChanges(branchName, baseBranch string, mode CompareMode, renames RenameOptions) ([]FileChange, error) {
+	cmp, err := e.compareBranches(branchName, baseBranch, mode, renames)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", baseBranch, err)
-	}
-
-	// Obtém as árvores de arquivos
-	targetTree, err := targetCommit.Tree()
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchName, err)
-	}
-
-	baseTree, err := baseCommit.Tree()
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", baseBranch, err)
-	}
-
-	// Compara as árvores e obtém as diferenças
-	changes, err := targetTree.Diff(baseTree)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao comparar árvores: %w", err)
+		return nil, err
 	}
 
 	var fileChanges []FileChange
 
-	for _, change := range changes {
+	for _, change := range cmp.changes {
 		action, err := change.Action()
 		if err != nil {
 			continue
@@ -496,6 +392,22 @@ func (e *Control) GetFileChanges(branchName, baseBranch string) ([]FileChange, e
 
 		var fc FileChange
 
+		if info, ok := cmp.renames[change]; ok {
+			fc = FileChange{
+				Path:       change.To.Name,
+				Action:     "renamed",
+				OldPath:    change.From.Name,
+				Similarity: info.similarity,
+			}
+			if info.copy {
+				fc.Action = "copied"
+			}
+			fc.setModes(change)
+
+			fileChanges = append(fileChanges, fc)
+			continue
+		}
+
 		switch action {
 		case merkletrie.Insert:
 			fc = FileChange{
@@ -507,6 +419,12 @@ func (e *Control) GetFileChanges(branchName, baseBranch string) ([]FileChange, e
 				Path:   change.To.Name,
 				Action: "modified",
 			}
+			fc.setModes(change)
+
+			// Só o modo mudou, o conteúdo é o mesmo
+			if change.From.TreeEntry.Hash == change.To.TreeEntry.Hash && fc.OldMode != fc.NewMode {
+				fc.Action = "mode-changed"
+			}
 		case merkletrie.Delete:
 			fc = FileChange{
 				Path:   change.From.Name,
@@ -514,60 +432,78 @@ func (e *Control) GetFileChanges(branchName, baseBranch string) ([]FileChange, e
 			}
 		}
 
+		// Submódulos trazem os commits em vez do conteúdo
+		if change.From.TreeEntry.Mode == filemode.Submodule {
+			fc.Submodule = true
+			fc.OldCommit = change.From.TreeEntry.Hash.String()
+		}
+		if change.To.TreeEntry.Mode == filemode.Submodule {
+			fc.Submodule = true
+			fc.NewCommit = change.To.TreeEntry.Hash.String()
+		}
+
 		fileChanges = append(fileChanges, fc)
 	}
 
-	return fileChanges, nil
-}
-
-// DownloadModifiedFiles baixa os arquivos modificados entre duas branchs
-// e os salva no diretório de destino, preservando a estrutura de pastas.
-func (e *Control) DownloadModifiedFiles(branchName, branchBase, destDir string) ([]string, error) {
-	_ = os.RemoveAll(destDir)
-
-	// Obtém a referência da branch alvo
-	targetRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchName)), true)
+	// Marca os conflitos de árvore; caminhos que só aparecem pelo conflito,
+	// como um arquivo removido na sua branch e renomeado na base, entram
+	// com a ação "conflict"
+	conflicts, err := e.comparisonConflicts(cmp)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", branchName, err)
+		return nil, err
 	}
-
-	// Obtém a referência da branch base
-	baseRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchBase)), true)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", branchBase, err)
+	for _, conflict := range conflicts {
+		found := false
+		for i := range fileChanges {
+			if fileChanges[i].Path == conflict.Path {
+				fileChanges[i].Conflict = conflict.Kind
+				found = true
+			}
+		}
+		if !found {
+			fileChanges = append(fileChanges, FileChange{Path: conflict.Path, Action: "conflict", Conflict: conflict.Kind})
+		}
 	}
 
-	// Obtém os commits
-	targetCommit, err := e.repository.CommitObject(targetRef.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", branchName, err)
+	// Marca os arquivos gerados segundo os .gitattributes das duas branches
+	for i := range fileChanges {
+		rules, err := rulesFor(fileChanges[i].Path, cmp.targetAttrs, cmp.baseAttrs)
+		if err != nil {
+			return nil, err
+		}
+		fileChanges[i].Generated = rules.generated
 	}
 
-	baseCommit, err := e.repository.CommitObject(baseRef.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", branchBase, err)
-	}
+	return fileChanges, nil
+}
 
-	// Obtém as árvores
-	targetTree, err := targetCommit.Tree()
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchName, err)
+// setModes preenche OldMode e NewMode quando a alteração mudou o modo do
+// arquivo.
+func (fc *FileChange) setModes(change *object.Change) {
+	oldMode, newMode := entryModeName(change.From), entryModeName(change.To)
+	if oldMode != "" && newMode != "" && oldMode != newMode {
+		fc.OldMode, fc.NewMode = oldMode, newMode
 	}
+}
 
-	baseTree, err := baseCommit.Tree()
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchBase, err)
-	}
+// DownloadModifiedFiles baixa os arquivos modificados entre duas branchs
+// e os salva no diretório de destino, preservando a estrutura de pastas e o
+// modo de cada arquivo: executáveis com permissão de execução e links
+// simbólicos como links para o mesmo destino.
+// Submódulos não têm conteúdo no repositório e ficam de fora.
+// mode define se a comparação é feita com a ponta da base ou com o ancestral comum
+// e renames a detecção de renomeações e cópias.
+func (e *Control) DownloadModifiedFiles(branchName, branchBase, destDir string, mode CompareMode, renames RenameOptions) ([]string, error) {
+	_ = os.RemoveAll(destDir)
 
-	// Compara as árvores
-	changes, err := targetTree.Diff(baseTree)
+	cmp, err := e.compareBranches(branchName, branchBase, mode, renames)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao comparar árvores: %w", err)
+		return nil, err
 	}
 
 	var downloaded []string
 
-	for _, change := range changes {
+	for _, change := range cmp.changes {
 		action, err := change.Action()
 		if err != nil {
 			continue
@@ -578,10 +514,14 @@ func (e *Control) DownloadModifiedFiles(branchName, branchBase, destDir string)
 			continue
 		}
 
+		if change.To.TreeEntry.Mode == filemode.Submodule {
+			continue
+		}
+
 		fileName := change.To.Name
 
 		// Lê o conteúdo do arquivo na tree do commit alvo
-		fileInTree, err := targetTree.File(fileName)
+		fileInTree, err := cmp.targetTree.File(fileName)
 		if err != nil {
 			return downloaded, fmt.Errorf("erro ao ler arquivo %s: %w", fileName, err)
 		}
@@ -599,9 +539,21 @@ func (e *Control) DownloadModifiedFiles(branchName, branchBase, destDir string)
 			return downloaded, fmt.Errorf("erro ao criar diretório %s: %w", filepath.Dir(destPath), err)
 		}
 
-		// Salva o arquivo
-		if err := os.WriteFile(destPath, []byte(content), 0644); err != nil {
-			return downloaded, fmt.Errorf("erro ao salvar arquivo %s: %w", destPath, err)
+		// Salva o arquivo no modo da árvore; o conteúdo de um link simbólico
+		// é o caminho para onde ele aponta
+		switch change.To.TreeEntry.Mode {
+		case filemode.Symlink:
+			if err := os.Symlink(content, destPath); err != nil {
+				return downloaded, fmt.Errorf("erro ao criar link simbólico %s: %w", destPath, err)
+			}
+		case filemode.Executable:
+			if err := os.WriteFile(destPath, []byte(content), 0755); err != nil {
+				return downloaded, fmt.Errorf("erro ao salvar arquivo %s: %w", destPath, err)
+			}
+		default:
+			if err := os.WriteFile(destPath, []byte(content), 0644); err != nil {
+				return downloaded, fmt.Errorf("erro ao salvar arquivo %s: %w", destPath, err)
+			}
 		}
 
 		downloaded = append(downloaded, destPath)
@@ -611,67 +563,120 @@ func (e *Control) DownloadModifiedFiles(branchName, branchBase, destDir string)
 }
 
 // DiffSpecificFile retorna o diff de um arquivo específico entre duas branches
-// com marcadores de conflito git.
-func (e *Control) DiffSpecificFile(branchName, branchBase, fileName string) (map[string]string, error) {
-	// Obtém a referência da branch alvo
-	targetRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchName)), true)
+// com marcadores de conflito git, gerados a partir de MergeFile. Arquivos
+// binários resultam em *BinaryFileError, submódulos em *SubmoduleError e
+// conflitos de árvore, exceto add/add, em *TreeConflictError.
+// style define o formato dos marcadores (merge, diff3 ou zdiff3). Os marcadores
+// trazem os nomes das revisões e os hashes abreviados, como "feature (1a2b3c4)".
+// renames define a detecção de renomeações, opts as diferenças ignoradas na
+// comparação das linhas e auto a resolução automática dos blocos.
+func (e *Control) DiffSpecificFile(branchName, branchBase, fileName string, mode CompareMode, renames RenameOptions, style ConflictStyle, opts DiffOptions, auto AutoResolveOptions) (map[string]string, error) {
+	merge, err := e.MergeFile(branchName, branchBase, fileName, mode, renames, opts, auto)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", branchName, err)
+		return nil, err
 	}
 
-	// Obtém a referência da branch base
-	baseRef, err := e.repository.Reference(plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchBase)), true)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter referência da branch %s: %w", branchBase, err)
+	if merge.Tree != nil && merge.Tree.Kind != TreeAddAdd {
+		return nil, &TreeConflictError{Merge: merge}
 	}
 
-	// Obtém os commits
-	targetCommit, err := e.repository.CommitObject(targetRef.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", branchName, err)
+	if merge.Binary != nil {
+		return nil, &BinaryFileError{Merge: merge}
 	}
 
-	baseCommit, err := e.repository.CommitObject(baseRef.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter commit da branch %s: %w", branchBase, err)
+	if merge.Submodule != nil {
+		return nil, &SubmoduleError{Merge: merge}
 	}
 
-	// Obtém as árvores
-	targetTree, err := targetCommit.Tree()
+	result := make(map[string]string)
+	result[fileName] = merge.Render(style)
+	return result, nil
+}
+
+// MergeFile faz o merge de um arquivo específico entre duas branches e retorna
+// o resultado como trechos estáveis e blocos em conflito. Os blocos triviais
+// e os que têm resolução gravada (rerere) já vêm resolvidos, conforme
+// auto, e as linhas são comparadas conforme opts.
+// O merge é feito pelo driver escolhido pelo merge=<nome> dos .gitattributes
+// ou pelo caminho do arquivo (ver RegisterMergeDriver). Os .gitattributes das
+// duas branches definem ainda a normalização dos fins de linha (text, eol) e
+// os arquivos gerados (linguist-generated). Arquivos binários (byte NUL no conteúdo ou
+// binary, -diff e -merge nos .gitattributes) não são mesclados linha a linha:
+// o resultado traz Binary com as versões. Submódulos vêm em Submodule, com
+// os commits dos lados. Modos diferentes nos dois lados (regular, executável
+// ou link simbólico) vêm em Mode.
+// Conflitos de árvore (TreeConflicts) vêm em Tree, sem Chunks; no add/add o
+// conteúdo também é mesclado, contra uma base vazia.
+// mode define se o arquivo é procurado entre as alterações feitas desde o
+// ancestral comum ou entre as diferenças das pontas das branches. O merge em si
+// sempre usa o ancestral comum quando ele existe. renames define a detecção
+// de renomeações e cópias.
+func (e *Control) MergeFile(branchName, branchBase, fileName string, mode CompareMode, renames RenameOptions, opts DiffOptions, auto AutoResolveOptions) (*FileMerge, error) {
+	cmp, err := e.compareBranches(branchName, branchBase, mode, renames)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchName, err)
+		return nil, err
 	}
 
-	baseTree, err := baseCommit.Tree()
+	conflicts, err := e.comparisonConflicts(cmp)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchBase, err)
+		return nil, err
 	}
 
-	// Compara as árvores
-	changes, err := targetTree.Diff(baseTree)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao comparar árvores: %w", err)
+	var tree *TreeConflict
+	for i := range conflicts {
+		if conflicts[i].Path == fileName {
+			tree = &conflicts[i]
+			break
+		}
+	}
+
+	// Fora o add/add, o conflito de árvore decide o arquivo inteiro
+	if tree != nil && tree.Kind != TreeAddAdd {
+		merge := &FileMerge{Path: fileName, Labels: cmp.labels(), Tree: tree}
+		merge.countPending()
+		return merge, nil
 	}
 
 	// Procura o arquivo específico nos changes
-	for _, change := range changes {
+	for _, change := range cmp.changes {
 		action, err := change.Action()
 		if err != nil {
 			continue
 		}
 
 		// Verifica se é o arquivo que estamos procurando
-		if change.To.Name != fileName {
+		if changePath(change) != fileName {
 			continue
 		}
 
 		// Processa apenas arquivos adicionados ou modificados
 		if action != merkletrie.Insert && action != merkletrie.Modify {
-			return nil, fmt.Errorf("arquivo foi deletado ou não modificado")
+			return nil, fmt.Errorf("o arquivo %s foi removido na sua branch sem conflito com a base", fileName)
+		}
+
+		// Arquivos renomeados são comparados com o caminho antigo
+		oldPath := fileName
+		if info, ok := cmp.renames[change]; ok && !info.copy {
+			oldPath = change.From.Name
+		}
+
+		// Submódulos guardam um commit de outro repositório, não conteúdo
+		if isGitlink(cmp.targetTree, fileName) || isGitlink(cmp.baseTree, fileName) {
+			merge, err := e.submoduleMerge(cmp, fileName, oldPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.resolveKnown(merge, auto); err != nil {
+				return nil, err
+			}
+			return merge, nil
 		}
 
 		// Lê o conteúdo do arquivo na branch base (branch remota/theirs)
-		baseFile, err := baseTree.File(fileName)
+		baseFile, err := cmp.baseTree.File(fileName)
+		if err != nil && oldPath != fileName {
+			baseFile, err = cmp.baseTree.File(oldPath)
+		}
 		if err != nil {
 			// Arquivo não existe na base, considera vazio
 			baseFile = nil
@@ -686,7 +691,7 @@ func (e *Control) DiffSpecificFile(branchName, branchBase, fileName string) (map
 		}
 
 		// Lê o conteúdo do arquivo na branch alvo (sua branch/ours)
-		targetFile, err := targetTree.File(fileName)
+		targetFile, err := cmp.targetTree.File(fileName)
 		if err != nil {
 			return nil, fmt.Errorf("erro ao ler arquivo %s: %w", fileName, err)
 		}
@@ -696,64 +701,91 @@ func (e *Control) DiffSpecificFile(branchName, branchBase, fileName string) (map
 			return nil, fmt.Errorf("erro ao obter conteúdo da sua branch: %w", err)
 		}
 
-		// Gera o diff com marcadores de conflito
-		diff := generateDiff(baseContent, targetContent)
-
-		result := make(map[string]string)
-		result[fileName] = diff
-		return result, nil
-	}
-
-	return nil, fmt.Errorf("arquivo %s não encontrado nas diferenças entre as branches", fileName)
-}
+		// Vale o .gitattributes das duas branches; o da sua branch tem prioridade
+		rules, err := rulesFor(fileName, cmp.targetAttrs, cmp.baseAttrs)
+		if err != nil {
+			return nil, err
+		}
 
-// generateDiff gera um arquivo no formato de conflito git.
-// branchContent = versão na branch base/remota (theirs)
-// localContent  = versão na sua branch (ours / HEAD)
-func generateDiff(branchContent, localContent string) string {
-	branchLines := strings.Split(branchContent, "\n")
-	localLines := strings.Split(localContent, "\n")
+		if rules.binary || isBinaryContent(targetContent) || isBinaryContent(baseContent) {
+			merge, err := cmp.binaryMerge(fileName, oldPath, targetFile, baseFile)
+			if err != nil {
+				return nil, err
+			}
+			merge.Tree = tree
+			merge.applyRules(rules)
+			if err := cmp.setMode(merge, oldPath); err != nil {
+				return nil, err
+			}
+			if err := e.resolveKnown(merge, auto); err != nil {
+				return nil, err
+			}
+			return merge, nil
+		}
 
-	var result strings.Builder
+		if rules.text {
+			targetContent, baseContent = normalizeEOL(targetContent), normalizeEOL(baseContent)
+		}
 
-	maxLen := len(branchLines)
-	if len(localLines) > maxLen {
-		maxLen = len(localLines)
-	}
+		// Históricos sem ancestral comum: o driver faz o diff de duas vias
+		input := MergeInput{Path: fileName, Ours: targetContent, Theirs: baseContent, Options: opts}
+		if len(cmp.ancestors) > 0 {
+			commonContent, err := ancestorContent(cmp.ancestors, oldPath)
+			if err != nil {
+				return nil, err
+			}
+			if rules.text {
+				commonContent = normalizeEOL(commonContent)
+			}
+			input.Base, input.HasBase = commonContent, true
+		}
 
-	for i := 0; i < maxLen; i++ {
-		branchLine := ""
-		localLine := ""
+		chunks, driver, err := e.mergeWithDriver(input, rules)
+		if err != nil {
+			return nil, err
+		}
 
-		if i < len(branchLines) {
-			branchLine = branchLines[i]
+		// Os blocos triviais e os conflitos já resolvidos antes são
+		// resolvidos antes de chegar ao editor
+		merge := newFileMerge(fileName, cmp.labels(), chunks)
+		if driver != DefaultDriver {
+			merge.Strategy = driver
 		}
-		if i < len(localLines) {
-			localLine = localLines[i]
+		merge.Tree = tree
+		merge.applyRules(rules)
+		if err := cmp.setMode(merge, oldPath); err != nil {
+			return nil, err
+		}
+		if err := e.resolveKnown(merge, auto); err != nil {
+			return nil, err
 		}
 
-		switch {
-		case i >= len(branchLines):
-			// Linha só existe no local (adicionada)
-			result.WriteString(localLine + "\n")
+		return merge, nil
+	}
 
-		case i >= len(localLines):
-			// Linha só existe na branch (removida)
-			result.WriteString(branchLine + "\n")
+	return nil, fmt.Errorf("arquivo %s não encontrado nas diferenças entre as branches", fileName)
+}
 
-		case branchLine == localLine:
-			// Linha igual nas duas versões
-			result.WriteString(localLine + "\n")
+// fileContentAt retorna o conteúdo de um arquivo em um commit.
+// Se o arquivo não existir no commit, retorna conteúdo vazio.
+func fileContentAt(commit *object.Commit, fileName string) (string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("erro ao obter árvore do commit %s: %w", commit.Hash, err)
+	}
 
-		default:
-			// Linha diferente — gera bloco de conflito
-			result.WriteString("<<<<<<< HEAD\n")
-			result.WriteString(localLine + "\n")
-			result.WriteString("=======\n")
-			result.WriteString(branchLine + "\n")
-			result.WriteString(">>>>>>> branch\n")
-		}
+	file, err := tree.File(fileName)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler arquivo %s no commit %s: %w", fileName, commit.Hash, err)
+	}
+
+	content, err := file.Contents()
+	if err != nil {
+		return "", fmt.Errorf("erro ao obter conteúdo de %s no commit %s: %w", fileName, commit.Hash, err)
 	}
 
-	return result.String()
+	return content, nil
 }
@@ -0,0 +1,793 @@
+package git
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Drivers de merge de arquivos Go e dos arquivos de módulo
+const (
+	// GoDriver une os imports dos dois lados.
+	GoDriver = "go"
+
+	// GoModDriver escolhe a maior versão das dependências do go.mod.
+	GoModDriver = "gomod"
+
+	// GoSumDriver une as linhas do go.sum dos dois lados.
+	GoSumDriver = "gosum"
+)
+
+func init() {
+	RegisterMergeDriver(GoDriver, MergeDriverFunc(goMerge))
+	RegisterMergeDriver(GoModDriver, MergeDriverFunc(goModMerge))
+	RegisterMergeDriver(GoSumDriver, MergeDriverFunc(goSumMerge))
+	_ = RegisterMergeDriverPattern("*.go", GoDriver)
+	_ = RegisterMergeDriverPattern("go.mod", GoModDriver)
+	_ = RegisterMergeDriverPattern("go.sum", GoSumDriver)
+}
+
+// goImports são os imports de um arquivo Go e as linhas que eles ocupam.
+type goImports struct {
+	// specs são os imports, como `"fmt"` ou `log "github.com/x/log"`
+	specs []string
+
+	// groups são os imports separados pelas linhas em branco, na ordem do
+	// arquivo, com os comentários
+	groups [][]goImport
+
+	// parens informa se os imports estão em um bloco entre parênteses
+	parens bool
+
+	// start e end delimitam as linhas das declarações de import, a partir
+	// de zero e sem incluir end. Sem imports, as duas ficam logo depois da
+	// declaração do pacote.
+	start, end int
+}
+
+// goImport é um import com as linhas que ocupa no bloco: os comentários
+// acima dele e a linha do import, com o comentário no fim dela. Comentários
+// soltos no bloco têm spec vazio.
+type goImport struct {
+	spec  string
+	lines []string
+}
+
+// parseGoImports lê os imports de um arquivo Go. Retorna false se o arquivo
+// não puder ser lido ou tiver import "C", cujo comentário é o código C, ou
+// imports que dividem a linha, que não podem ser reescritos sem perder
+// informação.
+func parseGoImports(content string) (*goImports, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+
+	line := func(pos token.Pos) int {
+		return fset.Position(pos).Line - 1
+	}
+
+	imports := &goImports{start: line(file.Name.End()) + 1}
+	imports.end = imports.start
+
+	// Linhas de cada import e das declarações sem parênteses
+	specs := make(map[int]string)
+	single := make(map[int]bool)
+	skip := make(map[int]bool)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if len(imports.specs) == 0 && len(specs) == 0 {
+			imports.start = line(gen.Pos())
+		}
+		imports.end = line(gen.End()) + 1
+
+		if gen.Lparen.IsValid() {
+			if line(gen.Lparen) == line(gen.Rparen) && len(gen.Specs) > 0 {
+				return nil, false
+			}
+			imports.parens = true
+			skip[line(gen.Lparen)], skip[line(gen.Rparen)] = true, true
+		} else {
+			single[line(gen.Pos())] = true
+		}
+
+		for _, s := range gen.Specs {
+			spec := s.(*ast.ImportSpec)
+			if spec.Path.Value == `"C"` {
+				return nil, false
+			}
+
+			text := spec.Path.Value
+			if spec.Name != nil {
+				text = spec.Name.Name + " " + text
+			}
+
+			n := line(spec.Path.Pos())
+			if _, ok := specs[n]; ok {
+				return nil, false
+			}
+			specs[n] = text
+			imports.specs = append(imports.specs, text)
+		}
+	}
+
+	// Os imports de declarações sem parênteses passam para a forma do bloco
+	lines := splitLines(content)
+	var group []goImport
+	var comments []string
+	for i := imports.start; i < imports.end; i++ {
+		text := lines[i]
+		switch {
+		case skip[i]:
+			continue
+		case strings.TrimSpace(text) == "":
+			if len(comments) > 0 {
+				group = append(group, goImport{lines: comments})
+				comments = nil
+			}
+			if len(group) > 0 {
+				imports.groups = append(imports.groups, group)
+				group = nil
+			}
+			continue
+		}
+
+		spec, ok := specs[i]
+		if !ok {
+			comments = append(comments, text)
+			continue
+		}
+		if single[i] {
+			text = "\t" + strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "import"))
+		}
+		group = append(group, goImport{spec: spec, lines: append(comments, text)})
+		comments = nil
+	}
+	if len(comments) > 0 {
+		group = append(group, goImport{lines: comments})
+	}
+	if len(group) > 0 {
+		imports.groups = append(imports.groups, group)
+	}
+
+	return imports, true
+}
+
+// goMerge é o driver de arquivos Go. Quando os dois lados alteraram os
+// imports, o bloco de imports fica com a união deles, sem os que um dos lados
+// removeu, mantendo os grupos e comentários de ours (ver renderImports). O
+// restante do arquivo passa pelo merge de linhas. Se algum lado não puder ser
+// lido, o merge é o de texto.
+func goMerge(input MergeInput) ([]Chunk, error) {
+	ours, okOurs := parseGoImports(input.Ours)
+	theirs, okTheirs := parseGoImports(input.Theirs)
+
+	base := &goImports{}
+	okBase := true
+	if input.HasBase {
+		base, okBase = parseGoImports(input.Base)
+	}
+
+	if !okOurs || !okTheirs || !okBase {
+		return textMerge(input)
+	}
+
+	merged, changed := mergeImportSpecs(base.specs, ours.specs, theirs.specs)
+	if !changed {
+		return textMerge(input)
+	}
+
+	oursLines, theirsLines := splitLines(input.Ours), splitLines(input.Theirs)
+	var baseLines []string
+	if input.HasBase {
+		baseLines = splitLines(input.Base)
+	}
+
+	section := func(lines []string, imports *goImports, part int) []string {
+		switch {
+		case lines == nil:
+			return nil
+		case part == 0:
+			return lines[:imports.start]
+		case part == 1:
+			return lines[imports.start:imports.end]
+		}
+		return lines[imports.end:]
+	}
+
+	// Antes dos imports
+	chunks := mergeSection(input,
+		section(baseLines, base, 0), section(oursLines, ours, 0), section(theirsLines, theirs, 0))
+
+	// Os imports, na organização de ours ou, se ours não tiver imports, na
+	// de theirs
+	layout, other := ours, theirs
+	if len(ours.specs) == 0 {
+		layout, other = theirs, ours
+	}
+	lines := renderImports(layout, other, merged)
+	if len(ours.specs) == 0 {
+		lines = append([]string{""}, lines...)
+	}
+
+	chunks = append(chunks, Chunk{
+		Conflict:   true,
+		Resolution: ResolveAutoImports,
+		Lines:      lines,
+		Ours:       newHunkSide(section(oursLines, ours, 1), ours.start),
+		Base:       importsSide(section(baseLines, base, 1), base.start, input.HasBase),
+		Theirs:     newHunkSide(section(theirsLines, theirs, 1), theirs.start),
+	})
+
+	// Depois dos imports, com as posições a partir do fim dos imports
+	after := mergeSection(input,
+		section(baseLines, base, 2), section(oursLines, ours, 2), section(theirsLines, theirs, 2))
+	for i := range after {
+		shiftSide(after[i].Ours, ours.end)
+		shiftSide(after[i].Base, base.end)
+		shiftSide(after[i].Theirs, theirs.end)
+	}
+
+	return append(chunks, after...), nil
+}
+
+// importsSide é a versão da base do bloco de imports, nil sem ancestral comum.
+func importsSide(lines []string, start int, hasBase bool) *HunkSide {
+	if !hasBase {
+		return nil
+	}
+	return newHunkSide(lines, start)
+}
+
+// mergeSection faz o merge de linhas de um trecho do arquivo.
+func mergeSection(input MergeInput, base, ours, theirs []string) []Chunk {
+	if !input.HasBase {
+		return diffChunks(ours, theirs, input.Options)
+	}
+	return mergeLines(base, ours, theirs, input.Options)
+}
+
+// shiftSide desloca a posição de um lado de um bloco.
+func shiftSide(side *HunkSide, offset int) {
+	if side != nil {
+		side.Start += offset
+	}
+}
+
+// mergeImportSpecs une os imports dos dois lados, sem os que um dos lados
+// removeu. changed informa se os dois lados alteraram os imports de formas
+// diferentes; caso contrário o merge de linhas já resolve o bloco.
+func mergeImportSpecs(base, ours, theirs []string) (merged []string, changed bool) {
+	set := func(specs []string) map[string]bool {
+		m := make(map[string]bool, len(specs))
+		for _, spec := range specs {
+			m[spec] = true
+		}
+		return m
+	}
+	b, o, t := set(base), set(ours), set(theirs)
+
+	same := func(x, y map[string]bool) bool {
+		if len(x) != len(y) {
+			return false
+		}
+		for spec := range x {
+			if !y[spec] {
+				return false
+			}
+		}
+		return true
+	}
+	if same(o, t) || same(b, o) || same(b, t) {
+		return nil, false
+	}
+
+	seen := make(map[string]bool)
+	for _, spec := range append(append([]string(nil), ours...), theirs...) {
+		if seen[spec] || (b[spec] && (!o[spec] || !t[spec])) {
+			continue
+		}
+		seen[spec] = true
+		merged = append(merged, spec)
+	}
+
+	return merged, true
+}
+
+// renderImports escreve o bloco de imports com os specs do merge. Os grupos
+// e os comentários vêm de layout, sem os imports removidos. Cada import que
+// só other tem, com os seus comentários, entra no grupo de layout onde está
+// um vizinho dele em other, em ordem se o grupo estiver ordenado. Sem
+// vizinhos, entra no primeiro grupo do mesmo tipo (biblioteca padrão ou não)
+// ou, se estava sozinho em um dos grupos de other, em um grupo novo.
+func renderImports(layout, other *goImports, specs []string) []string {
+	keep := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		keep[spec] = true
+	}
+
+	var groups [][]goImport
+	for _, group := range layout.groups {
+		var kept []goImport
+		hasSpecs := false
+		for _, imp := range group {
+			if imp.spec == "" || keep[imp.spec] {
+				kept = append(kept, imp)
+				hasSpecs = hasSpecs || imp.spec != ""
+			}
+		}
+		if hasSpecs || !slices.ContainsFunc(group, func(imp goImport) bool { return imp.spec != "" }) {
+			groups = append(groups, kept)
+		}
+	}
+
+	inLayout := make(map[string]bool, len(layout.specs))
+	for _, spec := range layout.specs {
+		inLayout[spec] = true
+	}
+
+	for _, spec := range specs {
+		if inLayout[spec] {
+			continue
+		}
+		imp, neighbors := other.find(spec)
+		groups = addImport(groups, imp, neighbors, len(other.groups) > 1)
+	}
+
+	count := 0
+	for _, group := range groups {
+		for _, imp := range group {
+			if imp.spec != "" {
+				count++
+			}
+		}
+	}
+
+	// Um import sozinho, sem comentários, continua fora do bloco
+	if count == 1 && !layout.parens && len(groups) == 1 && len(groups[0]) == 1 && len(groups[0][0].lines) == 1 {
+		return []string{"import " + strings.TrimSpace(groups[0][0].lines[0])}
+	}
+
+	lines := []string{"import ("}
+	for i, group := range groups {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		for _, imp := range group {
+			lines = append(lines, imp.lines...)
+		}
+	}
+	return append(lines, ")")
+}
+
+// find retorna um import e os outros do mesmo grupo.
+func (g *goImports) find(spec string) (goImport, []string) {
+	for _, group := range g.groups {
+		for _, imp := range group {
+			if imp.spec != spec {
+				continue
+			}
+
+			var neighbors []string
+			for _, n := range group {
+				if n.spec != "" && n.spec != spec {
+					neighbors = append(neighbors, n.spec)
+				}
+			}
+			return imp, neighbors
+		}
+	}
+	return goImport{spec: spec, lines: []string{"\t" + spec}}, nil
+}
+
+// addImport acrescenta um import ao grupo de um dos vizinhos. Sem vizinhos,
+// entra no primeiro grupo do mesmo tipo ou, quando o lado de onde veio separa
+// os imports em grupos (grouped), em um grupo novo: no início para a
+// biblioteca padrão e no fim para os demais.
+func addImport(groups [][]goImport, imp goImport, neighbors []string, grouped bool) [][]goImport {
+	index := -1
+	for i, group := range groups {
+		if slices.ContainsFunc(group, func(g goImport) bool { return slices.Contains(neighbors, g.spec) }) {
+			index = i
+			break
+		}
+	}
+
+	std := standardImport(imp.spec)
+	if index < 0 && (len(neighbors) > 0 || !grouped) {
+		for i, group := range groups {
+			j := slices.IndexFunc(group, func(g goImport) bool { return g.spec != "" })
+			if j >= 0 && standardImport(group[j].spec) == std {
+				index = i
+				break
+			}
+		}
+	}
+
+	switch {
+	case index >= 0:
+		groups[index] = insertImport(groups[index], imp)
+	case std:
+		groups = append([][]goImport{{imp}}, groups...)
+	default:
+		groups = append(groups, []goImport{imp})
+	}
+	return groups
+}
+
+// insertImport acrescenta um import ao grupo: na ordem do caminho se o grupo
+// estiver ordenado, senão depois do último import.
+func insertImport(group []goImport, imp goImport) []goImport {
+	var paths []string
+	for _, g := range group {
+		if g.spec != "" {
+			paths = append(paths, importPath(g.spec))
+		}
+	}
+
+	last := -1
+	for i, g := range group {
+		if g.spec == "" {
+			continue
+		}
+		if slices.IsSorted(paths) && importPath(g.spec) > importPath(imp.spec) {
+			return slices.Insert(group, i, imp)
+		}
+		last = i
+	}
+	return slices.Insert(group, last+1, imp)
+}
+
+// importPath retorna o caminho de um import, sem o nome e as aspas.
+func importPath(spec string) string {
+	quoted := spec[strings.LastIndex(spec, " ")+1:]
+	if unquoted, err := strconv.Unquote(quoted); err == nil {
+		return unquoted
+	}
+	return quoted
+}
+
+// standardImport informa se o import é da biblioteca padrão: o primeiro
+// elemento do caminho não tem ponto.
+func standardImport(spec string) bool {
+	first, _, _ := strings.Cut(importPath(spec), "/")
+	return !strings.Contains(first, ".")
+}
+
+// goModEntry é uma linha de dependência do go.mod: um require, um replace
+// ou as diretivas go e toolchain.
+type goModEntry struct {
+	line string
+
+	// key identifica a dependência, como "require github.com/x/y" ou
+	// "replace github.com/x/y v1.0.0"
+	key string
+
+	// target é o destino de um replace, sem a versão
+	target string
+
+	version string
+}
+
+// parseGoModEntry lê uma linha de dependência, dentro ou fora de um bloco.
+func parseGoModEntry(line string) (goModEntry, bool) {
+	text, _, _ := strings.Cut(line, "//")
+	fields := strings.Fields(text)
+	entry := goModEntry{line: line}
+
+	if len(fields) > 0 && (fields[0] == "require" || fields[0] == "replace") {
+		fields = fields[1:]
+	}
+
+	if arrow := slices.Index(fields, "=>"); arrow >= 0 {
+		left, right := fields[:arrow], fields[arrow+1:]
+		if len(left) < 1 || len(left) > 2 || len(right) < 1 || len(right) > 2 {
+			return entry, false
+		}
+		entry.key = "replace " + strings.Join(left, " ")
+		entry.target = right[0]
+		if len(right) == 2 {
+			entry.version = right[1]
+		}
+		return entry, true
+	}
+
+	if len(fields) != 2 {
+		return entry, false
+	}
+
+	switch fields[0] {
+	case "go", "toolchain":
+		entry.key = fields[0]
+	default:
+		entry.key = "require " + fields[0]
+	}
+	entry.version = fields[1]
+
+	return entry, true
+}
+
+// goModEntries lê as linhas de um lado de um bloco em conflito. Retorna false
+// se alguma linha não for uma dependência ou uma linha em branco.
+func goModEntries(side *HunkSide) ([]goModEntry, bool) {
+	var entries []goModEntry
+	for _, line := range sideLines(side) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entry, ok := parseGoModEntry(line)
+		if !ok {
+			return nil, false
+		}
+		entries = append(entries, entry)
+	}
+	return entries, true
+}
+
+// goModMerge é o driver do go.mod. Nos blocos em conflito formados só por
+// dependências, cada módulo que os dois lados alteraram fica com a maior
+// versão, e a escolha é descrita em Note. Dependências que um lado removeu e
+// o outro alterou, ou replaces que apontam para módulos diferentes, continuam
+// em conflito.
+func goModMerge(input MergeInput) ([]Chunk, error) {
+	chunks, _ := textMerge(input)
+	for i := range chunks {
+		if chunks[i].Pending() {
+			resolveGoMod(&chunks[i])
+		}
+	}
+	return chunks, nil
+}
+
+// resolveGoMod resolve um bloco em conflito do go.mod, se possível.
+func resolveGoMod(chunk *Chunk) {
+	ours, okOurs := goModEntries(chunk.Ours)
+	theirs, okTheirs := goModEntries(chunk.Theirs)
+	base, okBase := goModEntries(chunk.Base)
+	if !okOurs || !okTheirs || !okBase {
+		return
+	}
+
+	index := func(entries []goModEntry) map[string]*goModEntry {
+		m := make(map[string]*goModEntry, len(entries))
+		for i := range entries {
+			m[entries[i].key] = &entries[i]
+		}
+		return m
+	}
+	b, o, t := index(base), index(ours), index(theirs)
+
+	var lines, notes []string
+	seen := make(map[string]bool)
+	for _, entry := range append(append([]goModEntry(nil), ours...), theirs...) {
+		if seen[entry.key] {
+			continue
+		}
+		seen[entry.key] = true
+
+		chosen, note, ok := chooseGoModEntry(b[entry.key], o[entry.key], t[entry.key])
+		if !ok {
+			return
+		}
+		if chosen != nil {
+			lines = append(lines, chosen.line)
+		}
+		if note != "" {
+			notes = append(notes, note)
+		}
+	}
+
+	// Mantém a ordem por módulo que o go mod tidy usa
+	if sortedGoMod(ours) {
+		sort.SliceStable(lines, func(i, j int) bool {
+			return goModSortKey(lines[i]) < goModSortKey(lines[j])
+		})
+	}
+
+	chunk.Lines = lines
+	chunk.Resolution = ResolveAutoVersion
+	chunk.Note = strings.Join(notes, "; ")
+}
+
+// chooseGoModEntry escolhe a versão de uma dependência. Retorna nil se ela
+// deve sair do arquivo, a descrição da escolha quando os dois lados a
+// alteraram e false se não houver como escolher.
+func chooseGoModEntry(base, ours, theirs *goModEntry) (*goModEntry, string, bool) {
+	same := func(a, b *goModEntry) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return a.target == b.target && a.version == b.version
+	}
+
+	switch {
+	case same(ours, theirs), same(base, theirs):
+		return ours, "", true
+	case same(base, ours):
+		return theirs, "", true
+	case ours == nil || theirs == nil || ours.target != theirs.target:
+		return nil, "", false
+	}
+
+	chosen := ours
+	if compareVersions(theirs.version, ours.version) > 0 {
+		chosen = theirs
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(ours.key, "require "), "replace ")
+	note := fmt.Sprintf("%s: %s (ours) e %s (theirs), mantida %s", name, ours.version, theirs.version, chosen.version)
+
+	return chosen, note, true
+}
+
+// sortedGoMod informa se as dependências estão ordenadas por módulo.
+func sortedGoMod(entries []goModEntry) bool {
+	return sort.SliceIsSorted(entries, func(i, j int) bool {
+		return goModSortKey(entries[i].line) < goModSortKey(entries[j].line)
+	})
+}
+
+// goModSortKey é o módulo de uma linha de dependência, sem o require.
+func goModSortKey(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) > 1 && (fields[0] == "require" || fields[0] == "replace") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// goSumMerge é o driver do go.sum: o resultado é a união das linhas dos dois
+// lados, sem as que um lado removeu da base e o outro manteve, ordenada por
+// módulo e versão como o go a escreve. Não há conflitos.
+func goSumMerge(input MergeInput) ([]Chunk, error) {
+	set := func(content string) map[string]bool {
+		m := make(map[string]bool)
+		for _, line := range splitLines(content) {
+			if line = strings.TrimSpace(line); line != "" {
+				m[line] = true
+			}
+		}
+		return m
+	}
+
+	var base map[string]bool
+	if input.HasBase {
+		base = set(input.Base)
+	}
+	ours, theirs := set(input.Ours), set(input.Theirs)
+
+	seen := make(map[string]bool)
+	var lines []string
+	for _, content := range []string{input.Ours, input.Theirs} {
+		for _, line := range splitLines(content) {
+			line = strings.TrimSpace(line)
+			if line == "" || seen[line] {
+				continue
+			}
+			seen[line] = true
+
+			if base[line] && (!ours[line] || !theirs[line]) {
+				continue
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return compareGoSumLines(lines[i], lines[j]) < 0
+	})
+
+	if strings.HasSuffix(input.Ours, "\n") || input.Ours == "" {
+		lines = append(lines, "")
+	}
+
+	return []Chunk{{Lines: lines}}, nil
+}
+
+// compareGoSumLines compara duas linhas do go.sum pelo módulo, pela versão e
+// por fim pelo /go.mod, que vem depois da linha do código do módulo.
+func compareGoSumLines(a, b string) int {
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	if len(fa) < 2 || len(fb) < 2 {
+		return strings.Compare(a, b)
+	}
+
+	if fa[0] != fb[0] {
+		return strings.Compare(fa[0], fb[0])
+	}
+
+	va, modA := strings.CutSuffix(fa[1], "/go.mod")
+	vb, modB := strings.CutSuffix(fb[1], "/go.mod")
+	if c := compareVersions(va, vb); c != 0 {
+		return c
+	}
+
+	switch {
+	case modA == modB:
+		return strings.Compare(a, b)
+	case modB:
+		return -1
+	}
+	return 1
+}
+
+// compareVersions compara duas versões semânticas, como v1.2.3, v1.2.3-pre ou
+// 1.25 da diretiva go, retornando -1, 0 ou 1. Versões sem pré-lançamento são
+// maiores que as com pré-lançamento, como as pseudo-versões.
+func compareVersions(a, b string) int {
+	parse := func(v string) (release []string, pre string) {
+		v = strings.TrimPrefix(v, "v")
+		v, _, _ = strings.Cut(v, "+")
+		v, pre, _ = strings.Cut(v, "-")
+		return strings.Split(v, "."), pre
+	}
+
+	ra, pa := parse(a)
+	rb, pb := parse(b)
+
+	for i := 0; i < max(len(ra), len(rb)); i++ {
+		var x, y string
+		if i < len(ra) {
+			x = ra[i]
+		}
+		if i < len(rb) {
+			y = rb[i]
+		}
+		if c := compareIdentifiers(x, y); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case pa == pb:
+		return 0
+	case pa == "":
+		return 1
+	case pb == "":
+		return -1
+	}
+
+	ia, ib := strings.Split(pa, "."), strings.Split(pb, ".")
+	for i := 0; i < min(len(ia), len(ib)); i++ {
+		if c := compareIdentifiers(ia[i], ib[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(ia), len(ib))
+}
+
+// compareIdentifiers compara partes de uma versão: numericamente quando as
+// duas são números, senão como texto. Uma parte ausente vale zero.
+func compareIdentifiers(a, b string) int {
+	if a == "" {
+		a = "0"
+	}
+	if b == "" {
+		b = "0"
+	}
+
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(x, y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,151 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoMerge(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflicts          int
+	}{
+		{
+			name:   "imports dos dois lados com grupos e comentários",
+			base:   "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc A() {}\n",
+			ours:   "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\t\"strings\"\n\n\t\"example.com/x\" // x\n)\n\nfunc A() {}\n",
+			theirs: "package a\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\n\t\"example.com/y\"\n)\n\nfunc A() {}\n",
+			want:   "package a\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\t\"strings\"\n\n\t\"example.com/x\" // x\n\n\t\"example.com/y\"\n)\n\nfunc A() {}\n",
+		},
+		{
+			name:   "import único vira bloco",
+			base:   "package a\n\nimport \"fmt\"\n\nfunc A() {}\n",
+			ours:   "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc A() {}\n",
+			theirs: "package a\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n)\n\nfunc A() {}\n",
+			want:   "package a\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc A() {}\n",
+		},
+		{
+			name:   "base sem imports",
+			base:   "package a\n\nfunc A() {}\n",
+			ours:   "package a\n\nimport \"os\"\n\nfunc A() { os.Exit(0) }\n",
+			theirs: "package a\n\nimport \"fmt\"\n\nfunc A() {}\n",
+			want:   "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc A() { os.Exit(0) }\n",
+		},
+		{
+			name:      "import removido por um lado e conflito no código",
+			base:      "package a\n\nimport \"fmt\"\n\nfunc A() { x() }\n",
+			ours:      "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc A() { y() }\n",
+			theirs:    "package a\n\nimport \"errors\"\n\nfunc A() { z() }\n",
+			want:      "package a\n\nimport (\n\t\"errors\"\n\t\"os\"\n)\n\n<<<<<<< ours\nfunc A() { y() }\n=======\nfunc A() { z() }\n>>>>>>> theirs\n",
+			conflicts: 1,
+		},
+		{
+			name:   "só um lado alterou os imports",
+			base:   "package a\n\nfunc A() {}\n",
+			ours:   "package a\n\nfunc A() {}\n\nfunc B() {}\n",
+			theirs: "package a\n\nimport \"fmt\"\n\nfunc A() { fmt.Println() }\n",
+			want:   "package a\n\nimport \"fmt\"\n\nfunc A() { fmt.Println() }\n\nfunc B() {}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := driverMerge(t, goMerge, "a.go", tt.base, tt.ours, tt.theirs)
+			if got := merge.Render(ConflictMerge); got != tt.want {
+				t.Errorf("Render =\n%s\nesperado\n%s", got, tt.want)
+			}
+			if merge.Conflicts != tt.conflicts {
+				t.Errorf("conflitos = %d, esperado %d", merge.Conflicts, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestGoModMerge(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		note               string
+		conflicts          int
+	}{
+		{
+			name:   "maior versão nos dois lados",
+			base:   "module m\n\ngo 1.21\n\nrequire (\n\ta.com/a v1.0.0\n\tb.com/b v1.0.0\n)\n",
+			ours:   "module m\n\ngo 1.22\n\nrequire (\n\ta.com/a v1.2.0\n\tb.com/b v1.0.0\n)\n",
+			theirs: "module m\n\ngo 1.21\n\nrequire (\n\ta.com/a v1.10.0\n\tb.com/b v1.0.0\n)\n",
+			want:   "module m\n\ngo 1.22\n\nrequire (\n\ta.com/a v1.10.0\n\tb.com/b v1.0.0\n)\n",
+			note:   "a.com/a: v1.2.0 (ours) e v1.10.0 (theirs), mantida v1.10.0",
+		},
+		{
+			name:      "removida e alterada",
+			base:      "module m\n\nrequire (\n\ta.com/a v1.0.0\n)\n",
+			ours:      "module m\n\nrequire (\n)\n",
+			theirs:    "module m\n\nrequire (\n\ta.com/a v1.1.0\n)\n",
+			want:      "module m\n\nrequire (\n<<<<<<< ours\n=======\n\ta.com/a v1.1.0\n>>>>>>> theirs\n)\n",
+			conflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := driverMerge(t, goModMerge, "go.mod", tt.base, tt.ours, tt.theirs)
+			if got := merge.Render(ConflictMerge); got != tt.want {
+				t.Errorf("Render =\n%s\nesperado\n%s", got, tt.want)
+			}
+			if merge.Conflicts != tt.conflicts {
+				t.Errorf("conflitos = %d, esperado %d", merge.Conflicts, tt.conflicts)
+			}
+
+			var notes []string
+			for _, chunk := range merge.Chunks {
+				if chunk.Note != "" {
+					notes = append(notes, chunk.Note)
+				}
+			}
+			if note := strings.Join(notes, "; "); note != tt.note {
+				t.Errorf("nota = %q, esperado %q", note, tt.note)
+			}
+		})
+	}
+}
+
+func TestGoSumMerge(t *testing.T) {
+	base := "a.com/a v1.0.0 h1:x=\na.com/a v1.0.0/go.mod h1:y=\n"
+	ours := "a.com/a v1.1.0 h1:z=\na.com/a v1.1.0/go.mod h1:w=\n"
+	theirs := base + "b.com/b v0.9.0 h1:q=\na.com/a v1.10.0/go.mod h1:v=\n"
+
+	// As linhas que ours removeu da base não voltam e a ordem é a do go
+	want := "a.com/a v1.1.0 h1:z=\na.com/a v1.1.0/go.mod h1:w=\na.com/a v1.10.0/go.mod h1:v=\nb.com/b v0.9.0 h1:q=\n"
+
+	merge := driverMerge(t, goSumMerge, "go.sum", base, ours, theirs)
+	if got := merge.Render(ConflictMerge); got != want || merge.Conflicts != 0 {
+		t.Fatalf("conflitos = %d, Render =\n%s\nesperado\n%s", merge.Conflicts, got, want)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.0", "v1.10.0", -1},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v1.0.0-rc.1", "v1.0.0", -1},
+		{"v1.0.0-rc.2", "v1.0.0-rc.10", -1},
+		{"v1.0.0-rc.1", "v1.0.0-rc.1.1", -1},
+		{"v0.0.0-20240101000000-abcdef123456", "v0.0.0-20230101000000-abcdef123456", 1},
+		{"v1.0.0+incompatible", "v1.0.0", 0},
+		{"1.25", "1.25.0", 0},
+		{"1.22.1", "1.25", -1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, esperado %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// ResolveInline monta o bloco a partir dos trechos de InlineDiff: cada
+// trecho diferente fica com o lado escolhido em HunkDecision.Choices, ou com
+// ours se não houver escolha. As linhas de ours sem correspondente em theirs
+// são mantidas.
+const ResolveInline = "inline"
+
+// inlineMaxChars é o tamanho máximo, em caracteres, das linhas comparadas
+// caractere a caractere; linhas maiores têm apenas a comparação por palavras.
+const inlineMaxChars = 1000
+
+// InlineSegment é um trecho de uma linha de ours comparada com a linha
+// correspondente de theirs. Trechos iguais têm o mesmo texto nos dois lados.
+type InlineSegment struct {
+	Changed bool   `json:"changed,omitempty"`
+	Ours    string `json:"ours"`
+	Theirs  string `json:"theirs"`
+
+	// OursStart e TheirsStart são as colunas onde o trecho começa em cada
+	// linha, a partir de zero e em unidades UTF-16, como as do editor.
+	OursStart   int `json:"oursStart"`
+	TheirsStart int `json:"theirsStart"`
+}
+
+// InlineDiff compara uma linha de Ours com a linha de Theirs que a substitui
+// no bloco em conflito. Ours e Theirs são os índices das linhas em
+// HunkSide.Lines. Words separa as linhas em palavras, espaços e pontuação;
+// Chars em caracteres e fica vazio em linhas longas.
+type InlineDiff struct {
+	Ours   int             `json:"ours"`
+	Theirs int             `json:"theirs"`
+	Words  []InlineSegment `json:"words"`
+	Chars  []InlineSegment `json:"chars,omitempty"`
+}
+
+// InlineChoice escolhe o lado de um trecho diferente para ResolveInline.
+// Line é o índice em Chunk.Inline e Segment o índice em Words, ou em Chars
+// quando Chars for true.
+type InlineChoice struct {
+	Line    int    `json:"line"`
+	Segment int    `json:"segment"`
+	Chars   bool   `json:"chars,omitempty"`
+	Side    string `json:"side"`
+}
+
+// inlineDiffs compara as linhas alteradas de ours com as de theirs que as
+// substituem, em pares na ordem em que aparecem.
+func inlineDiffs(ours, theirs []string) []InlineDiff {
+	var diffs []InlineDiff
+	for _, h := range diffHunks(ours, theirs) {
+		for i := 0; h.baseStart+i < h.baseEnd && h.sideStart+i < h.sideEnd; i++ {
+			o, t := h.baseStart+i, h.sideStart+i
+			diff := InlineDiff{
+				Ours:   o,
+				Theirs: t,
+				Words:  inlineSegments(wordTokens(ours[o]), wordTokens(theirs[t])),
+			}
+			if len(ours[o]) <= inlineMaxChars && len(theirs[t]) <= inlineMaxChars {
+				diff.Chars = inlineSegments(charTokens(ours[o]), charTokens(theirs[t]))
+			}
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// wordTokens separa uma linha em palavras (letras, dígitos e _), sequências
+// de espaços e caracteres de pontuação isolados.
+func wordTokens(line string) []string {
+	class := func(r rune) int {
+		switch {
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		}
+		return 0
+	}
+
+	var tokens []string
+	start, prev := 0, -1
+	for i, r := range line {
+		c := class(r)
+		if i > start && (c != prev || c == 0) {
+			tokens = append(tokens, line[start:i])
+			start = i
+		}
+		prev = c
+	}
+	if start < len(line) {
+		tokens = append(tokens, line[start:])
+	}
+	return tokens
+}
+
+// charTokens separa uma linha em caracteres.
+func charTokens(line string) []string {
+	tokens := make([]string, 0, len(line))
+	for _, r := range line {
+		tokens = append(tokens, string(r))
+	}
+	return tokens
+}
+
+// inlineSegments compara os tokens das duas linhas e junta os consecutivos
+// em trechos iguais e diferentes.
+func inlineSegments(ours, theirs []string) []InlineSegment {
+	var segments []InlineSegment
+	oursCol, theirsCol := 0, 0
+
+	add := func(changed bool, o, t []string) {
+		if len(o) == 0 && len(t) == 0 {
+			return
+		}
+		oursText, theirsText := strings.Join(o, ""), strings.Join(t, "")
+		segments = append(segments, InlineSegment{
+			Changed:     changed,
+			Ours:        oursText,
+			Theirs:      theirsText,
+			OursStart:   oursCol,
+			TheirsStart: theirsCol,
+		})
+		oursCol += utf16Len(oursText)
+		theirsCol += utf16Len(theirsText)
+	}
+
+	oi, ti := 0, 0
+	matches := append(diffLines(ours, theirs), lineMatch{a: len(ours), b: len(theirs)})
+	for k := 0; k < len(matches); {
+		m := matches[k]
+		add(true, ours[oi:m.a], theirs[ti:m.b])
+
+		// Tokens iguais consecutivos formam um só trecho
+		n := 0
+		for k+n < len(matches)-1 && matches[k+n].a == m.a+n && matches[k+n].b == m.b+n {
+			n++
+		}
+		add(false, ours[m.a:m.a+n], theirs[m.b:m.b+n])
+
+		oi, ti = m.a+n, m.b+n
+		k += max(n, 1)
+	}
+
+	return segments
+}
+
+// utf16Len é o tamanho do texto em unidades UTF-16.
+func utf16Len(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// inlineLines monta as linhas do bloco para ResolveInline.
+func (c *Chunk) inlineLines(choices []InlineChoice) ([]string, error) {
+	type segmentKey struct {
+		segment int
+		chars   bool
+	}
+
+	byLine := make(map[int]map[segmentKey]string)
+	for _, choice := range choices {
+		if choice.Line < 0 || choice.Line >= len(c.Inline) {
+			return nil, fmt.Errorf("bloco %s não tem a linha %d", c.ID, choice.Line)
+		}
+
+		diff := c.Inline[choice.Line]
+		segments := diff.Words
+		if choice.Chars {
+			segments = diff.Chars
+		}
+		if choice.Segment < 0 || choice.Segment >= len(segments) {
+			return nil, fmt.Errorf("linha %d do bloco %s não tem o trecho %d", choice.Line, c.ID, choice.Segment)
+		}
+
+		if choice.Side != ResolveOurs && choice.Side != ResolveTheirs {
+			return nil, fmt.Errorf("lado desconhecido para o trecho %d da linha %d: %s", choice.Segment, choice.Line, choice.Side)
+		}
+
+		if byLine[choice.Line] == nil {
+			byLine[choice.Line] = make(map[segmentKey]string)
+		}
+		for key := range byLine[choice.Line] {
+			if key.chars != choice.Chars {
+				return nil, fmt.Errorf("linha %d do bloco %s tem escolhas por palavra e por caractere", choice.Line, c.ID)
+			}
+		}
+		byLine[choice.Line][segmentKey{choice.Segment, choice.Chars}] = choice.Side
+	}
+
+	lines := append([]string(nil), sideLines(c.Ours)...)
+	for i, diff := range c.Inline {
+		chosen := byLine[i]
+
+		segments, chars := diff.Words, false
+		for key := range chosen {
+			if key.chars {
+				segments, chars = diff.Chars, true
+			}
+		}
+
+		var line strings.Builder
+		for j, segment := range segments {
+			if chosen[segmentKey{j, chars}] == ResolveTheirs {
+				line.WriteString(segment.Theirs)
+			} else {
+				line.WriteString(segment.Ours)
+			}
+		}
+		lines[diff.Ours] = line.String()
+	}
+
+	return lines, nil
+}
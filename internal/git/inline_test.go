@@ -0,0 +1,159 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWordTokens(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"foo_bar(x, 12)", []string{"foo_bar", "(", "x", ",", " ", "12", ")"}},
+		{"  a\tb", []string{"  ", "a", "\t", "b"}},
+		{"ação!!", []string{"ação", "!", "!"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := wordTokens(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wordTokens(%q) = %q, esperado %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestInlineSegments(t *testing.T) {
+	seg := func(changed bool, ours, theirs string, oursStart, theirsStart int) InlineSegment {
+		return InlineSegment{Changed: changed, Ours: ours, Theirs: theirs, OursStart: oursStart, TheirsStart: theirsStart}
+	}
+
+	tests := []struct {
+		name         string
+		ours, theirs string
+		chars        bool
+		want         []InlineSegment
+	}{
+		{
+			name: "palavras", ours: "x = foo(1)", theirs: "x = bar(1)",
+			want: []InlineSegment{seg(false, "x = ", "x = ", 0, 0), seg(true, "foo", "bar", 4, 4), seg(false, "(1)", "(1)", 7, 7)},
+		},
+		{
+			name: "caracteres", ours: "color", theirs: "colour", chars: true,
+			want: []InlineSegment{seg(false, "colo", "colo", 0, 0), seg(true, "", "u", 4, 4), seg(false, "r", "r", 4, 5)},
+		},
+		{
+			name: "colunas em UTF-16", ours: "é😀 a", theirs: "é😀 bb",
+			want: []InlineSegment{seg(false, "é😀 ", "é😀 ", 0, 0), seg(true, "a", "bb", 4, 4)},
+		},
+		{
+			name: "linha inteira diferente", ours: "a", theirs: "b",
+			want: []InlineSegment{seg(true, "a", "b", 0, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := wordTokens
+			if tt.chars {
+				tokens = charTokens
+			}
+			if got := inlineSegments(tokens(tt.ours), tokens(tt.theirs)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("inlineSegments = %+v, esperado %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInlineDiffs(t *testing.T) {
+	ours := []string{"igual", "a = 1", "só ours", "fim"}
+	theirs := []string{"igual", "a = 2", "fim"}
+
+	diffs := inlineDiffs(ours, theirs)
+	if len(diffs) != 1 || diffs[0].Ours != 1 || diffs[0].Theirs != 1 {
+		t.Fatalf("inlineDiffs = %+v, esperado o par das linhas 1", diffs)
+	}
+	if len(diffs[0].Words) != 2 || len(diffs[0].Chars) != 2 {
+		t.Fatalf("trechos = %+v", diffs[0])
+	}
+
+	// Linhas longas só têm a comparação por palavras
+	long := strings.Repeat("a ", inlineMaxChars)
+	diffs = inlineDiffs([]string{long + "x"}, []string{long + "y"})
+	if len(diffs) != 1 || diffs[0].Words == nil || diffs[0].Chars != nil {
+		t.Fatalf("inlineDiffs de linha longa = %d pares, chars = %v", len(diffs), diffs[0].Chars != nil)
+	}
+}
+
+func TestResolveInline(t *testing.T) {
+	newMerge := func() *FileMerge {
+		return newFileMerge("a.go", ConflictLabels{}, []Chunk{{
+			Conflict: true,
+			Ours:     &HunkSide{Lines: []string{"x := foo(1)", "extra"}},
+			Theirs:   &HunkSide{Lines: []string{"x := bar(2)"}},
+		}})
+	}
+
+	// Words: "x := " | foo/bar | "(" | 1/2 | ")"
+	tests := []struct {
+		name    string
+		choices []InlineChoice
+		want    string
+		fails   bool
+	}{
+		{name: "sem escolhas fica ours", want: "x := foo(1)\nextra"},
+		{
+			name:    "uma palavra de theirs",
+			choices: []InlineChoice{{Line: 0, Segment: 1, Side: ResolveTheirs}},
+			want:    "x := bar(1)\nextra",
+		},
+		{
+			name: "todas as palavras de theirs",
+			choices: []InlineChoice{
+				{Line: 0, Segment: 1, Side: ResolveTheirs},
+				{Line: 0, Segment: 3, Side: ResolveTheirs},
+			},
+			want: "x := bar(2)\nextra",
+		},
+		{
+			name:    "por caractere",
+			choices: []InlineChoice{{Line: 0, Segment: 3, Chars: true, Side: ResolveTheirs}},
+			want:    "x := foo(2)\nextra",
+		},
+		{
+			name: "palavra e caractere na mesma linha",
+			choices: []InlineChoice{
+				{Line: 0, Segment: 1, Side: ResolveTheirs},
+				{Line: 0, Segment: 1, Chars: true, Side: ResolveTheirs},
+			},
+			fails: true,
+		},
+		{name: "linha inexistente", choices: []InlineChoice{{Line: 1, Side: ResolveOurs}}, fails: true},
+		{name: "trecho inexistente", choices: []InlineChoice{{Segment: 9, Side: ResolveOurs}}, fails: true},
+		{name: "lado desconhecido", choices: []InlineChoice{{Segment: 1, Side: ResolveBase}}, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := newMerge()
+			if got := merge.Chunks[0].Inline[0].Chars; len(got) != 5 || got[3].Ours != "1" {
+				t.Fatalf("trechos por caractere = %+v", got)
+			}
+
+			err := merge.Apply(HunkDecision{Hunk: merge.Chunks[0].ID, Resolution: ResolveInline, Choices: tt.choices})
+			if tt.fails {
+				if err == nil || merge.Conflicts != 1 {
+					t.Fatalf("Apply = %v, conflitos = %d; esperado erro", err, merge.Conflicts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if got := merge.Render(ConflictMerge); got != tt.want {
+				t.Fatalf("Render = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
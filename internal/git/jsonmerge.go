@@ -0,0 +1,436 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONDriver é o nome do driver de merge de arquivos JSON.
+const JSONDriver = "json"
+
+func init() {
+	RegisterMergeDriver(JSONDriver, MergeDriverFunc(jsonMerge))
+	_ = RegisterMergeDriverPattern("*.json", JSONDriver)
+}
+
+// jsonNode é um valor JSON que guarda a ordem das chaves dos objetos.
+// Escalares (strings, números, booleanos e null) ficam em raw. text é o
+// trecho original do valor no documento, copiado para o resultado quando o
+// valor não precisa de merge.
+type jsonNode struct {
+	object  []jsonMember
+	array   []*jsonNode
+	raw     string
+	text    string
+	isObj   bool
+	isArray bool
+}
+
+// jsonMember é um par chave e valor de um objeto JSON.
+type jsonMember struct {
+	key   string
+	value *jsonNode
+}
+
+// jsonMerge é o driver de arquivos JSON: os objetos são mesclados chave a
+// chave em relação à base e só há conflito nas chaves que os dois lados
+// alteraram de formas diferentes. Arrays e escalares alterados pelos dois
+// lados são conflito. Os valores que não precisam de merge mantêm o texto
+// original e as chaves mescladas usam a indentação de ours. Se algum lado
+// não for JSON válido, ou se ours for JSON compacto em uma linha, o merge é
+// o de texto.
+func jsonMerge(input MergeInput) ([]Chunk, error) {
+	ours, errOurs := parseJSON(input.Ours)
+	theirs, errTheirs := parseJSON(input.Theirs)
+
+	var base *jsonNode
+	var errBase error
+	if input.HasBase && strings.TrimSpace(input.Base) != "" {
+		base, errBase = parseJSON(input.Base)
+	}
+
+	if errOurs != nil || errTheirs != nil || errBase != nil {
+		return textMerge(input)
+	}
+
+	// Sem alteração de conteúdo em um dos lados, o texto do outro é mantido
+	// como está, sem reformatar
+	switch {
+	case sameJSON(ours, theirs), base != nil && sameJSON(base, theirs):
+		return []Chunk{{Lines: splitLines(input.Ours)}}, nil
+	case base != nil && sameJSON(base, ours):
+		return []Chunk{{Lines: splitLines(input.Theirs)}}, nil
+	}
+
+	// Um documento compacto, em uma só linha, não tem onde separar os blocos
+	// sem ser reformatado
+	if !strings.Contains(strings.TrimSpace(input.Ours), "\n") {
+		return textMerge(input)
+	}
+
+	w := &jsonWriter{indent: detectIndent(input.Ours)}
+	w.value(base, ours, theirs, 0, "", "")
+	w.flush()
+
+	// Mantém a quebra de linha final de ours
+	if strings.HasSuffix(input.Ours, "\n") {
+		w.stable = append(w.stable, "")
+		w.flush()
+	}
+
+	return w.chunks, nil
+}
+
+// jsonDecoder lê um documento JSON guardando o texto original de cada valor.
+type jsonDecoder struct {
+	*json.Decoder
+	content string
+}
+
+// parseJSON lê um documento JSON mantendo a ordem das chaves.
+func parseJSON(content string) (*jsonNode, error) {
+	decoder := &jsonDecoder{Decoder: json.NewDecoder(strings.NewReader(content)), content: content}
+	decoder.UseNumber()
+
+	node, err := decoder.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("conteúdo extra depois do documento JSON")
+	}
+
+	return node, nil
+}
+
+// start retorna a posição no documento onde começa o próximo valor, depois
+// dos espaços e separadores.
+func (d *jsonDecoder) start() int {
+	offset := int(d.InputOffset())
+	for offset < len(d.content) && strings.IndexByte(" \t\r\n:,", d.content[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// value lê o próximo valor do documento.
+func (d *jsonDecoder) value() (*jsonNode, error) {
+	start := d.start()
+
+	node, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	node.text = d.content[start:d.InputOffset()]
+	return node, nil
+}
+
+// decode lê o próximo valor do documento, sem o texto original.
+func (d *jsonDecoder) decode() (*jsonNode, error) {
+	token, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			node := &jsonNode{isObj: true}
+			for d.More() {
+				keyToken, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := d.value()
+				if err != nil {
+					return nil, err
+				}
+				node.object = append(node.object, jsonMember{key: keyToken.(string), value: value})
+			}
+			_, err := d.Token()
+			return node, err
+		case '[':
+			node := &jsonNode{isArray: true}
+			for d.More() {
+				value, err := d.value()
+				if err != nil {
+					return nil, err
+				}
+				node.array = append(node.array, value)
+			}
+			_, err := d.Token()
+			return node, err
+		}
+		return nil, fmt.Errorf("delimitador inesperado %s", t)
+	case json.Number:
+		return &jsonNode{raw: t.String()}, nil
+	case nil:
+		return &jsonNode{raw: "null"}, nil
+	default:
+		return &jsonNode{raw: encodeJSONScalar(t)}, nil
+	}
+}
+
+// encodeJSONScalar escreve uma string ou booleano em JSON.
+func encodeJSONScalar(value any) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// member retorna o valor de uma chave do objeto, nil se não existir.
+func (n *jsonNode) member(key string) *jsonNode {
+	if n == nil || !n.isObj {
+		return nil
+	}
+	for _, m := range n.object {
+		if m.key == key {
+			return m.value
+		}
+	}
+	return nil
+}
+
+// sameJSON informa se dois valores são iguais; a ordem das chaves dos
+// objetos não importa. Dois valores ausentes são iguais.
+func sameJSON(a, b *jsonNode) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	switch {
+	case a.isObj && b.isObj:
+		if len(a.object) != len(b.object) {
+			return false
+		}
+		for _, m := range a.object {
+			if !sameJSON(m.value, b.member(m.key)) {
+				return false
+			}
+		}
+		return true
+	case a.isArray && b.isArray:
+		if len(a.array) != len(b.array) {
+			return false
+		}
+		for i := range a.array {
+			if !sameJSON(a.array[i], b.array[i]) {
+				return false
+			}
+		}
+		return true
+	case a.isObj || b.isObj || a.isArray || b.isArray:
+		return false
+	}
+	return a.raw == b.raw
+}
+
+// detectIndent retorna a indentação usada no documento: a da primeira linha
+// indentada, ou dois espaços.
+func detectIndent(content string) string {
+	for _, line := range splitLines(content) {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+// jsonWriter escreve o resultado do merge em linhas, separando os trechos
+// estáveis dos blocos em conflito.
+type jsonWriter struct {
+	indent string
+	stable []string
+	chunks []Chunk
+	line   int
+}
+
+// flush fecha o trecho estável em andamento.
+func (w *jsonWriter) flush() {
+	if len(w.stable) > 0 {
+		w.chunks = append(w.chunks, Chunk{Lines: w.stable})
+		w.stable = nil
+	}
+}
+
+// emit acrescenta uma linha estável.
+func (w *jsonWriter) emit(line string) {
+	w.stable = append(w.stable, line)
+	w.line++
+}
+
+// value escreve o merge de um valor. prefix vem antes do valor na primeira
+// linha (a chave) e suffix depois dele na última (a vírgula).
+func (w *jsonWriter) value(base, ours, theirs *jsonNode, depth int, prefix, suffix string) {
+	if n, ok := jsonChoice(base, ours, theirs); ok {
+		w.write(n, depth, prefix, suffix)
+		return
+	}
+
+	if !mergeableObjects(base, ours, theirs) {
+		w.conflict(base, ours, theirs, depth, prefix, suffix)
+		return
+	}
+
+	pad := strings.Repeat(w.indent, depth)
+	w.emit(pad + prefix + "{")
+	w.members(base, ours, theirs, depth+1)
+	w.emit(pad + "}" + suffix)
+}
+
+// jsonChoice retorna o valor do resultado quando ele não depende de merge:
+// o dos dois lados, se forem iguais, ou o do lado que alterou o valor. Retorna
+// false se os dois lados o alteraram.
+func jsonChoice(base, ours, theirs *jsonNode) (*jsonNode, bool) {
+	switch {
+	case sameJSON(ours, theirs), sameJSON(base, theirs):
+		return ours, true
+	case sameJSON(base, ours):
+		return theirs, true
+	}
+	return nil, false
+}
+
+// mergeableObjects informa se os dois lados são objetos, mesclados chave a
+// chave.
+func mergeableObjects(base, ours, theirs *jsonNode) bool {
+	return ours != nil && theirs != nil && ours.isObj && theirs.isObj && (base == nil || base.isObj)
+}
+
+// members escreve o merge das chaves de um objeto. A vírgula depois de uma
+// chave depende de haver outra depois dela em cada lado: as chaves em
+// conflito depois da última resolvida sem conflito ficam em um só bloco, com
+// a última linha dessa chave, e cada lado leva as próprias vírgulas.
+func (w *jsonWriter) members(base, ours, theirs *jsonNode, depth int) {
+	keys := mergedKeys(base, ours, theirs)
+
+	// last é a última chave que não está em conflito, presente nos três lados
+	last := -1
+	for i, key := range keys {
+		b, o, t := base.member(key), ours.member(key), theirs.member(key)
+		if _, ok := jsonChoice(b, o, t); ok || mergeableObjects(b, o, t) {
+			last = i
+		}
+	}
+
+	// A vírgula da última delas vai para o bloco com as chaves seguintes
+	for i, key := range keys[:last+1] {
+		comma := ","
+		if i == last {
+			comma = ""
+		}
+		w.value(base.member(key), ours.member(key), theirs.member(key), depth, encodeJSONScalar(key)+": ", comma)
+	}
+
+	tail := keys[last+1:]
+	if len(tail) == 0 {
+		return
+	}
+
+	// A última linha da chave anterior passa para o bloco, sem a vírgula
+	var previous []string
+	if last >= 0 {
+		previous = w.stable[len(w.stable)-1:]
+		w.stable = w.stable[:len(w.stable)-1]
+		w.line--
+	}
+
+	side := func(n *jsonNode) *HunkSide {
+		var members []jsonMember
+		for _, key := range tail {
+			if value := n.member(key); value != nil {
+				members = append(members, jsonMember{key: key, value: value})
+			}
+		}
+
+		lines := append([]string(nil), previous...)
+		if len(lines) > 0 && len(members) > 0 {
+			lines[0] += ","
+		}
+		for i, m := range members {
+			comma := ","
+			if i == len(members)-1 {
+				comma = ""
+			}
+			lines = append(lines, w.lines(m.value, depth, encodeJSONScalar(m.key)+": ", comma)...)
+		}
+		return newHunkSide(lines, w.line)
+	}
+
+	w.flush()
+	chunk := Chunk{Conflict: true, Ours: side(ours), Theirs: side(theirs), Base: side(base)}
+	w.chunks = append(w.chunks, chunk)
+	w.line += chunk.Ours.Count
+}
+
+// mergedKeys retorna as chaves do objeto resultante: as de ours na ordem de
+// ours e depois as que só theirs acrescentou, na ordem de theirs. Chaves que
+// um lado removeu e o outro não alterou saem do resultado.
+func mergedKeys(base, ours, theirs *jsonNode) []string {
+	var keys []string
+	seen := make(map[string]bool)
+
+	add := func(key string) {
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		if n, ok := jsonChoice(base.member(key), ours.member(key), theirs.member(key)); ok && n == nil {
+			return
+		}
+		keys = append(keys, key)
+	}
+
+	for _, m := range ours.object {
+		add(m.key)
+	}
+	for _, m := range theirs.object {
+		add(m.key)
+	}
+
+	return keys
+}
+
+// conflict escreve um bloco em conflito com as três versões do valor.
+// Um lado que removeu a chave fica vazio.
+func (w *jsonWriter) conflict(base, ours, theirs *jsonNode, depth int, prefix, suffix string) {
+	w.flush()
+
+	side := func(n *jsonNode) *HunkSide {
+		return newHunkSide(w.lines(n, depth, prefix, suffix), w.line)
+	}
+
+	chunk := Chunk{Conflict: true, Ours: side(ours), Theirs: side(theirs), Base: side(base)}
+	w.chunks = append(w.chunks, chunk)
+	w.line += chunk.Ours.Count
+}
+
+// write escreve um valor sem merge.
+func (w *jsonWriter) write(n *jsonNode, depth int, prefix, suffix string) {
+	for _, line := range w.lines(n, depth, prefix, suffix) {
+		w.emit(line)
+	}
+}
+
+// lines retorna as linhas de um valor com o texto original do documento; a
+// primeira leva a indentação e a chave. Um valor ausente não tem linhas.
+func (w *jsonWriter) lines(n *jsonNode, depth int, prefix, suffix string) []string {
+	if n == nil {
+		return nil
+	}
+
+	lines := splitLines(n.text)
+	lines[0] = strings.Repeat(w.indent, depth) + prefix + lines[0]
+	lines[len(lines)-1] += suffix
+	return lines
+}
@@ -0,0 +1,124 @@
+package git
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONMerge(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflicts          int
+		invalid            bool
+	}{
+		{
+			name:   "chaves diferentes alteradas",
+			base:   "{\n  \"a\": 1,\n  \"b\": 2,\n  \"c\": 3\n}\n",
+			ours:   "{\n  \"a\": 10,\n  \"b\": 2,\n  \"c\": 3\n}\n",
+			theirs: "{\n  \"a\": 1,\n  \"b\": 2,\n  \"c\": 30\n}\n",
+			want:   "{\n  \"a\": 10,\n  \"b\": 2,\n  \"c\": 30\n}\n",
+		},
+		{
+			name:   "objetos aninhados",
+			base:   "{\n  \"a\": {\n    \"b\": 1,\n    \"c\": 1\n  }\n}\n",
+			ours:   "{\n  \"a\": {\n    \"b\": 2,\n    \"c\": 1\n  }\n}\n",
+			theirs: "{\n  \"a\": {\n    \"b\": 1,\n    \"c\": 2\n  },\n  \"d\": null\n}\n",
+			want:   "{\n  \"a\": {\n    \"b\": 2,\n    \"c\": 2\n  },\n  \"d\": null\n}\n",
+		},
+		{
+			name:   "valores sem merge mantêm o texto original",
+			base:   "{\n  \"x\": [1, 2],\n  \"a\": 1\n}\n",
+			ours:   "{\n  \"x\": [1, 2],\n  \"a\": 1,\n  \"n\": {\"k\": true}\n}\n",
+			theirs: "{\n  \"x\": [1, 2],\n  \"a\": 2\n}\n",
+			want:   "{\n  \"x\": [1, 2],\n  \"a\": 2,\n  \"n\": {\"k\": true}\n}\n",
+		},
+		{
+			name:   "sem ancestral comum",
+			ours:   "{\n  \"a\": 1\n}\n",
+			theirs: "{\"b\": 2}\n",
+			want:   "{\n  \"a\": 1,\n  \"b\": 2\n}\n",
+		},
+		{
+			name:   "JSON compacto alterado em um lado continua compacto",
+			base:   "{\"a\":1,\"b\":2}\n",
+			ours:   "{\"a\":1,\"b\":2}\n",
+			theirs: "{\"a\":1,\"b\":3}\n",
+			want:   "{\"a\":1,\"b\":3}\n",
+		},
+		{
+			name:      "JSON compacto alterado nos dois lados usa o merge de texto",
+			base:      "{\"a\":1,\"b\":2}\n",
+			ours:      "{\"a\":5,\"b\":2}\n",
+			theirs:    "{\"a\":1,\"b\":3}\n",
+			want:      "<<<<<<< ours\n{\"a\":5,\"b\":2}\n=======\n{\"a\":1,\"b\":3}\n>>>>>>> theirs\n",
+			conflicts: 1,
+		},
+		{
+			name:      "mesma chave alterada nos dois lados",
+			base:      "{\n  \"a\": 1,\n  \"b\": 2\n}\n",
+			ours:      "{\n  \"a\": 5,\n  \"b\": 2\n}\n",
+			theirs:    "{\n  \"a\": 6,\n  \"b\": 2\n}\n",
+			want:      "{\n<<<<<<< ours\n  \"a\": 5,\n=======\n  \"a\": 6,\n>>>>>>> theirs\n  \"b\": 2\n}\n",
+			conflicts: 1,
+		},
+		{
+			name:      "última chave removida e alterada",
+			base:      "{\n  \"a\": 1,\n  \"b\": 2\n}\n",
+			ours:      "{\n  \"a\": 1\n}\n",
+			theirs:    "{\n  \"a\": 1,\n  \"b\": 3\n}\n",
+			want:      "{\n<<<<<<< ours\n  \"a\": 1\n=======\n  \"a\": 1,\n  \"b\": 3\n>>>>>>> theirs\n}\n",
+			conflicts: 1,
+		},
+		{
+			name:      "JSON inválido usa o merge de texto",
+			base:      "{\"a\": 1}",
+			ours:      "{\"a\": 1",
+			theirs:    "{\"a\": 2}",
+			want:      "<<<<<<< ours\n{\"a\": 1\n=======\n{\"a\": 2}\n>>>>>>> theirs",
+			conflicts: 1,
+			invalid:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := driverMerge(t, jsonMerge, "x.json", tt.base, tt.ours, tt.theirs)
+			if got := merge.Render(ConflictMerge); got != tt.want {
+				t.Errorf("Render =\n%s\nesperado\n%s", got, tt.want)
+			}
+			if merge.Conflicts != tt.conflicts {
+				t.Errorf("conflitos = %d, esperado %d", merge.Conflicts, tt.conflicts)
+			}
+			if tt.invalid {
+				return
+			}
+
+			// Qualquer lado escolhido nos blocos resulta em JSON válido
+			for _, resolution := range []string{ResolveOurs, ResolveTheirs} {
+				if content := resolveAll(t, merge, resolution); !json.Valid([]byte(content)) {
+					t.Errorf("resolução %s não é JSON válido:\n%s", resolution, content)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONMergeFile(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"config.json": "{\n  \"a\": 1,\n  \"b\": 1\n}\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"config.json": "{\n  \"a\": 2,\n  \"b\": 1\n}\n"})
+	repo.commit("main", map[string]string{"config.json": "{\n  \"a\": 1,\n  \"b\": 2\n}\n"})
+
+	merge, err := repo.control.MergeFile("feature", "main", "config.json", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{Disabled: true})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+
+	want := "{\n  \"a\": 2,\n  \"b\": 2\n}\n"
+	if merge.Conflicts != 0 || merge.Render(ConflictMerge) != want {
+		t.Fatalf("conflitos = %d, Render =\n%s\nesperado\n%s", merge.Conflicts, merge.Render(ConflictMerge), want)
+	}
+}
@@ -0,0 +1,258 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Marcadores de conflito no formato usado pelo git
+const (
+	markerOurs   = "<<<<<<<"
+	markerBase   = "|||||||"
+	markerSep    = "======="
+	markerTheirs = ">>>>>>>"
+)
+
+// ConflictStyle define como os blocos de conflito são escritos,
+// como a opção merge.conflictStyle do git.
+type ConflictStyle int
+
+const (
+	// ConflictMerge escreve apenas os dois lados do conflito.
+	ConflictMerge ConflictStyle = iota
+
+	// ConflictDiff3 inclui a versão do ancestral comum numa seção
+	// "||||||| base" entre os dois lados.
+	ConflictDiff3
+
+	// ConflictZdiff3 é o diff3 com as linhas iniciais e finais iguais nos
+	// dois lados movidas para fora do bloco de conflito.
+	ConflictZdiff3
+)
+
+// ParseConflictStyle converte o nome usado na API em ConflictStyle.
+// Aceita "merge", "diff3" e "zdiff3"; vazio resulta em ConflictMerge.
+func ParseConflictStyle(name string) (ConflictStyle, error) {
+	switch name {
+	case "", "merge":
+		return ConflictMerge, nil
+	case "diff3":
+		return ConflictDiff3, nil
+	case "zdiff3":
+		return ConflictZdiff3, nil
+	}
+	return ConflictMerge, fmt.Errorf("estilo de conflito desconhecido: %s", name)
+}
+
+// defaultLabels são os nomes usados quando não há branches para identificar
+// os lados do conflito.
+var defaultLabels = ConflictLabels{Ours: "HEAD", Base: "base", Theirs: "branch"}
+
+// sideHunk associa um trecho alterado ao lado que o alterou.
+// ignored marca trechos que as DiffOptions mandam ignorar.
+type sideHunk struct {
+	diffHunk
+	ours    bool
+	ignored bool
+}
+
+// sideHunks calcula os trechos alterados de um lado em relação à base,
+// comparando as linhas conforme opts.
+func sideHunks(base, side []string, ours bool, opts DiffOptions) []sideHunk {
+	base, side = opts.normalize(base), opts.normalize(side)
+
+	var hunks []sideHunk
+	for _, h := range diffHunks(base, side) {
+		hunks = append(hunks, sideHunk{diffHunk: h, ours: ours, ignored: opts.ignored(h, base, side)})
+	}
+	return hunks
+}
+
+// mergeLines faz o merge de três vias linha a linha.
+// base é o ancestral comum, ours e theirs as duas versões derivadas dele.
+// Apenas regiões alteradas pelos dois lados de formas diferentes viram conflito.
+// opts define as diferenças ignoradas; onde nenhum lado alterou a região
+// (pelas regras de opts) o resultado usa as linhas de ours.
+func mergeLines(base, ours, theirs []string, opts DiffOptions) []Chunk {
+	hunks := append(sideHunks(base, ours, true, opts), sideHunks(base, theirs, false, opts)...)
+
+	// Ordena pela posição na base; em empate, ours vem primeiro
+	sort.SliceStable(hunks, func(i, j int) bool {
+		if hunks[i].baseStart != hunks[j].baseStart {
+			return hunks[i].baseStart < hunks[j].baseStart
+		}
+		return hunks[i].ours && !hunks[j].ours
+	})
+
+	var chunks []Chunk
+	stable := func(lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		// Junta trechos estáveis consecutivos
+		if n := len(chunks); n > 0 && !chunks[n-1].Conflict {
+			chunks[n-1].Lines = append(chunks[n-1].Lines, lines...)
+			return
+		}
+		chunks = append(chunks, Chunk{Lines: append([]string(nil), lines...)})
+	}
+
+	// Fora dos trechos alterados, a linha i da base é a linha i+delta de cada lado
+	baseIdx, oursDelta, theirsDelta := 0, 0, 0
+	for len(hunks) > 0 {
+		// Agrupa os trechos que se sobrepõem (ou se tocam) na base
+		regionStart := hunks[0].baseStart
+		regionEnd := hunks[0].baseEnd
+		region := []sideHunk{hunks[0]}
+		hunks = hunks[1:]
+
+		for len(hunks) > 0 && hunks[0].baseStart <= regionEnd {
+			if hunks[0].baseEnd > regionEnd {
+				regionEnd = hunks[0].baseEnd
+			}
+			region = append(region, hunks[0])
+			hunks = hunks[1:]
+		}
+
+		stable(ours[baseIdx+oursDelta : regionStart+oursDelta])
+		baseIdx = regionEnd
+
+		oursLines, oursStart, oursChanged := regionSide(region, true, regionStart, regionEnd, oursDelta, ours)
+		theirsLines, theirsStart, theirsChanged := regionSide(region, false, regionStart, regionEnd, theirsDelta, theirs)
+		oursDelta = oursStart + len(oursLines) - regionEnd
+		theirsDelta = theirsStart + len(theirsLines) - regionEnd
+
+		switch {
+		case theirsChanged && !oursChanged:
+			stable(theirsLines)
+		case !theirsChanged:
+			stable(oursLines)
+		case opts.equal(oursLines, theirsLines):
+			// Os dois lados fizeram a mesma alteração
+			stable(oursLines)
+		default:
+			chunks = append(chunks, Chunk{
+				Conflict: true,
+				Ours:     newHunkSide(oursLines, oursStart),
+				Base:     newHunkSide(append([]string(nil), base[regionStart:regionEnd]...), regionStart),
+				Theirs:   newHunkSide(theirsLines, theirsStart),
+			})
+		}
+	}
+
+	stable(ours[baseIdx+oursDelta:])
+
+	return chunks
+}
+
+// regionSide retorna as linhas de um lado correspondentes à região base[start:end],
+// a posição, a partir de zero, onde elas começam no lado e se o lado alterou a
+// região. delta é o deslocamento do lado em relação à base antes da região.
+// Fora dos trechos alterados pelo lado, a região é igual à base, então basta
+// estender o primeiro e o último trecho até as bordas da região.
+func regionSide(region []sideHunk, ours bool, start, end, delta int, side []string) ([]string, int, bool) {
+	var first, last *sideHunk
+	changed := false
+	for i := range region {
+		if region[i].ours != ours {
+			continue
+		}
+		if first == nil {
+			first = &region[i]
+		}
+		last = &region[i]
+		changed = changed || !region[i].ignored
+	}
+
+	if first == nil {
+		return append([]string(nil), side[start+delta:end+delta]...), start + delta, false
+	}
+
+	sideStart := first.sideStart - (first.baseStart - start)
+	sideEnd := last.sideEnd + (end - last.baseEnd)
+
+	return append([]string(nil), side[sideStart:sideEnd]...), sideStart, changed
+}
+
+// diffChunks gera os trechos de um diff de duas vias, sem ancestral comum.
+// Cada região diferente entre ours e theirs, conforme opts, vira um conflito
+// sem base.
+func diffChunks(ours, theirs []string, opts DiffOptions) []Chunk {
+	var chunks []Chunk
+	oursIdx := 0
+
+	for _, h := range sideHunks(ours, theirs, false, opts) {
+		if h.ignored {
+			continue
+		}
+		if h.baseStart > oursIdx {
+			chunks = append(chunks, Chunk{Lines: ours[oursIdx:h.baseStart]})
+		}
+		chunks = append(chunks, Chunk{
+			Conflict: true,
+			Ours:     newHunkSide(ours[h.baseStart:h.baseEnd], h.baseStart),
+			Theirs:   newHunkSide(theirs[h.sideStart:h.sideEnd], h.sideStart),
+		})
+		oursIdx = h.baseEnd
+	}
+
+	if oursIdx < len(ours) {
+		chunks = append(chunks, Chunk{Lines: ours[oursIdx:]})
+	}
+
+	return chunks
+}
+
+// commonPrefix retorna quantas linhas iniciais são iguais em a e b.
+func commonPrefix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// commonSuffix retorna quantas linhas finais são iguais em a e b.
+func commonSuffix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
+
+// hasConflictMarkers informa se o conteúdo ainda tem marcadores de conflito:
+// uma linha <<<<<<<, depois ======= (com ou sem ||||||| antes) e depois
+// >>>>>>>, nessa ordem. Linhas ======= soltas, como os títulos sublinhados de
+// Markdown e RST, não contam.
+func hasConflictMarkers(content string) bool {
+	// 0: fora de conflito; 1: em ours; 2: na base; 3: em theirs
+	state := 0
+	for _, line := range splitLines(content) {
+		switch {
+		case isConflictMarker(line, markerOurs):
+			state = 1
+		case state == 1 && isConflictMarker(line, markerBase):
+			state = 2
+		case (state == 1 || state == 2) && line == markerSep:
+			state = 3
+		case state == 3 && isConflictMarker(line, markerTheirs):
+			return true
+		}
+	}
+	return false
+}
+
+// isConflictMarker informa se a linha é o marcador informado, sozinho ou
+// seguido de um espaço e do rótulo do lado.
+func isConflictMarker(line, marker string) bool {
+	return line == marker || strings.HasPrefix(line, marker+" ")
+}
+
+// mergeContents faz o merge de três vias de conteúdos completos e retorna o
+// texto com marcadores de conflito no estilo informado e a quantidade de conflitos.
+func mergeContents(base, ours, theirs string, style ConflictStyle, labels ConflictLabels) (string, int) {
+	merge := newFileMerge("", labels, mergeLines(splitLines(base), splitLines(ours), splitLines(theirs), DiffOptions{}))
+	return merge.Render(style), merge.Conflicts
+}
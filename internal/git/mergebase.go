@@ -0,0 +1,316 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// CompareMode define contra qual versão a branch é comparada.
+type CompareMode int
+
+const (
+	// CompareTips compara diretamente as pontas das duas branches,
+	// como `git diff base branch`.
+	CompareTips CompareMode = iota
+
+	// CompareMergeBase compara a branch com o ancestral comum,
+	// como `git diff base...branch`. Arquivos alterados apenas na base
+	// depois do fork não aparecem como alterados na branch.
+	CompareMergeBase
+)
+
+// ParseCompareMode converte o nome usado na API em CompareMode.
+// Aceita "tips" e "mergebase"; vazio resulta em CompareTips, o comportamento
+// anterior ao modo mergebase, que precisa ser pedido explicitamente.
+func ParseCompareMode(name string) (CompareMode, error) {
+	switch name {
+	case "", "tips":
+		return CompareTips, nil
+	case "mergebase":
+		return CompareMergeBase, nil
+	}
+	return CompareTips, fmt.Errorf("modo de comparação desconhecido: %s", name)
+}
+
+// comparison reúne os commits e árvores usados para comparar uma branch
+// com a base, além das alterações já calculadas.
+type comparison struct {
+	// targetName e baseName são as revisões como foram informadas
+	targetName string
+	baseName   string
+
+	targetCommit *object.Commit
+	baseCommit   *object.Commit
+	targetTree   *object.Tree
+	baseTree     *object.Tree
+
+	// ancestors são os ancestrais comuns das duas branches.
+	// Em históricos cruzados (criss-cross) pode haver mais de um.
+	ancestors []*object.Commit
+
+	// changes são as alterações da referência de comparação (ponta da base
+	// ou ancestral comum, conforme o modo) até a branch alvo.
+	changes object.Changes
+
+	// renames marca as alterações de changes que são renomeações ou cópias
+	// detectadas, com a similaridade entre os conteúdos.
+	renames map[*object.Change]renameInfo
+
+	// renameOptions é a detecção de renomeações pedida para a comparação,
+	// usada também nos conflitos de árvore
+	renameOptions RenameOptions
+
+	// similarities guarda as linhas dos blobs lidos na detecção de
+	// renomeações, reaproveitadas pelos conflitos de árvore
+	similarities *similarityCache
+
+	// targetAttrs e baseAttrs leem os .gitattributes das duas árvores
+	targetAttrs *attributes
+	baseAttrs   *attributes
+}
+
+// labels retorna os nomes dos marcadores de conflito da comparação: as
+// revisões informadas e os hashes abreviados dos dois lados e da base.
+func (cmp *comparison) labels() ConflictLabels {
+	labels := ConflictLabels{
+		Ours:   revisionLabel(cmp.targetName, cmp.targetCommit),
+		Theirs: revisionLabel(cmp.baseName, cmp.baseCommit),
+	}
+
+	switch len(cmp.ancestors) {
+	case 0:
+		labels.Base = "sem ancestral comum"
+	case 1:
+		labels.Base = revisionLabel("merge-base", cmp.ancestors[0])
+	default:
+		hashes := make([]string, 0, len(cmp.ancestors))
+		for _, ancestor := range cmp.ancestors {
+			hashes = append(hashes, shortHash(ancestor))
+		}
+		labels.Base = "merged common ancestors (" + strings.Join(hashes, ", ") + ")"
+	}
+
+	return labels
+}
+
+// revisionLabel monta o nome de um lado do conflito, como "main (1a2b3c4)".
+func revisionLabel(name string, commit *object.Commit) string {
+	return name + " (" + shortHash(commit) + ")"
+}
+
+// shortHash retorna o hash abreviado do commit, como `git rev-parse --short`.
+func shortHash(commit *object.Commit) string {
+	return commit.Hash.String()[:7]
+}
+
+// compareBranches carrega as duas branches e calcula as alterações da branch
+// alvo de acordo com o modo de comparação e a detecção de renomeações.
+func (e *Control) compareBranches(branchName, branchBase string, mode CompareMode, renames RenameOptions) (*comparison, error) {
+	if e.repository == nil {
+		return nil, fmt.Errorf("repositório não inicializado")
+	}
+
+	cmp := &comparison{targetName: branchName, baseName: branchBase, renameOptions: renames}
+
+	// Obtém os commits das duas revisões (branches, tags, hashes...)
+	var err error
+	if cmp.targetCommit, err = e.resolveCommit(branchName); err != nil {
+		return nil, err
+	}
+
+	if cmp.baseCommit, err = e.resolveCommit(branchBase); err != nil {
+		return nil, err
+	}
+
+	// Obtém as árvores de arquivos
+	cmp.targetTree, err = cmp.targetCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchName, err)
+	}
+
+	cmp.baseTree, err = cmp.baseCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao obter árvore da branch %s: %w", branchBase, err)
+	}
+
+	cmp.targetAttrs = newAttributes(cmp.targetTree)
+	cmp.baseAttrs = newAttributes(cmp.baseTree)
+
+	cmp.ancestors, err = mergeBases(cmp.targetCommit, cmp.baseCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sem ancestral comum não há o que comparar além das pontas
+	fromTree := cmp.baseTree
+	if mode == CompareTips || len(cmp.ancestors) == 0 {
+		cmp.changes, err = object.DiffTree(cmp.baseTree, cmp.targetTree)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao comparar árvores: %w", err)
+		}
+	} else {
+		cmp.changes, err = changesSinceAncestors(cmp.ancestors, cmp.targetTree)
+		if err != nil {
+			return nil, err
+		}
+
+		if fromTree, err = cmp.ancestors[0].Tree(); err != nil {
+			return nil, fmt.Errorf("erro ao obter árvore do commit %s: %w", cmp.ancestors[0].Hash, err)
+		}
+	}
+
+	// As árvores são comparadas sem a detecção de renomeações do go-git,
+	// que usa um limiar fixo; aqui vale o limiar pedido
+	cmp.similarities = e.newSimilarityCache()
+	cmp.changes, cmp.renames, err = e.detectRenames(cmp.changes, fromTree, renames, cmp.similarities)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmp, nil
+}
+
+// changesSinceAncestors retorna as alterações feitas na árvore alvo desde os
+// ancestrais comuns. Com mais de um ancestral, um arquivo só é considerado
+// alterado se for diferente em relação a todos eles; se for igual a algum,
+// a mudança veio do histórico compartilhado e não da branch.
+func changesSinceAncestors(ancestors []*object.Commit, targetTree *object.Tree) (object.Changes, error) {
+	var changes object.Changes
+	var kept map[string]bool
+
+	for i, ancestor := range ancestors {
+		ancestorTree, err := ancestor.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao obter árvore do commit %s: %w", ancestor.Hash, err)
+		}
+
+		diff, err := object.DiffTree(ancestorTree, targetTree)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao comparar árvores: %w", err)
+		}
+
+		paths := make(map[string]bool)
+		for _, change := range diff {
+			paths[changePath(change)] = true
+		}
+
+		if i == 0 {
+			changes = diff
+			kept = paths
+			continue
+		}
+
+		for path := range kept {
+			if !paths[path] {
+				delete(kept, path)
+			}
+		}
+	}
+
+	var filtered object.Changes
+	for _, change := range changes {
+		if kept[changePath(change)] {
+			filtered = append(filtered, change)
+		}
+	}
+
+	return filtered, nil
+}
+
+// changePath retorna o caminho do arquivo afetado por uma alteração.
+func changePath(change *object.Change) string {
+	if change.To.Name != "" {
+		return change.To.Name
+	}
+	return change.From.Name
+}
+
+// mergeBases retorna todos os melhores ancestrais comuns de dois commits,
+// como `git merge-base --all`, do mais recente para o mais antigo.
+func mergeBases(a, b *object.Commit) ([]*object.Commit, error) {
+	bases, err := a.MergeBase(b)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao calcular o ancestral comum: %w", err)
+	}
+
+	sort.SliceStable(bases, func(i, j int) bool {
+		return bases[i].Committer.When.After(bases[j].Committer.When)
+	})
+
+	return bases, nil
+}
+
+// MergeBase retorna os hashes dos ancestrais comuns entre duas branches.
+// Em históricos cruzados (criss-cross) pode haver mais de um ancestral; todos
+// são retornados, do mais recente para o mais antigo. Sem histórico comum,
+// retorna um slice vazio.
+func (e *Control) MergeBase(a, b string) ([]string, error) {
+	if e.repository == nil {
+		return nil, fmt.Errorf("repositório não inicializado")
+	}
+
+	commitA, err := e.resolveCommit(a)
+	if err != nil {
+		return nil, err
+	}
+	commitB, err := e.resolveCommit(b)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := mergeBases(commitA, commitB)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(ancestors))
+	for _, ancestor := range ancestors {
+		hashes = append(hashes, ancestor.Hash.String())
+	}
+
+	return hashes, nil
+}
+
+// ancestorContent retorna o conteúdo de um arquivo no ancestral comum.
+// Com mais de um ancestral, monta um ancestral virtual fazendo o merge dos
+// ancestrais entre si, como a estratégia recursive do git. Conflitos nesse
+// merge intermediário permanecem no conteúdo com seus marcadores.
+func ancestorContent(ancestors []*object.Commit, fileName string) (string, error) {
+	if len(ancestors) == 0 {
+		return "", nil
+	}
+
+	content, err := fileContentAt(ancestors[0], fileName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, other := range ancestors[1:] {
+		otherContent, err := fileContentAt(other, fileName)
+		if err != nil {
+			return "", err
+		}
+
+		// O ancestral dos ancestrais serve de base para o merge virtual
+		deeper, err := mergeBases(ancestors[0], other)
+		if err != nil {
+			return "", err
+		}
+
+		deeperContent, err := ancestorContent(deeper, fileName)
+		if err != nil {
+			return "", err
+		}
+
+		content, _ = mergeContents(deeperContent, content, otherContent, ConflictMerge, ConflictLabels{
+			Ours:   "Temporary merge branch 1",
+			Base:   "merged common ancestors",
+			Theirs: "Temporary merge branch 2",
+		})
+	}
+
+	return content, nil
+}
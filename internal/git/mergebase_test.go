@@ -0,0 +1,175 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// crissCross monta um histórico cruzado: as branches a e b fazem merge uma da
+// outra e depois continuam, de forma que têm dois ancestrais comuns, a1 e b1.
+func crissCross(t *testing.T) (repo *testRepo, a1, b1 plumbing.Hash) {
+	repo = newTestRepo(t)
+	repo.commit("a", map[string]string{"f.txt": "a\nb\nc\nd\ne\n"})
+	repo.setBranch("b", repo.tip("a"))
+
+	a1 = repo.commit("a", map[string]string{"f.txt": "A\nb\nc\nd\ne\n"})
+	b1 = repo.commit("b", map[string]string{"f.txt": "a\nb\nc\nd\nE\n"})
+	repo.commit("a", map[string]string{"f.txt": "A\nb\nc\nd\nE\n"}, a1, b1)
+	repo.commit("b", map[string]string{"f.txt": "A\nb\nc\nd\nE\n"}, b1, a1)
+
+	repo.commit("a", map[string]string{"f.txt": "A\nb\nc\nd\nE2\n"})
+	repo.commit("b", map[string]string{"f.txt": "A\nB\nc\nd\nE\n"})
+	return repo, a1, b1
+}
+
+func TestMergeBase(t *testing.T) {
+	repo := newTestRepo(t)
+	fork := repo.commit("main", map[string]string{"a.txt": "a\n"})
+	repo.setBranch("feature", fork)
+	repo.commit("feature", map[string]string{"a.txt": "feature\n"})
+	repo.setBranch("ahead", repo.tip("feature"))
+	repo.commit("ahead", map[string]string{"a.txt": "ahead\n"})
+	repo.commit("main", map[string]string{"a.txt": "main\n"})
+	repo.commit("other", map[string]string{"b.txt": "b\n"})
+
+	cross, a1, b1 := crissCross(t)
+
+	tests := []struct {
+		name string
+		repo *testRepo
+		a, b string
+		want []plumbing.Hash
+	}{
+		{"branches divergentes", repo, "feature", "main", []plumbing.Hash{fork}},
+		{"branch à frente", repo, "ahead", "feature", []plumbing.Hash{repo.tip("feature")}},
+		{"sem histórico comum", repo, "other", "main", nil},
+		{"histórico cruzado", cross, "a", "b", []plumbing.Hash{b1, a1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.repo.control.MergeBase(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("MergeBase: %v", err)
+			}
+
+			want := []string{}
+			for _, hash := range tt.want {
+				want = append(want, hash.String())
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("MergeBase = %v, esperado %v", got, want)
+			}
+		})
+	}
+}
+
+func TestVirtualAncestor(t *testing.T) {
+	repo, _, _ := crissCross(t)
+
+	// Com só um dos ancestrais como base, E2 e E (ou b e B) conflitam; o
+	// ancestral virtual já tem A e E e o merge fica limpo
+	diff, err := repo.control.DiffSpecificFile("a", "b", "f.txt", CompareMergeBase, RenameOptions{}, ConflictMerge, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("DiffSpecificFile: %v", err)
+	}
+	if got, want := diff["f.txt"], "A\nB\nc\nd\nE2\n"; got != want {
+		t.Fatalf("DiffSpecificFile =\n%s\nesperado\n%s", got, want)
+	}
+}
+
+func TestAncestorContent(t *testing.T) {
+	cross, _, _ := crissCross(t)
+
+	// Os dois ancestrais alteram a mesma linha de formas diferentes
+	conflicting := newTestRepo(t)
+	conflicting.commit("a", map[string]string{"f.txt": "x\ny\n"})
+	conflicting.setBranch("b", conflicting.tip("a"))
+	a2 := conflicting.commit("a", map[string]string{"f.txt": "A\ny\n", "novo.txt": "n\n"})
+	b2 := conflicting.commit("b", map[string]string{"f.txt": "B\ny\n"})
+	conflicting.commit("a", map[string]string{"f.txt": "AB\ny\n"}, a2, b2)
+	conflicting.commit("b", map[string]string{"f.txt": "AB\ny\n"}, b2, a2)
+
+	tests := []struct {
+		name string
+		repo *testRepo
+		file string
+		want string
+	}{
+		{"ancestral virtual limpo", cross, "f.txt", "A\nb\nc\nd\nE\n"},
+		{
+			// b2 é o ancestral mais recente e fica no lugar de ours
+			"ancestral virtual com conflito", conflicting, "f.txt",
+			"<<<<<<< Temporary merge branch 1\nB\n=======\nA\n>>>>>>> Temporary merge branch 2\ny\n",
+		},
+		{"arquivo em um só ancestral", conflicting, "novo.txt", "n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashes, err := tt.repo.control.MergeBase("a", "b")
+			if err != nil || len(hashes) != 2 {
+				t.Fatalf("MergeBase = %v, %v; esperado dois ancestrais", hashes, err)
+			}
+
+			var ancestors []*object.Commit
+			for _, hash := range hashes {
+				commit, err := tt.repo.repo.CommitObject(plumbing.NewHash(hash))
+				if err != nil {
+					t.Fatal(err)
+				}
+				ancestors = append(ancestors, commit)
+			}
+
+			got, err := ancestorContent(ancestors, tt.file)
+			if err != nil {
+				t.Fatalf("ancestorContent: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("ancestorContent =\n%s\nesperado\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareMode(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"a.txt": "feature\n", "b.txt": "b\n"})
+	repo.commit("main", map[string]string{"a.txt": "a\n", "b.txt": "main\n", "c.txt": "c\n"})
+
+	tests := []struct {
+		name string
+		mode string
+		want []FileChange
+	}{
+		{"pontas", "tips", []FileChange{{Path: "a.txt", Action: "modified"}, {Path: "b.txt", Action: "modified"}, {Path: "c.txt", Action: "deleted"}}},
+		{"ancestral comum", "mergebase", []FileChange{{Path: "a.txt", Action: "modified"}}},
+		{"padrão", "", []FileChange{{Path: "a.txt", Action: "modified"}, {Path: "b.txt", Action: "modified"}, {Path: "c.txt", Action: "deleted"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := ParseCompareMode(tt.mode)
+			if err != nil {
+				t.Fatalf("ParseCompareMode: %v", err)
+			}
+
+			got, err := repo.control.GetFileChanges("feature", "main", mode, RenameOptions{})
+			if err != nil {
+				t.Fatalf("GetFileChanges: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GetFileChanges = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseCompareMode("outro"); err == nil {
+		t.Fatalf("ParseCompareMode aceitou um modo desconhecido")
+	}
+}
@@ -0,0 +1,193 @@
+package git
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Nomes dos modos de arquivo usados na API
+const (
+	ModeRegular    = "regular"
+	ModeExecutable = "executable"
+	ModeSymlink    = "symlink"
+	ModeSubmodule  = "submodule"
+)
+
+// Resoluções do modo de um arquivo alterado pelos dois lados. Valem só para
+// o modo; o bloco da decisão é ignorado.
+const (
+	ResolveModeOurs   = "mode-ours"
+	ResolveModeTheirs = "mode-theirs"
+)
+
+// ModeConflict descreve os modos de um arquivo (regular, executável ou link
+// simbólico) quando são diferentes nos dois lados. O modo do resultado é o
+// escolhido em Resolution, independente do merge do conteúdo.
+type ModeConflict struct {
+	Ours string `json:"ours"`
+
+	// Base fica vazio quando não existe ancestral comum ou o arquivo não
+	// existia nele.
+	Base string `json:"base,omitempty"`
+
+	Theirs     string `json:"theirs"`
+	Resolution string `json:"resolution,omitempty"`
+}
+
+// modeName retorna o nome de um modo de arquivo.
+func modeName(mode filemode.FileMode) string {
+	switch mode {
+	case filemode.Regular, filemode.Deprecated:
+		return ModeRegular
+	case filemode.Executable:
+		return ModeExecutable
+	case filemode.Symlink:
+		return ModeSymlink
+	case filemode.Submodule:
+		return ModeSubmodule
+	}
+	return mode.String()
+}
+
+// parseModeName converte o nome de um modo de arquivo.
+func parseModeName(name string) (filemode.FileMode, error) {
+	switch name {
+	case ModeRegular:
+		return filemode.Regular, nil
+	case ModeExecutable:
+		return filemode.Executable, nil
+	case ModeSymlink:
+		return filemode.Symlink, nil
+	}
+	return filemode.Empty, fmt.Errorf("modo de arquivo desconhecido: %s", name)
+}
+
+// mergeModes faz o merge de três vias do modo de um arquivo: vale o modo do
+// lado que o alterou. Retorna false se os dois lados o alteraram de formas
+// diferentes ou, sem base, se os modos forem diferentes.
+func mergeModes(base, ours, theirs filemode.FileMode, hasBase bool) (filemode.FileMode, bool) {
+	switch {
+	case sameMode(ours, theirs):
+		return ours, true
+	case hasBase && sameMode(ours, base):
+		return theirs, true
+	case hasBase && sameMode(theirs, base):
+		return ours, true
+	}
+	return ours, false
+}
+
+// modeConflict compara os modos do arquivo nos dois lados e no primeiro
+// ancestral comum. Retorna nil se os dois lados tiverem o mesmo modo.
+func (cmp *comparison) modeConflict(fileName, oldPath string) (*ModeConflict, error) {
+	ours, err := cmp.targetTree.FindEntry(fileName)
+	if err != nil {
+		return nil, nil
+	}
+
+	theirs, err := cmp.baseTree.FindEntry(fileName)
+	if err != nil && oldPath != fileName {
+		theirs, err = cmp.baseTree.FindEntry(oldPath)
+	}
+	if err != nil || sameMode(ours.Mode, theirs.Mode) {
+		return nil, nil
+	}
+
+	conflict := &ModeConflict{Ours: modeName(ours.Mode), Theirs: modeName(theirs.Mode)}
+
+	if len(cmp.ancestors) > 0 {
+		tree, err := cmp.ancestors[0].Tree()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao obter árvore do commit %s: %w", cmp.ancestors[0].Hash, err)
+		}
+		if base, err := tree.FindEntry(oldPath); err == nil {
+			conflict.Base = modeName(base.Mode)
+		}
+	}
+
+	return conflict, nil
+}
+
+// setMode preenche Mode quando o arquivo tem modos diferentes nos dois lados.
+func (cmp *comparison) setMode(merge *FileMerge, oldPath string) error {
+	conflict, err := cmp.modeConflict(merge.Path, oldPath)
+	if err != nil {
+		return err
+	}
+	merge.Mode = conflict
+	merge.countPending()
+	return nil
+}
+
+// sameMode informa se dois modos são iguais; o modo regular antigo (100664)
+// é igual ao regular.
+func sameMode(a, b filemode.FileMode) bool {
+	return modeName(a) == modeName(b)
+}
+
+// pending informa se ainda falta escolher o modo.
+func (c *ModeConflict) pending() bool {
+	return c.Resolution == ""
+}
+
+// chosen retorna o nome do modo escolhido, vazio enquanto estiver pendente.
+func (c *ModeConflict) chosen() string {
+	switch c.Resolution {
+	case ResolveModeOurs, ResolveAutoOurs:
+		return c.Ours
+	case ResolveModeTheirs, ResolveAutoTheirs:
+		return c.Theirs
+	}
+	return ""
+}
+
+// apply escolhe o modo de um dos lados.
+func (c *ModeConflict) apply(resolution string) error {
+	if resolution != ResolveModeOurs && resolution != ResolveModeTheirs {
+		return fmt.Errorf("o modo aceita apenas as resoluções mode-ours e mode-theirs: %s", resolution)
+	}
+	c.Resolution = resolution
+	return nil
+}
+
+// autoResolve escolhe o modo do lado que o alterou. Retorna se resolveu.
+func (c *ModeConflict) autoResolve() bool {
+	if !c.pending() || c.Base == "" {
+		return false
+	}
+
+	switch c.Base {
+	case c.Ours:
+		c.Resolution = ResolveAutoTheirs
+	case c.Theirs:
+		c.Resolution = ResolveAutoOurs
+	default:
+		return false
+	}
+	return true
+}
+
+// FileMode retorna o nome do modo escolhido para um arquivo com modos
+// diferentes nos dois lados, já resolvido.
+func (e *Control) FileMode(merge *FileMerge) (string, error) {
+	if merge.Mode == nil {
+		return "", fmt.Errorf("o arquivo %s tem o mesmo modo nos dois lados", merge.Path)
+	}
+
+	mode := merge.Mode.chosen()
+	if mode == "" {
+		return "", fmt.Errorf("o modo do arquivo %s ainda não foi resolvido", merge.Path)
+	}
+	return mode, nil
+}
+
+// entryModeName retorna o nome do modo de uma entrada de alteração, vazio se
+// a entrada não existir.
+func entryModeName(entry object.ChangeEntry) string {
+	if entry.Name == "" {
+		return ""
+	}
+	return modeName(entry.TreeEntry.Mode)
+}
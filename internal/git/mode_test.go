@@ -0,0 +1,288 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+func TestMergeModes(t *testing.T) {
+	regular, exec, link := filemode.Regular, filemode.Executable, filemode.Symlink
+
+	tests := []struct {
+		name               string
+		base, ours, theirs filemode.FileMode
+		hasBase            bool
+		want               filemode.FileMode
+		ok                 bool
+	}{
+		{name: "nenhum lado alterou", base: regular, ours: regular, theirs: regular, hasBase: true, want: regular, ok: true},
+		{name: "só ours alterou", base: regular, ours: exec, theirs: regular, hasBase: true, want: exec, ok: true},
+		{name: "só theirs alterou", base: regular, ours: regular, theirs: link, hasBase: true, want: link, ok: true},
+		{name: "mesma alteração", base: regular, ours: exec, theirs: exec, hasBase: true, want: exec, ok: true},
+		{name: "alterações diferentes", base: regular, ours: exec, theirs: link, hasBase: true, want: exec},
+		{name: "regular antigo", base: filemode.Deprecated, ours: regular, theirs: exec, hasBase: true, want: exec, ok: true},
+		{name: "sem base e iguais", ours: exec, theirs: exec, want: exec, ok: true},
+		{name: "sem base e diferentes", ours: regular, theirs: exec, want: regular},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mergeModes(tt.base, tt.ours, tt.theirs, tt.hasBase)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("mergeModes = %v, %v; esperado %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseModeName(t *testing.T) {
+	for _, mode := range []filemode.FileMode{filemode.Regular, filemode.Executable, filemode.Symlink} {
+		if got, err := parseModeName(modeName(mode)); err != nil || got != mode {
+			t.Fatalf("parseModeName(%s) = %v, %v", modeName(mode), got, err)
+		}
+	}
+	for _, name := range []string{ModeSubmodule, "", "100644"} {
+		if _, err := parseModeName(name); err == nil {
+			t.Fatalf("parseModeName(%q) sem erro", name)
+		}
+	}
+}
+
+func TestModeConflictAutoResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		conflict ModeConflict
+		want     string
+		chosen   string
+	}{
+		{name: "só ours alterou", conflict: ModeConflict{Ours: ModeExecutable, Base: ModeRegular, Theirs: ModeRegular}, want: ResolveAutoOurs, chosen: ModeExecutable},
+		{name: "só theirs alterou", conflict: ModeConflict{Ours: ModeRegular, Base: ModeRegular, Theirs: ModeSymlink}, want: ResolveAutoTheirs, chosen: ModeSymlink},
+		{name: "os dois alteraram", conflict: ModeConflict{Ours: ModeExecutable, Base: ModeRegular, Theirs: ModeSymlink}},
+		{name: "sem base", conflict: ModeConflict{Ours: ModeExecutable, Theirs: ModeRegular}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflict := tt.conflict
+			if resolved := conflict.autoResolve(); resolved != (tt.want != "") {
+				t.Fatalf("autoResolve = %v, esperado %v", resolved, tt.want != "")
+			}
+			if conflict.Resolution != tt.want || conflict.chosen() != tt.chosen {
+				t.Fatalf("Resolution = %q, chosen = %q; esperado %q e %q", conflict.Resolution, conflict.chosen(), tt.want, tt.chosen)
+			}
+		})
+	}
+}
+
+func TestModeApply(t *testing.T) {
+	control := new(Control)
+
+	merge := &FileMerge{Path: "a.sh", Mode: &ModeConflict{Ours: ModeExecutable, Base: ModeRegular, Theirs: ModeSymlink}}
+	merge.countPending()
+	if merge.Conflicts != 1 {
+		t.Fatalf("conflitos = %d, esperado 1", merge.Conflicts)
+	}
+	if _, err := control.FileMode(merge); err == nil {
+		t.Fatalf("FileMode de modo pendente sem erro")
+	}
+
+	// O bloco é ignorado nas resoluções do modo
+	for _, tt := range []struct{ resolution, want string }{
+		{ResolveModeTheirs, ModeSymlink},
+		{ResolveModeOurs, ModeExecutable},
+	} {
+		if err := merge.Apply(HunkDecision{Hunk: "inexistente", Resolution: tt.resolution}); err != nil {
+			t.Fatalf("Apply(%s): %v", tt.resolution, err)
+		}
+		if mode, err := control.FileMode(merge); err != nil || mode != tt.want || merge.Conflicts != 0 {
+			t.Fatalf("FileMode = %q, %v, conflitos = %d; esperado %q", mode, err, merge.Conflicts, tt.want)
+		}
+	}
+
+	same := &FileMerge{Path: "b.txt"}
+	if err := same.Apply(HunkDecision{Resolution: ResolveModeOurs}); err == nil {
+		t.Fatalf("Apply de modo em arquivo com o mesmo modo sem erro")
+	}
+	if _, err := control.FileMode(same); err == nil {
+		t.Fatalf("FileMode de arquivo com o mesmo modo sem erro")
+	}
+	if err := (&ModeConflict{}).apply(ResolveOurs); err == nil {
+		t.Fatalf("apply(ours) no modo sem erro")
+	}
+}
+
+// modeRepo monta duas branches a partir de main: feature torna a.sh e b.sh
+// executáveis e altera c.txt; main altera o conteúdo de a.sh e troca b.sh
+// por um link simbólico.
+func modeRepo(t *testing.T) *testRepo {
+	repo := newDiskTestRepo(t)
+	repo.commit("main", map[string]string{"a.sh": "1\n2\n3\n", "b.sh": "b\n", "c.txt": "c\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"a.sh": executable("1\n2\n3\n"), "b.sh": executable("b\n"), "c.txt": "feature\n"})
+	repo.commit("main", map[string]string{"a.sh": "1\n2\nmain\n", "b.sh": symlink("alvo"), "c.txt": "c\n"})
+	return repo
+}
+
+func TestMergeFileMode(t *testing.T) {
+	repo := modeRepo(t)
+
+	merge, err := repo.control.MergeFile("feature", "main", "a.sh", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	want := ModeConflict{Ours: ModeExecutable, Base: ModeRegular, Theirs: ModeRegular, Resolution: ResolveAutoOurs}
+	if merge.Mode == nil || *merge.Mode != want || merge.Conflicts != 0 {
+		t.Fatalf("Mode = %+v, conflitos = %d; esperado %+v", merge.Mode, merge.Conflicts, want)
+	}
+	if got := merge.Render(ConflictMerge); got != "1\n2\nmain\n" {
+		t.Fatalf("Render = %q", got)
+	}
+
+	merge, err = repo.control.MergeFile("feature", "main", "b.sh", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	want = ModeConflict{Ours: ModeExecutable, Base: ModeRegular, Theirs: ModeSymlink}
+	if merge.Mode == nil || *merge.Mode != want || merge.Conflicts != 1 {
+		t.Fatalf("Mode = %+v, conflitos = %d; esperado %+v", merge.Mode, merge.Conflicts, want)
+	}
+
+	merge, err = repo.control.MergeFile("feature", "main", "c.txt", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	if merge.Mode != nil {
+		t.Fatalf("Mode = %+v em arquivo com o mesmo modo", merge.Mode)
+	}
+}
+
+func TestModeChanges(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.sh": "a\n", "b.sh": "b\n", "c.txt": "c\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{
+		"a.sh":   executable("a\n"),
+		"b.sh":   executable("novo\n"),
+		"c.txt":  "c\n",
+		"link":   symlink("c.txt"),
+		"run.sh": executable("echo\n"),
+	})
+
+	changes, err := repo.control.GetFileChanges("feature", "main", CompareMergeBase, RenameOptions{})
+	if err != nil {
+		t.Fatalf("GetFileChanges: %v", err)
+	}
+	want := []FileChange{
+		{Path: "a.sh", Action: "mode-changed", OldMode: ModeRegular, NewMode: ModeExecutable},
+		{Path: "b.sh", Action: "modified", OldMode: ModeRegular, NewMode: ModeExecutable},
+		{Path: "link", Action: "added"},
+		{Path: "run.sh", Action: "added"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("GetFileChanges = %+v, esperado %+v", changes, want)
+	}
+
+	dest := filepath.Join(t.TempDir(), "saida")
+	if _, err := repo.control.DownloadModifiedFiles("feature", "main", dest, CompareMergeBase, RenameOptions{}); err != nil {
+		t.Fatalf("DownloadModifiedFiles: %v", err)
+	}
+
+	// Executáveis saem com permissão de execução
+	for _, name := range []string{"a.sh", "b.sh", "run.sh"} {
+		info, err := os.Lstat(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.Mode().IsRegular() || info.Mode().Perm()&0100 == 0 {
+			t.Fatalf("%s exportado com modo %v", name, info.Mode())
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(dest, "link")); err != nil || target != "c.txt" {
+		t.Fatalf("link aponta para %q, %v; esperado c.txt", target, err)
+	}
+}
+
+func TestCommitMergeModes(t *testing.T) {
+	author := Signature{Name: "Teste", Email: "teste@example.com"}
+
+	tests := []struct {
+		name       string
+		files      map[string]string
+		modes      map[string]string
+		want       map[string]filemode.FileMode
+		unresolved bool
+		fails      bool
+	}{
+		{name: "modos alterados pelos dois lados", unresolved: true},
+		{name: "conteúdo resolvido sem o modo", files: map[string]string{"b.sh": "resolvido\n"}, unresolved: true},
+		{name: "modo desconhecido", modes: map[string]string{"b.sh": "fifo"}, fails: true},
+		{
+			name:  "modo escolhido",
+			modes: map[string]string{"b.sh": ModeSymlink},
+			want:  map[string]filemode.FileMode{"a.sh": filemode.Executable, "b.sh": filemode.Symlink, "c.txt": filemode.Regular},
+		},
+		{
+			name:  "conteúdo e modo resolvidos",
+			files: map[string]string{"b.sh": "resolvido\n"},
+			modes: map[string]string{"b.sh": ModeExecutable},
+			want:  map[string]filemode.FileMode{"a.sh": filemode.Executable, "b.sh": filemode.Executable, "c.txt": filemode.Regular},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := modeRepo(t)
+
+			hash, err := repo.control.CommitMerge(MergeCommitOptions{
+				Target:  "feature",
+				Parents: []string{"feature", "main"},
+				Files:   tt.files,
+				Modes:   tt.modes,
+				Message: "merge de main",
+				Author:  author,
+			})
+
+			var unresolved *UnresolvedError
+			switch {
+			case tt.unresolved:
+				if !errors.As(err, &unresolved) || !reflect.DeepEqual(unresolved.Files, []string{"b.sh"}) {
+					t.Fatalf("CommitMerge = %v, esperado UnresolvedError em b.sh", err)
+				}
+				return
+			case tt.fails:
+				if err == nil || errors.As(err, &unresolved) {
+					t.Fatalf("CommitMerge = %v, esperado erro do modo", err)
+				}
+				return
+			case err != nil:
+				t.Fatalf("CommitMerge: %v", err)
+			}
+
+			commit, err := repo.repo.CommitObject(plumbing.NewHash(hash))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tree, err := commit.Tree()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, mode := range tt.want {
+				entry, err := tree.FindEntry(name)
+				if err != nil || entry.Mode != mode {
+					t.Fatalf("%s com modo %v, %v; esperado %v", name, entry.Mode, err, mode)
+				}
+			}
+
+			// O conteúdo de a.sh vem de main e o modo de feature
+			if got := repo.files(commit.Hash)["a.sh"]; got != "1\n2\nmain\n" {
+				t.Fatalf("a.sh = %q", got)
+			}
+		})
+	}
+}
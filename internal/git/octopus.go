@@ -0,0 +1,200 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// BranchOverlap descreve os arquivos alterados por duas branches ao mesmo tempo.
+// Arquivos que as duas branches deixaram com conteúdo idêntico não entram na lista,
+// pois não geram conflito.
+type BranchOverlap struct {
+	BranchA string   `json:"branchA"`
+	BranchB string   `json:"branchB"`
+	Files   []string `json:"files"`
+}
+
+// PlanStep é um passo da ordem de merge sugerida.
+// Overlaps são os arquivos da branch que já foram alterados pelas branches
+// mescladas nos passos anteriores, ou seja, os prováveis pontos de conflito.
+type PlanStep struct {
+	Branch   string   `json:"branch"`
+	Overlaps []string `json:"overlaps"`
+}
+
+// MergePlan é o resultado do planejamento de um merge de várias branches (octopus).
+type MergePlan struct {
+	Base     string   `json:"base"`
+	Branches []string `json:"branches"`
+
+	// Files é a união ordenada de todos os arquivos alterados pelas branches.
+	Files []string `json:"files"`
+
+	// Matrix[i][j] indica se Branches[i] alterou Files[j].
+	Matrix [][]bool `json:"matrix"`
+
+	// Overlaps lista, para cada par de branches, os arquivos em comum.
+	// Pares sem arquivos em comum são omitidos.
+	Overlaps []BranchOverlap `json:"overlaps"`
+
+	// Steps é a ordem de merge sugerida, com os conflitos esperados em cada passo.
+	Steps []PlanStep `json:"steps"`
+}
+
+// PlanOctopusMerge calcula quais arquivos cada branch altera em relação ao
+// ancestral comum com a base, quais pares de branches se sobrepõem e em quais
+// arquivos, e sugere uma ordem de merge que minimiza os conflitos. renames
+// define a detecção de renomeações e cópias.
+func (e *Control) PlanOctopusMerge(baseBranch string, branches []string, renames RenameOptions) (*MergePlan, error) {
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("nenhuma branch informada para o plano de merge")
+	}
+
+	seen := make(map[string]bool)
+	for _, branch := range branches {
+		if seen[branch] {
+			return nil, fmt.Errorf("branch %s informada mais de uma vez", branch)
+		}
+		seen[branch] = true
+	}
+
+	// touched[branch][arquivo] guarda o hash do arquivo resultante na branch
+	touched := make(map[string]map[string]plumbing.Hash)
+	fileSet := make(map[string]bool)
+
+	for _, branch := range branches {
+		cmp, err := e.compareBranches(branch, baseBranch, CompareMergeBase, renames)
+		if err != nil {
+			return nil, err
+		}
+
+		files := make(map[string]plumbing.Hash)
+		for _, change := range cmp.changes {
+			path := changePath(change)
+			files[path] = change.To.TreeEntry.Hash
+			fileSet[path] = true
+
+			// Numa renomeação o caminho antigo também deixou de existir e
+			// conta como removido
+			if info, ok := cmp.renames[change]; ok && !info.copy {
+				files[change.From.Name] = plumbing.ZeroHash
+				fileSet[change.From.Name] = true
+			}
+		}
+		touched[branch] = files
+	}
+
+	plan := &MergePlan{
+		Base:     baseBranch,
+		Branches: append([]string(nil), branches...),
+	}
+
+	for file := range fileSet {
+		plan.Files = append(plan.Files, file)
+	}
+	sort.Strings(plan.Files)
+
+	for _, branch := range branches {
+		row := make([]bool, len(plan.Files))
+		for j, file := range plan.Files {
+			_, row[j] = touched[branch][file]
+		}
+		plan.Matrix = append(plan.Matrix, row)
+	}
+
+	// Sobreposições par a par
+	pairOverlap := make(map[[2]string][]string)
+	for i := 0; i < len(branches); i++ {
+		for j := i + 1; j < len(branches); j++ {
+			a, b := branches[i], branches[j]
+			files := overlappingFiles(touched[a], touched[b])
+			if len(files) == 0 {
+				continue
+			}
+			pairOverlap[[2]string{a, b}] = files
+			pairOverlap[[2]string{b, a}] = files
+			plan.Overlaps = append(plan.Overlaps, BranchOverlap{BranchA: a, BranchB: b, Files: files})
+		}
+	}
+
+	plan.Steps = planOrder(branches, touched, pairOverlap)
+
+	return plan, nil
+}
+
+// overlappingFiles retorna, em ordem, os arquivos alterados pelas duas branches
+// com resultados diferentes.
+func overlappingFiles(a, b map[string]plumbing.Hash) []string {
+	var files []string
+	for file, hashA := range a {
+		hashB, ok := b[file]
+		if !ok || hashA == hashB {
+			continue
+		}
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// planOrder monta a ordem de merge de forma gulosa: a cada passo escolhe a
+// branch que menos se sobrepõe às já mescladas. Em caso de empate, prefere a
+// branch com menos sobreposições no total e, por fim, a ordem informada.
+func planOrder(branches []string, touched map[string]map[string]plumbing.Hash, pairOverlap map[[2]string][]string) []PlanStep {
+	totalOverlap := make(map[string]int)
+	for pair, files := range pairOverlap {
+		totalOverlap[pair[0]] += len(files)
+	}
+
+	remaining := append([]string(nil), branches...)
+	merged := make(map[string]plumbing.Hash)
+	conflicted := make(map[string]bool)
+	var steps []PlanStep
+
+	for len(remaining) > 0 {
+		best := -1
+		var bestFiles []string
+
+		for i, branch := range remaining {
+			files := stepOverlaps(touched[branch], merged, conflicted)
+			if best == -1 ||
+				len(files) < len(bestFiles) ||
+				(len(files) == len(bestFiles) && totalOverlap[branch] < totalOverlap[remaining[best]]) {
+				best = i
+				bestFiles = files
+			}
+		}
+
+		branch := remaining[best]
+		steps = append(steps, PlanStep{Branch: branch, Overlaps: bestFiles})
+
+		for file, hash := range touched[branch] {
+			if previous, ok := merged[file]; ok && previous != hash {
+				conflicted[file] = true
+			}
+			merged[file] = hash
+		}
+
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+
+	return steps
+}
+
+// stepOverlaps retorna os arquivos de uma branch que conflitam com o que já foi
+// mesclado. Arquivos que já tiveram conflito têm resultado desconhecido, então
+// qualquer nova alteração neles é contada.
+func stepOverlaps(files, merged map[string]plumbing.Hash, conflicted map[string]bool) []string {
+	var overlaps []string
+	for file, hash := range files {
+		previous, ok := merged[file]
+		if !ok || (previous == hash && !conflicted[file]) {
+			continue
+		}
+		overlaps = append(overlaps, file)
+	}
+	sort.Strings(overlaps)
+	return overlaps
+}
@@ -0,0 +1,122 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanOctopusMerge(t *testing.T) {
+	repo := newTestRepo(t)
+	base := map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"}
+	repo.commit("main", base)
+
+	branch := func(name string, changes map[string]string) {
+		files := make(map[string]string)
+		for path, content := range base {
+			files[path] = content
+		}
+		for path, content := range changes {
+			files[path] = content
+		}
+		repo.setBranch(name, repo.tip("main"))
+		repo.commit(name, files)
+	}
+	branch("x", map[string]string{"a.txt": "x\n", "b.txt": "x\n"})
+	branch("y", map[string]string{"a.txt": "y\n"})
+	branch("z", map[string]string{"c.txt": "z\n"})
+	branch("w", map[string]string{"a.txt": "x\n"}) // a mesma alteração de x
+
+	plan, err := repo.control.PlanOctopusMerge("main", []string{"x", "y", "z", "w"}, RenameOptions{})
+	if err != nil {
+		t.Fatalf("PlanOctopusMerge: %v", err)
+	}
+
+	if want := []string{"a.txt", "b.txt", "c.txt"}; !reflect.DeepEqual(plan.Files, want) {
+		t.Errorf("Files = %v, esperado %v", plan.Files, want)
+	}
+
+	wantMatrix := [][]bool{
+		{true, true, false},
+		{true, false, false},
+		{false, false, true},
+		{true, false, false},
+	}
+	if !reflect.DeepEqual(plan.Matrix, wantMatrix) {
+		t.Errorf("Matrix = %v, esperado %v", plan.Matrix, wantMatrix)
+	}
+
+	// x e w deixaram a.txt igual e não se sobrepõem
+	wantOverlaps := []BranchOverlap{
+		{BranchA: "x", BranchB: "y", Files: []string{"a.txt"}},
+		{BranchA: "y", BranchB: "w", Files: []string{"a.txt"}},
+	}
+	if !reflect.DeepEqual(plan.Overlaps, wantOverlaps) {
+		t.Errorf("Overlaps = %v, esperado %v", plan.Overlaps, wantOverlaps)
+	}
+
+	wantSteps := []PlanStep{
+		{Branch: "z"},
+		{Branch: "x"},
+		{Branch: "w"},
+		{Branch: "y", Overlaps: []string{"a.txt"}},
+	}
+	if !reflect.DeepEqual(plan.Steps, wantSteps) {
+		t.Errorf("Steps = %v, esperado %v", plan.Steps, wantSteps)
+	}
+}
+
+func TestPlanOctopusMergeRenames(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": "um\ndois\ntrês\n", "b.txt": "b\n", "c.txt": "c\n"})
+
+	branch := func(name string, files map[string]string) {
+		repo.setBranch(name, repo.tip("main"))
+		repo.commit(name, files)
+	}
+	branch("renamed", map[string]string{"novo.txt": "um\ndois\ntrês\n", "b.txt": "b\n", "c.txt": "c\n"})
+	branch("edited", map[string]string{"a.txt": "um\ndois\nquatro\n", "b.txt": "x\n", "c.txt": "c\n"})
+	branch("deleted", map[string]string{"a.txt": "um\ndois\ntrês\n", "c.txt": "c\n"})
+	branch("copied", map[string]string{"a.txt": "um\ndois\ntrês\n", "b.txt": "b\n", "c.txt": "c\n", "d.txt": "c\n"})
+
+	plan, err := repo.control.PlanOctopusMerge("main", []string{"renamed", "edited", "deleted", "copied"}, RenameOptions{})
+	if err != nil {
+		t.Fatalf("PlanOctopusMerge: %v", err)
+	}
+
+	// O caminho antigo da renomeação e o arquivo removido contam como
+	// alterados; a origem de uma cópia não
+	if want := []string{"a.txt", "b.txt", "d.txt", "novo.txt"}; !reflect.DeepEqual(plan.Files, want) {
+		t.Errorf("Files = %v, esperado %v", plan.Files, want)
+	}
+
+	wantOverlaps := []BranchOverlap{
+		{BranchA: "renamed", BranchB: "edited", Files: []string{"a.txt"}},
+		{BranchA: "edited", BranchB: "deleted", Files: []string{"b.txt"}},
+	}
+	if !reflect.DeepEqual(plan.Overlaps, wantOverlaps) {
+		t.Errorf("Overlaps = %v, esperado %v", plan.Overlaps, wantOverlaps)
+	}
+}
+
+func TestPlanOctopusMergeErrors(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": "a\n"})
+	repo.setBranch("x", repo.tip("main"))
+
+	tests := []struct {
+		name     string
+		branches []string
+	}{
+		{"sem branches", nil},
+		{"branch repetida", []string{"x", "x"}},
+		{"branch inexistente", []string{"x", "y"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.control.PlanOctopusMerge("main", tt.branches, RenameOptions{}); err == nil {
+				t.Fatalf("PlanOctopusMerge(%v) não retornou erro", tt.branches)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package git
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// DefaultRenameThreshold é a similaridade mínima, em porcentagem, para que um
+// arquivo removido e um adicionado sejam considerados uma renomeação.
+// É o mesmo padrão do git.
+const DefaultRenameThreshold = 50
+
+// renameLimit limita a quantidade de pares comparados na detecção inexata,
+// evitando que comparações com milhares de arquivos fiquem lentas demais.
+const renameLimit = 100000
+
+// RenameOptions configura a detecção de arquivos renomeados e copiados.
+type RenameOptions struct {
+	// Disabled desliga a detecção; arquivos movidos aparecem como um
+	// "deleted" e um "added".
+	Disabled bool
+
+	// Threshold é a similaridade mínima em porcentagem (1 a 100).
+	// Zero usa DefaultRenameThreshold.
+	Threshold int
+
+	// Copies também detecta arquivos novos copiados de arquivos existentes.
+	Copies bool
+}
+
+// ParseRenameOptions converte os nomes usados na API, separados por vírgula,
+// em RenameOptions. Aceita "on", "off", "copies" e o limiar de similaridade
+// em porcentagem, como "60"; vazio resulta em "on" com DefaultRenameThreshold.
+func ParseRenameOptions(names string) (RenameOptions, error) {
+	var opts RenameOptions
+	if names == "" {
+		return opts, nil
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "on":
+		case "off":
+			opts.Disabled = true
+		case "copies":
+			opts.Copies = true
+		default:
+			threshold, err := strconv.Atoi(name)
+			if err != nil || threshold < 1 || threshold > 100 {
+				return RenameOptions{}, fmt.Errorf("opção de renomeação desconhecida: %s", name)
+			}
+			opts.Threshold = threshold
+		}
+	}
+
+	return opts, nil
+}
+
+// renameInfo descreve uma alteração que é uma renomeação ou cópia.
+type renameInfo struct {
+	similarity int
+	copy       bool
+}
+
+// detectRenames junta pares de arquivos removidos e adicionados com conteúdo
+// parecido em uma única alteração de renomeação (From e To com nomes
+// diferentes). Com cópias habilitadas, arquivos adicionados parecidos com um
+// arquivo de fromTree viram cópias. opts define o limiar e se a detecção e as
+// cópias estão ligadas. Retorna as alterações reescritas e as
+// informações de similaridade de cada renomeação ou cópia. sims guarda as
+// linhas dos blobs já lidos e pode ser compartilhado entre as detecções de
+// uma mesma comparação.
+func (e *Control) detectRenames(changes object.Changes, fromTree *object.Tree, opts RenameOptions, sims *similarityCache) (object.Changes, map[*object.Change]renameInfo, error) {
+	renames := make(map[*object.Change]renameInfo)
+	if opts.Disabled {
+		return changes, renames, nil
+	}
+
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultRenameThreshold
+	}
+
+	var deleted, added, others []*object.Change
+	for _, change := range changes {
+		switch {
+		case change.From.Name == "" && change.To.TreeEntry.Mode.IsFile():
+			added = append(added, change)
+		case change.To.Name == "" && change.From.TreeEntry.Mode.IsFile():
+			deleted = append(deleted, change)
+		default:
+			others = append(others, change)
+		}
+	}
+
+	usedDeleted := make(map[*object.Change]bool)
+	usedAdded := make(map[*object.Change]bool)
+	var result object.Changes
+
+	pair := func(from, to *object.Change, similarity int) {
+		usedDeleted[from] = true
+		usedAdded[to] = true
+		rename := &object.Change{From: from.From, To: to.To}
+		renames[rename] = renameInfo{similarity: similarity}
+		result = append(result, rename)
+	}
+
+	// Renomeações exatas: mesmo conteúdo
+	byHash := make(map[plumbing.Hash][]*object.Change)
+	for _, del := range deleted {
+		byHash[del.From.TreeEntry.Hash] = append(byHash[del.From.TreeEntry.Hash], del)
+	}
+	for _, add := range added {
+		for _, del := range byHash[add.To.TreeEntry.Hash] {
+			if !usedDeleted[del] {
+				pair(del, add, 100)
+				break
+			}
+		}
+	}
+
+	// Renomeações inexatas: pares com maior similaridade primeiro
+	type candidate struct {
+		from, to   *object.Change
+		similarity int
+	}
+	var candidates []candidate
+
+	pending := func(list []*object.Change, used map[*object.Change]bool) []*object.Change {
+		var out []*object.Change
+		for _, c := range list {
+			if !used[c] {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+	restDeleted := pending(deleted, usedDeleted)
+	restAdded := pending(added, usedAdded)
+
+	if len(restDeleted)*len(restAdded) <= renameLimit {
+		for _, del := range restDeleted {
+			for _, add := range restAdded {
+				similarity, err := sims.similarity(del.From.TreeEntry.Hash, add.To.TreeEntry.Hash)
+				if err != nil {
+					return nil, nil, err
+				}
+				if similarity >= threshold {
+					candidates = append(candidates, candidate{from: del, to: add, similarity: similarity})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+	for _, c := range candidates {
+		if !usedDeleted[c.from] && !usedAdded[c.to] {
+			pair(c.from, c.to, c.similarity)
+		}
+	}
+
+	if opts.Copies && fromTree != nil {
+		copies, err := e.detectCopies(pending(added, usedAdded), others, fromTree, threshold, sims)
+		if err != nil {
+			return nil, nil, err
+		}
+		for add, copied := range copies {
+			usedAdded[add] = true
+			renames[copied.change] = renameInfo{similarity: copied.similarity, copy: true}
+			result = append(result, copied.change)
+		}
+	}
+
+	for _, change := range others {
+		result = append(result, change)
+	}
+	for _, del := range pending(deleted, usedDeleted) {
+		result = append(result, del)
+	}
+	for _, add := range pending(added, usedAdded) {
+		result = append(result, add)
+	}
+
+	// Mantém a ordem por caminho, como no diff de árvores do go-git
+	sort.SliceStable(result, func(i, j int) bool {
+		return changePath(result[i]) < changePath(result[j])
+	})
+
+	return result, renames, nil
+}
+
+// copyMatch é uma cópia detectada e sua similaridade.
+type copyMatch struct {
+	change     *object.Change
+	similarity int
+}
+
+// detectCopies procura a origem de arquivos adicionados. Cópias exatas são
+// procuradas na árvore inteira; cópias inexatas apenas entre os arquivos
+// modificados, como `git diff --find-copies`.
+func (e *Control) detectCopies(added, modified []*object.Change, fromTree *object.Tree, threshold int, sims *similarityCache) (map[*object.Change]copyMatch, error) {
+	copies := make(map[*object.Change]copyMatch)
+	if len(added) == 0 {
+		return copies, nil
+	}
+
+	entries, err := flattenTree(fromTree)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[plumbing.Hash]string)
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := byHash[entries[name].Hash]; !ok && entries[name].Mode.IsFile() {
+			byHash[entries[name].Hash] = name
+		}
+	}
+
+	source := func(name string, similarity int, add *object.Change) {
+		entry := entries[name]
+		copies[add] = copyMatch{
+			change: &object.Change{
+				From: object.ChangeEntry{Name: name, Tree: fromTree, TreeEntry: entry},
+				To:   add.To,
+			},
+			similarity: similarity,
+		}
+	}
+
+	for _, add := range added {
+		if name, ok := byHash[add.To.TreeEntry.Hash]; ok {
+			source(name, 100, add)
+			continue
+		}
+
+		if len(modified) > renameLimit {
+			continue
+		}
+
+		best, bestName := 0, ""
+		for _, mod := range modified {
+			if mod.From.Name == "" || !mod.From.TreeEntry.Mode.IsFile() {
+				continue
+			}
+			similarity, err := sims.similarity(mod.From.TreeEntry.Hash, add.To.TreeEntry.Hash)
+			if err != nil {
+				return nil, err
+			}
+			if similarity >= threshold && similarity > best {
+				best, bestName = similarity, mod.From.Name
+			}
+		}
+		if bestName != "" {
+			source(bestName, best, add)
+		}
+	}
+
+	return copies, nil
+}
+
+// lineSignature resume as linhas de um blob: quantas vezes cada linha, pelo
+// hash do texto, aparece e o total de linhas.
+type lineSignature struct {
+	counts map[uint64]int
+	total  int
+}
+
+// newLineSignature calcula a assinatura das linhas.
+func newLineSignature(lines []string) lineSignature {
+	sig := lineSignature{counts: make(map[uint64]int, len(lines)), total: len(lines)}
+	for _, line := range lines {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(line))
+		sig.counts[h.Sum64()]++
+	}
+	return sig
+}
+
+// similarityCache guarda a assinatura das linhas de cada blob lido durante
+// uma comparação, para que cada blob seja lido e dividido uma só vez mesmo
+// comparado com muitos candidatos.
+type similarityCache struct {
+	control    *Control
+	signatures map[plumbing.Hash]lineSignature
+}
+
+// newSimilarityCache cria um cache vazio para uma comparação.
+func (e *Control) newSimilarityCache() *similarityCache {
+	return &similarityCache{control: e, signatures: make(map[plumbing.Hash]lineSignature)}
+}
+
+// signature retorna a assinatura do blob, lendo-o na primeira vez.
+func (c *similarityCache) signature(hash plumbing.Hash) (lineSignature, error) {
+	if sig, ok := c.signatures[hash]; ok {
+		return sig, nil
+	}
+
+	content, err := c.control.blobContent(hash)
+	if err != nil {
+		return lineSignature{}, err
+	}
+
+	sig := newLineSignature(splitLines(content))
+	c.signatures[hash] = sig
+	return sig, nil
+}
+
+// similarity calcula a similaridade, em porcentagem, entre dois blobs pela
+// quantidade de linhas em comum, sem considerar a ordem.
+func (c *similarityCache) similarity(a, b plumbing.Hash) (int, error) {
+	if a == b {
+		return 100, nil
+	}
+
+	sigA, err := c.signature(a)
+	if err != nil {
+		return 0, err
+	}
+
+	sigB, err := c.signature(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return signatureSimilarity(sigA, sigB), nil
+}
+
+// signatureSimilarity retorna 2 * linhas em comum / total de linhas, em
+// porcentagem.
+func signatureSimilarity(a, b lineSignature) int {
+	if a.total+b.total == 0 {
+		return 100
+	}
+
+	// Percorre a menor das duas
+	if len(a.counts) > len(b.counts) {
+		a, b = b, a
+	}
+
+	common := 0
+	for line, n := range a.counts {
+		common += min(n, b.counts[line])
+	}
+
+	return 200 * common / (a.total + b.total)
+}
@@ -0,0 +1,254 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// numbered gera n linhas diferentes com o prefixo informado.
+func numbered(prefix string, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString(prefix)
+		b.WriteString(strings.Repeat("x", i))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestSignatureSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"iguais", "a\nb", "a\nb", 100},
+		{"vazios", "", "", 100},
+		{"ordem diferente", "a\nb", "b\na", 100},
+		{"metade", "a\nb", "a\nc", 50},
+		{"nada em comum", "a\nb", "c\nd", 0},
+		{"linhas repetidas", "a\na\na", "a", 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := newLineSignature(splitLines(tt.a)), newLineSignature(splitLines(tt.b))
+			if got := signatureSimilarity(a, b); got != tt.want {
+				t.Fatalf("signatureSimilarity = %d, esperado %d", got, tt.want)
+			}
+			if got := signatureSimilarity(b, a); got != tt.want {
+				t.Fatalf("signatureSimilarity invertida = %d, esperado %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimilarityCache(t *testing.T) {
+	repo := newTestRepo(t)
+	tree, err := repo.repo.TreeObject(repo.tree(map[string]string{"a.txt": "a\nb", "b.txt": "a\nc"}, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := tree.FindEntry("a.txt")
+	b, _ := tree.FindEntry("b.txt")
+
+	sims := repo.control.newSimilarityCache()
+	if got, err := sims.similarity(a.Hash, b.Hash); err != nil || got != 50 {
+		t.Fatalf("similarity = %d, %v; esperado 50", got, err)
+	}
+	if len(sims.signatures) != 2 {
+		t.Fatalf("assinaturas guardadas = %d, esperado 2", len(sims.signatures))
+	}
+
+	// Uma assinatura guardada é usada sem ler o blob de novo
+	sims.signatures[b.Hash] = newLineSignature([]string{"a", "b"})
+	if got, _ := sims.similarity(a.Hash, b.Hash); got != 100 {
+		t.Fatalf("similarity com a assinatura guardada = %d, esperado 100", got)
+	}
+
+	if _, err := sims.similarity(a.Hash, plumbing.NewHash("1111111111111111111111111111111111111111")); err == nil {
+		t.Fatalf("similarity com blob inexistente sem erro")
+	}
+}
+
+func TestRenameDetection(t *testing.T) {
+	original := numbered("linha", 10)
+	edited := strings.Replace(original, "linhaxx\n", "alterada\n", 1)
+	different := numbered("outra", 10)
+
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": original, "b.txt": different, "c.txt": original + "c\n"})
+	repo.setBranch("exact", repo.tip("main"))
+	repo.commit("exact", map[string]string{"novo.txt": original, "b.txt": different, "c.txt": original + "c\n"})
+	repo.setBranch("edited", repo.tip("main"))
+	repo.commit("edited", map[string]string{"novo.txt": edited, "b.txt": different, "c.txt": original + "c\n"})
+	repo.setBranch("replaced", repo.tip("main"))
+	repo.commit("replaced", map[string]string{"novo.txt": numbered("nova", 10), "b.txt": different, "c.txt": original + "c\n"})
+	repo.setBranch("copied", repo.tip("main"))
+	repo.commit("copied", map[string]string{
+		"a.txt": original, "b.txt": different, "b2.txt": different,
+		"c.txt": original + "c2\n", "c2.txt": original + "c\n",
+	})
+
+	tests := []struct {
+		name   string
+		branch string
+		opts   RenameOptions
+		want   []FileChange
+	}{
+		{
+			name: "renomeação exata", branch: "exact",
+			want: []FileChange{{Path: "novo.txt", Action: "renamed", OldPath: "a.txt", Similarity: 100}},
+		},
+		{
+			name: "renomeação com alteração", branch: "edited",
+			want: []FileChange{{Path: "novo.txt", Action: "renamed", OldPath: "a.txt", Similarity: 90}},
+		},
+		{
+			name: "abaixo do limite", branch: "edited", opts: RenameOptions{Threshold: 95},
+			want: []FileChange{{Path: "a.txt", Action: "deleted"}, {Path: "novo.txt", Action: "added"}},
+		},
+		{
+			name: "detecção desligada", branch: "exact", opts: RenameOptions{Disabled: true},
+			want: []FileChange{{Path: "a.txt", Action: "deleted"}, {Path: "novo.txt", Action: "added"}},
+		},
+		{
+			name: "conteúdo diferente", branch: "replaced",
+			want: []FileChange{{Path: "a.txt", Action: "deleted"}, {Path: "novo.txt", Action: "added"}},
+		},
+		{
+			name: "cópias desligadas", branch: "copied",
+			want: []FileChange{{Path: "b2.txt", Action: "added"}, {Path: "c.txt", Action: "modified"}, {Path: "c2.txt", Action: "added"}},
+		},
+		{
+			name: "cópias", branch: "copied", opts: RenameOptions{Copies: true},
+			want: []FileChange{
+				{Path: "b2.txt", Action: "copied", OldPath: "b.txt", Similarity: 100},
+				{Path: "c.txt", Action: "modified"},
+				{Path: "c2.txt", Action: "copied", OldPath: "c.txt", Similarity: 100},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.control.GetFileChanges(tt.branch, "main", CompareMergeBase, tt.opts)
+			if err != nil {
+				t.Fatalf("GetFileChanges: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GetFileChanges = %+v, esperado %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenameOptionsPerCall(t *testing.T) {
+	original := numbered("linha", 10)
+
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": original})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"novo.txt": original, "copia.txt": original, "a.txt": original})
+
+	// Chamadas simultâneas com opções diferentes não interferem entre si
+	tests := []struct {
+		opts RenameOptions
+		want []FileChange
+	}{
+		{RenameOptions{}, []FileChange{{Path: "copia.txt", Action: "added"}, {Path: "novo.txt", Action: "added"}}},
+		{RenameOptions{Copies: true}, []FileChange{
+			{Path: "copia.txt", Action: "copied", OldPath: "a.txt", Similarity: 100},
+			{Path: "novo.txt", Action: "copied", OldPath: "a.txt", Similarity: 100},
+		}},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, tt := range tests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, err := repo.control.GetFileChanges("feature", "main", CompareMergeBase, tt.opts)
+				if err != nil {
+					t.Errorf("GetFileChanges: %v", err)
+					return
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("GetFileChanges(%+v) = %+v, esperado %+v", tt.opts, got, tt.want)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestMergeFileRenamed(t *testing.T) {
+	original := numbered("linha", 10)
+	ours := strings.Replace(original, "linhax\n", "feature\n", 1)
+	theirs := strings.Replace(original, "linhaxxxxxxxx\n", "main\n", 1)
+
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": original})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"novo.txt": ours})
+	repo.commit("main", map[string]string{"a.txt": theirs})
+
+	tests := []struct {
+		name string
+		opts RenameOptions
+		want string
+	}{
+		// O arquivo renomeado é mesclado com o caminho antigo do outro lado
+		{"com detecção", RenameOptions{}, strings.Replace(ours, "linhaxxxxxxxx\n", "main\n", 1)},
+		// Sem a detecção, é um arquivo novo só da branch
+		{"sem detecção", RenameOptions{Disabled: true}, ours},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge, err := repo.control.MergeFile("feature", "main", "novo.txt", CompareMergeBase, tt.opts, DiffOptions{}, AutoResolveOptions{})
+			if err != nil {
+				t.Fatalf("MergeFile: %v", err)
+			}
+			if got := merge.Render(ConflictMerge); got != tt.want || merge.Conflicts != 0 {
+				t.Fatalf("Render =\n%s\nesperado\n%s (conflitos: %d)", got, tt.want, merge.Conflicts)
+			}
+		})
+	}
+}
+
+func TestParseRenameOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  RenameOptions
+		fails bool
+	}{
+		{name: "", want: RenameOptions{}},
+		{name: "on", want: RenameOptions{}},
+		{name: "off", want: RenameOptions{Disabled: true}},
+		{name: "copies", want: RenameOptions{Copies: true}},
+		{name: "60", want: RenameOptions{Threshold: 60}},
+		{name: "copies, 80", want: RenameOptions{Copies: true, Threshold: 80}},
+		{name: "0", fails: true},
+		{name: "101", fails: true},
+		{name: "copias", fails: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRenameOptions(tt.name)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("ParseRenameOptions(%q) não retornou erro", tt.name)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRenameOptions(%q) = %+v, %v; esperado %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
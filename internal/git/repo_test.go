@@ -0,0 +1,268 @@
+package git
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// testRepo é um repositório de teste, com um Control aberto nele. Os commits
+// são gravados direto nos objetos, sem diretório de trabalho.
+type testRepo struct {
+	t       *testing.T
+	repo    *git.Repository
+	control *Control
+	when    time.Time
+}
+
+// newTestRepo cria um repositório em memória.
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatalf("erro ao criar repositório: %v", err)
+	}
+	return openTestRepo(t, repo)
+}
+
+// newDiskTestRepo cria um repositório bare em um diretório temporário, para
+// os recursos que gravam arquivos no .git, como o rerere.
+func newDiskTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+
+	repo, err := git.PlainInit(t.TempDir(), true)
+	if err != nil {
+		t.Fatalf("erro ao criar repositório: %v", err)
+	}
+	return openTestRepo(t, repo)
+}
+
+// openTestRepo abre o Control no repositório.
+func openTestRepo(t *testing.T, repo *git.Repository) *testRepo {
+	control := new(Control)
+	control.Init()
+	control.repository = repo
+
+	return &testRepo{
+		t:       t,
+		repo:    repo,
+		control: control,
+		when:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// commit grava um commit com os arquivos informados (o conteúdo completo da
+// árvore) e move a branch para ele. Sem parents, o pai é a ponta atual da
+// branch, se existir.
+func (r *testRepo) commit(branch string, files map[string]string, parents ...plumbing.Hash) plumbing.Hash {
+	r.t.Helper()
+
+	if len(parents) == 0 {
+		if ref, err := r.repo.Reference(plumbing.NewBranchReferenceName(branch), true); err == nil {
+			parents = []plumbing.Hash{ref.Hash()}
+		}
+	}
+
+	r.when = r.when.Add(time.Minute)
+	signature := object.Signature{Name: "Teste", Email: "teste@example.com", When: r.when}
+	commit := &object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      "commit em " + branch,
+		TreeHash:     r.tree(files, ""),
+		ParentHashes: parents,
+	}
+
+	hash := r.store(commit)
+	r.setBranch(branch, hash)
+	return hash
+}
+
+// Prefixos que, no conteúdo passado a commit, gravam o caminho com outro
+// modo (ver gitlink, executable e symlink).
+const (
+	gitlinkPrefix    = "gitlink:"
+	executablePrefix = "executable:"
+	symlinkPrefix    = "symlink:"
+)
+
+// gitlink é o conteúdo que grava o caminho como um submódulo apontando para
+// o commit.
+func gitlink(commit plumbing.Hash) string {
+	return gitlinkPrefix + commit.String()
+}
+
+// executable é o conteúdo que grava o caminho como um arquivo executável.
+func executable(content string) string {
+	return executablePrefix + content
+}
+
+// symlink é o conteúdo que grava o caminho como um link simbólico para target.
+func symlink(target string) string {
+	return symlinkPrefix + target
+}
+
+// setBranch cria ou move uma branch para o commit.
+func (r *testRepo) setBranch(branch string, hash plumbing.Hash) {
+	r.t.Helper()
+
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), hash)
+	if err := r.repo.Storer.SetReference(ref); err != nil {
+		r.t.Fatalf("erro ao gravar a branch %s: %v", branch, err)
+	}
+}
+
+// tip retorna o commit na ponta de uma branch.
+func (r *testRepo) tip(branch string) plumbing.Hash {
+	r.t.Helper()
+
+	ref, err := r.repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		r.t.Fatalf("erro ao ler a branch %s: %v", branch, err)
+	}
+	return ref.Hash()
+}
+
+// tree grava a árvore dos arquivos que ficam sob dir.
+func (r *testRepo) tree(files map[string]string, dir string) plumbing.Hash {
+	r.t.Helper()
+
+	blobs := make(map[string]string)
+	subdirs := make(map[string]bool)
+	for path, content := range files {
+		rest, ok := strings.CutPrefix(path, dir)
+		if !ok {
+			continue
+		}
+		if name, _, nested := strings.Cut(rest, "/"); nested {
+			subdirs[name] = true
+		} else {
+			blobs[name] = content
+		}
+	}
+
+	tree := &object.Tree{}
+	for name, content := range blobs {
+		if commit, ok := strings.CutPrefix(content, gitlinkPrefix); ok {
+			tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Submodule, Hash: plumbing.NewHash(commit)})
+			continue
+		}
+
+		mode := filemode.Regular
+		if rest, ok := strings.CutPrefix(content, executablePrefix); ok {
+			mode, content = filemode.Executable, rest
+		} else if rest, ok := strings.CutPrefix(content, symlinkPrefix); ok {
+			mode, content = filemode.Symlink, rest
+		}
+
+		blob := r.repo.Storer.NewEncodedObject()
+		blob.SetType(plumbing.BlobObject)
+		writer, _ := blob.Writer()
+		_, _ = writer.Write([]byte(content))
+		_ = writer.Close()
+
+		hash, err := r.repo.Storer.SetEncodedObject(blob)
+		if err != nil {
+			r.t.Fatalf("erro ao gravar o arquivo %s: %v", dir+name, err)
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: mode, Hash: hash})
+	}
+	for name := range subdirs {
+		hash := r.tree(files, dir+name+"/")
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// O git ordena as entradas comparando os diretórios com uma / no fim
+	sortKey := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return sortKey(tree.Entries[i]) < sortKey(tree.Entries[j])
+	})
+
+	return r.store(tree)
+}
+
+// store grava um objeto no repositório.
+func (r *testRepo) store(obj interface {
+	Encode(plumbing.EncodedObject) error
+}) plumbing.Hash {
+	r.t.Helper()
+
+	encoded := r.repo.Storer.NewEncodedObject()
+	if err := obj.Encode(encoded); err != nil {
+		r.t.Fatalf("erro ao codificar objeto: %v", err)
+	}
+
+	hash, err := r.repo.Storer.SetEncodedObject(encoded)
+	if err != nil {
+		r.t.Fatalf("erro ao gravar objeto: %v", err)
+	}
+	return hash
+}
+
+// files lê todos os arquivos da árvore de um commit, indexados pelo caminho.
+func (r *testRepo) files(hash plumbing.Hash) map[string]string {
+	r.t.Helper()
+
+	commit, err := r.repo.CommitObject(hash)
+	if err != nil {
+		r.t.Fatalf("erro ao ler o commit %s: %v", hash, err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		r.t.Fatalf("erro ao ler a árvore do commit %s: %v", hash, err)
+	}
+
+	files := make(map[string]string)
+	err = tree.Files().ForEach(func(file *object.File) error {
+		content, err := file.Contents()
+		files[file.Name] = content
+		return err
+	})
+	if err != nil {
+		r.t.Fatalf("erro ao ler os arquivos do commit %s: %v", hash, err)
+	}
+	return files
+}
+
+// driverMerge roda um driver de merge sobre as três versões de um arquivo e
+// monta o FileMerge com o resultado. base vazio é um arquivo sem ancestral.
+func driverMerge(t *testing.T, driver MergeDriverFunc, path, base, ours, theirs string) *FileMerge {
+	t.Helper()
+
+	chunks, err := driver(MergeInput{Path: path, Base: base, Ours: ours, Theirs: theirs, HasBase: base != ""})
+	if err != nil {
+		t.Fatalf("erro no merge de %s: %v", path, err)
+	}
+	return newFileMerge(path, ConflictLabels{Ours: "ours", Base: "base", Theirs: "theirs"}, chunks)
+}
+
+// resolveAll decide todos os blocos pendentes de uma cópia do merge com a
+// mesma resolução e retorna o conteúdo final.
+func resolveAll(t *testing.T, merge *FileMerge, resolution string) string {
+	t.Helper()
+
+	resolved := merge.Clone()
+	for _, chunk := range merge.Chunks {
+		if !chunk.Pending() {
+			continue
+		}
+		if err := resolved.Apply(HunkDecision{Hunk: chunk.ID, Resolution: resolution}); err != nil {
+			t.Fatalf("erro ao resolver o bloco %s: %v", chunk.ID, err)
+		}
+	}
+	return resolved.Render(ConflictMerge)
+}
@@ -0,0 +1,334 @@
+package git
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ResolveRerere marca blocos resolvidos com uma resolução gravada antes
+// para o mesmo conflito.
+const ResolveRerere = "rerere"
+
+// rerereDir é o diretório, dentro do .git, onde as resoluções são gravadas,
+// um arquivo JSON por conflito.
+const rerereDir = "gitmerge/rr-cache"
+
+// RecordedResolution é a resolução gravada para um conflito, identificado
+// pela impressão digital do conteúdo dos dois lados.
+type RecordedResolution struct {
+	Fingerprint string    `json:"fingerprint"`
+	File        string    `json:"file"`
+	Ours        []string  `json:"ours"`
+	Theirs      []string  `json:"theirs"`
+	Resolution  []string  `json:"resolution"`
+	Recorded    time.Time `json:"recorded"`
+}
+
+// Fingerprint calcula a impressão digital de um bloco em conflito, como o
+// `git rerere`: os dois lados normalizados (sem espaços no fim das linhas e
+// sem \r) em ordem, de forma que o mesmo conflito com os lados trocados tem
+// a mesma impressão digital.
+func (c *Chunk) Fingerprint() string {
+	ours := joinLines(normalizeTrailing(sideLines(c.Ours)))
+	theirs := joinLines(normalizeTrailing(sideLines(c.Theirs)))
+	if ours > theirs {
+		ours, theirs = theirs, ours
+	}
+
+	hash := sha1.Sum([]byte(ours + "\x00" + theirs))
+	return hex.EncodeToString(hash[:])
+}
+
+// normalizeTrailing remove espaços e \r do fim das linhas.
+func normalizeTrailing(lines []string) []string {
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = strings.TrimRight(line, " \t\r")
+	}
+	return normalized
+}
+
+// replayResolutions resolve os blocos pendentes que têm uma resolução
+// gravada e retorna quantos resolveu; Replayed e Conflicts são atualizados.
+func (e *Control) replayResolutions(merge *FileMerge) (int, error) {
+	replayed := 0
+	for i := range merge.Chunks {
+		chunk := &merge.Chunks[i]
+		if !chunk.Pending() {
+			continue
+		}
+
+		recorded, err := e.recordedResolution(chunk.Fingerprint())
+		if err != nil {
+			return replayed, err
+		}
+		if recorded == nil {
+			continue
+		}
+
+		chunk.Resolution = ResolveRerere
+		chunk.Lines = append([]string(nil), recorded.Resolution...)
+		replayed++
+	}
+
+	merge.Replayed += replayed
+	merge.countPending()
+
+	return replayed, nil
+}
+
+// RecordResolutions grava as resoluções dos blocos em conflito de merge a
+// partir do conteúdo final salvo para o arquivo. merge deve ser o modelo em que
+// o arquivo foi resolvido: os blocos são localizados pela posição, entre os
+// trechos estáveis vizinhos, e os já resolvidos nele delimitam os demais.
+// Blocos resolvidos automaticamente não são gravados, nem os que não é
+// possível localizar porque os trechos estáveis foram editados. Retorna
+// quantas resoluções gravou.
+func (e *Control) RecordResolutions(merge *FileMerge, content string) (int, error) {
+	resolutions := merge.locateResolutions(splitLines(content))
+
+	indexes := make([]int, 0, len(resolutions))
+	for i := range resolutions {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	recorded := 0
+	for _, i := range indexes {
+		chunk, lines := &merge.Chunks[i], resolutions[i]
+		if strings.HasPrefix(chunk.Resolution, "auto-") {
+			continue
+		}
+
+		// Um resultado que ainda tem marcadores não é uma resolução
+		if hasConflictMarkers(joinLines(lines)) {
+			continue
+		}
+
+		err := e.saveRecordedResolution(RecordedResolution{
+			Fingerprint: chunk.Fingerprint(),
+			File:        merge.Path,
+			Ours:        sideLines(chunk.Ours),
+			Theirs:      sideLines(chunk.Theirs),
+			Resolution:  lines,
+			Recorded:    time.Now(),
+		})
+		if err != nil {
+			return recorded, err
+		}
+		recorded++
+	}
+
+	return recorded, nil
+}
+
+// locateResolutions encontra no conteúdo final as linhas que substituíram cada
+// bloco em conflito, usando os trechos estáveis como âncoras. Retorna um map
+// do índice do bloco em Chunks para as linhas, vazio se as âncoras não forem
+// encontradas em ordem.
+func (m *FileMerge) locateResolutions(lines []string) map[int][]string {
+	resolutions := make(map[int][]string)
+	var group []int
+	pos := 0
+
+	for i, chunk := range m.Chunks {
+		if chunk.Conflict {
+			group = append(group, i)
+			continue
+		}
+
+		start := indexLines(lines, chunk.Lines, pos)
+		if start < 0 || (len(group) == 0 && start != pos) {
+			return map[int][]string{}
+		}
+
+		m.splitResolutions(group, lines[pos:start], resolutions)
+		group = group[:0]
+		pos = start + len(chunk.Lines)
+	}
+
+	if len(group) == 0 && pos != len(lines) {
+		return map[int][]string{}
+	}
+	m.splitResolutions(group, lines[pos:], resolutions)
+
+	return resolutions
+}
+
+// splitResolutions reparte entre os blocos em conflito vizinhos de group as
+// linhas que ficaram entre duas âncoras. Os blocos já resolvidos no merge
+// têm as linhas conhecidas e são retirados das pontas; o trecho restante só
+// é atribuído se sobrar um único bloco.
+func (m *FileMerge) splitResolutions(group []int, lines []string, resolutions map[int][]string) {
+	first, last := 0, len(group)
+
+	for first < last {
+		chunk := &m.Chunks[group[first]]
+		if chunk.Pending() || !hasPrefixLines(lines, chunk.Lines) {
+			break
+		}
+		resolutions[group[first]] = lines[:len(chunk.Lines)]
+		lines = lines[len(chunk.Lines):]
+		first++
+	}
+
+	for first < last {
+		chunk := &m.Chunks[group[last-1]]
+		if chunk.Pending() || !hasSuffixLines(lines, chunk.Lines) {
+			break
+		}
+		resolutions[group[last-1]] = lines[len(lines)-len(chunk.Lines):]
+		lines = lines[:len(lines)-len(chunk.Lines)]
+		last--
+	}
+
+	if last-first == 1 {
+		resolutions[group[first]] = lines
+	}
+}
+
+// hasPrefixLines informa se lines começa com prefix.
+func hasPrefixLines(lines, prefix []string) bool {
+	return len(prefix) <= len(lines) && equalLines(lines[:len(prefix)], prefix)
+}
+
+// hasSuffixLines informa se lines termina com suffix.
+func hasSuffixLines(lines, suffix []string) bool {
+	return len(suffix) <= len(lines) && equalLines(lines[len(lines)-len(suffix):], suffix)
+}
+
+// indexLines retorna a posição da primeira ocorrência de sub em lines a
+// partir de from, ou -1.
+func indexLines(lines, sub []string, from int) int {
+	for i := from; i+len(sub) <= len(lines); i++ {
+		if equalLines(lines[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// RecordedResolutions retorna as resoluções gravadas no repositório, da mais
+// antiga para a mais recente. Com fileName preenchido, retorna apenas as
+// gravadas para esse arquivo.
+func (e *Control) RecordedResolutions(fileName string) ([]RecordedResolution, error) {
+	dir, err := e.gitPath(rerereDir)
+	if errors.Is(err, errNotOnDisk) {
+		return []RecordedResolution{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []RecordedResolution{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar resoluções gravadas: %w", err)
+	}
+
+	list := []RecordedResolution{}
+	for _, entry := range entries {
+		fingerprint, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+
+		recorded, err := e.recordedResolution(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		if recorded != nil && (fileName == "" || recorded.File == fileName) {
+			list = append(list, *recorded)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Recorded.Before(list[j].Recorded)
+	})
+
+	return list, nil
+}
+
+// ForgetResolution apaga a resolução gravada de um conflito.
+func (e *Control) ForgetResolution(fingerprint string) error {
+	if !isHexPrefix(fingerprint) || len(fingerprint) != 40 {
+		return fmt.Errorf("impressão digital inválida: %s", fingerprint)
+	}
+
+	path, err := e.gitPath(rerereDir + "/" + fingerprint + ".json")
+	if errors.Is(err, errNotOnDisk) {
+		return fmt.Errorf("resolução %s não encontrada", fingerprint)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("resolução %s não encontrada", fingerprint)
+		}
+		return fmt.Errorf("erro ao apagar resolução %s: %w", fingerprint, err)
+	}
+
+	return nil
+}
+
+// recordedResolution lê a resolução gravada de um conflito, ou nil se não
+// houver. Um repositório fora do disco não tem resoluções gravadas.
+func (e *Control) recordedResolution(fingerprint string) (*RecordedResolution, error) {
+	path, err := e.gitPath(rerereDir + "/" + fingerprint + ".json")
+	if errors.Is(err, errNotOnDisk) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler resolução %s: %w", fingerprint, err)
+	}
+
+	recorded := new(RecordedResolution)
+	if err := json.Unmarshal(data, recorded); err != nil {
+		return nil, fmt.Errorf("erro ao ler resolução %s: %w", fingerprint, err)
+	}
+
+	return recorded, nil
+}
+
+// saveRecordedResolution grava a resolução de um conflito, substituindo a anterior.
+func (e *Control) saveRecordedResolution(recorded RecordedResolution) error {
+	path, err := e.gitPath(rerereDir + "/" + recorded.Fingerprint + ".json")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %w", filepath.Dir(path), err)
+	}
+
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao gravar resolução %s: %w", recorded.Fingerprint, err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar resolução %s: %w", recorded.Fingerprint, err)
+	}
+
+	return nil
+}
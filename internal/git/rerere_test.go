@@ -0,0 +1,241 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stableChunk e conflictChunk montam os trechos de um FileMerge de teste.
+func stableChunk(lines ...string) Chunk {
+	return Chunk{Lines: lines}
+}
+
+func conflictChunk(ours, theirs []string) Chunk {
+	return Chunk{
+		Conflict: true,
+		Ours:     &HunkSide{Lines: ours},
+		Theirs:   &HunkSide{Lines: theirs},
+	}
+}
+
+// resolvedChunk é um bloco em conflito já resolvido com as linhas informadas.
+func resolvedChunk(ours, theirs []string, lines ...string) Chunk {
+	chunk := conflictChunk(ours, theirs)
+	chunk.Resolution = ResolveOurs
+	chunk.Lines = lines
+	return chunk
+}
+
+func TestFingerprint(t *testing.T) {
+	base := conflictChunk([]string{"a", "b"}, []string{"c"})
+
+	tests := []struct {
+		name  string
+		chunk Chunk
+		same  bool
+	}{
+		{"mesmo conteúdo", conflictChunk([]string{"a", "b"}, []string{"c"}), true},
+		{"lados trocados", conflictChunk([]string{"c"}, []string{"a", "b"}), true},
+		{"espaços e \\r no fim", conflictChunk([]string{"a  ", "b\r"}, []string{"c\t"}), true},
+		{"conteúdo diferente", conflictChunk([]string{"a", "b"}, []string{"d"}), false},
+		{"linhas unidas", conflictChunk([]string{"a b"}, []string{"c"}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := tt.chunk.Fingerprint() == base.Fingerprint(); same != tt.same {
+				t.Fatalf("impressões iguais = %v, esperado %v", same, tt.same)
+			}
+		})
+	}
+}
+
+func TestLocateResolutions(t *testing.T) {
+	ours, theirs := []string{"o"}, []string{"t"}
+
+	tests := []struct {
+		name    string
+		chunks  []Chunk
+		content string
+		want    map[int][]string
+	}{
+		{
+			name:    "entre trechos estáveis",
+			chunks:  []Chunk{stableChunk("a"), conflictChunk(ours, theirs), stableChunk("c")},
+			content: "a\nx\ny\nc",
+			want:    map[int][]string{1: {"x", "y"}},
+		},
+		{
+			name:    "no início e no fim",
+			chunks:  []Chunk{conflictChunk(ours, theirs), stableChunk("b"), conflictChunk(theirs, ours)},
+			content: "x\nb\ny",
+			want:    map[int][]string{0: {"x"}, 2: {"y"}},
+		},
+		{
+			name:    "resolução que remove o bloco",
+			chunks:  []Chunk{stableChunk("a"), conflictChunk(ours, theirs), stableChunk("c")},
+			content: "a\nc",
+			want:    map[int][]string{1: {}},
+		},
+		{
+			name: "vizinho já resolvido",
+			chunks: []Chunk{
+				stableChunk("a"),
+				resolvedChunk(ours, theirs, "o"),
+				conflictChunk([]string{"p"}, []string{"q"}),
+				stableChunk("c"),
+			},
+			content: "a\no\nx\nc",
+			want:    map[int][]string{1: {"o"}, 2: {"x"}},
+		},
+		{
+			name: "vizinhos pendentes",
+			chunks: []Chunk{
+				stableChunk("a"),
+				conflictChunk(ours, theirs),
+				conflictChunk([]string{"p"}, []string{"q"}),
+				stableChunk("c"),
+			},
+			content: "a\nx\ny\nc",
+			want:    map[int][]string{},
+		},
+		{
+			name:    "trecho estável editado",
+			chunks:  []Chunk{stableChunk("a"), conflictChunk(ours, theirs), stableChunk("c")},
+			content: "a\nx\nC",
+			want:    map[int][]string{},
+		},
+		{
+			name:    "linhas a mais antes do primeiro trecho",
+			chunks:  []Chunk{stableChunk("a"), conflictChunk(ours, theirs)},
+			content: "z\na\nx",
+			want:    map[int][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := &FileMerge{Chunks: tt.chunks}
+			got := merge.locateResolutions(splitLines(tt.content))
+			if len(got) != len(tt.want) {
+				t.Fatalf("locateResolutions = %q, esperado %q", got, tt.want)
+			}
+			for i, lines := range tt.want {
+				if !equalLines(got[i], lines) {
+					t.Fatalf("locateResolutions = %q, esperado %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordResolutions(t *testing.T) {
+	repo := newDiskTestRepo(t)
+	repo.commit("main", map[string]string{
+		"a.txt": "um\ndois\ntrês\n",
+		"b.txt": "um\ndois\ntrês\n",
+	})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{
+		"a.txt": "um\nfeature\ntrês\n",
+		"b.txt": "um\nfeature\ntrês\n",
+	})
+	repo.commit("main", map[string]string{
+		"a.txt": "um\nmain\ntrês\n",
+		"b.txt": "um\nmain  \ntrês\n",
+	})
+
+	mergeFile := func(name string) *FileMerge {
+		t.Helper()
+		merge, err := repo.control.MergeFile("feature", "main", name, CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+		if err != nil {
+			t.Fatalf("MergeFile(%s): %v", name, err)
+		}
+		return merge
+	}
+
+	merge := mergeFile("a.txt")
+	if merge.Conflicts != 1 {
+		t.Fatalf("conflitos = %d, esperado 1", merge.Conflicts)
+	}
+
+	// Um conteúdo que ainda tem marcadores não é gravado
+	recorded, err := repo.control.RecordResolutions(merge, merge.Render(ConflictMerge))
+	if err != nil || recorded != 0 {
+		t.Fatalf("RecordResolutions com marcadores = %d, %v; esperado 0", recorded, err)
+	}
+
+	recorded, err = repo.control.RecordResolutions(merge, "um\nfeature e main\ntrês\n")
+	if err != nil || recorded != 1 {
+		t.Fatalf("RecordResolutions = %d, %v; esperado 1", recorded, err)
+	}
+
+	list, err := repo.control.RecordedResolutions("a.txt")
+	if err != nil {
+		t.Fatalf("RecordedResolutions: %v", err)
+	}
+	if len(list) != 1 || !reflect.DeepEqual(list[0].Resolution, []string{"feature e main"}) {
+		t.Fatalf("RecordedResolutions = %+v", list)
+	}
+	if other, _ := repo.control.RecordedResolutions("outro.txt"); len(other) != 0 {
+		t.Fatalf("RecordedResolutions de outro arquivo = %+v", other)
+	}
+
+	// O mesmo conflito em outro arquivo, com espaços no fim, é resolvido
+	replayed := mergeFile("b.txt")
+	if replayed.Conflicts != 0 || replayed.Replayed != 1 {
+		t.Fatalf("conflitos = %d, reaplicados = %d; esperado 0 e 1", replayed.Conflicts, replayed.Replayed)
+	}
+	if got := replayed.Render(ConflictMerge); got != "um\nfeature e main\ntrês\n" {
+		t.Fatalf("Render = %q", got)
+	}
+
+	if err := repo.control.ForgetResolution(list[0].Fingerprint); err != nil {
+		t.Fatalf("ForgetResolution: %v", err)
+	}
+	if err := repo.control.ForgetResolution(list[0].Fingerprint); err == nil {
+		t.Fatalf("ForgetResolution de resolução apagada não retornou erro")
+	}
+	if err := repo.control.ForgetResolution("../a"); err == nil || !strings.Contains(err.Error(), "inválida") {
+		t.Fatalf("ForgetResolution com impressão inválida = %v", err)
+	}
+
+	if merge := mergeFile("b.txt"); merge.Conflicts != 1 {
+		t.Fatalf("conflitos depois de apagar a resolução = %d, esperado 1", merge.Conflicts)
+	}
+}
+
+func TestRecordedResolutionsInMemory(t *testing.T) {
+	repo := mergeRepo(t)
+
+	// Sem diretório .git, não há resoluções para reaplicar
+	merge, err := repo.control.MergeFile("feature", "main", "conflict.txt", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	if merge.Conflicts != 1 || merge.Replayed != 0 {
+		t.Fatalf("conflitos = %d, reaplicados = %d; esperado 1 e 0", merge.Conflicts, merge.Replayed)
+	}
+
+	list, err := repo.control.RecordedResolutions("")
+	if err != nil || len(list) != 0 {
+		t.Fatalf("RecordedResolutions = %+v, %v; esperado lista vazia", list, err)
+	}
+
+	var fingerprint string
+	for _, chunk := range merge.Chunks {
+		if chunk.Conflict {
+			fingerprint = chunk.Fingerprint()
+			break
+		}
+	}
+	if err := repo.control.ForgetResolution(fingerprint); err == nil || !strings.Contains(err.Error(), "não encontrada") {
+		t.Fatalf("ForgetResolution = %v, esperado resolução não encontrada", err)
+	}
+
+	// Gravar continua exigindo o disco
+	if _, err := repo.control.RecordResolutions(merge, "resolvido\n"); err == nil {
+		t.Fatalf("RecordResolutions em memória não retornou erro")
+	}
+}
@@ -0,0 +1,224 @@
+package git
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Resoluções aceitas para um bloco em conflito
+const (
+	ResolveOurs            = "ours"
+	ResolveTheirs          = "theirs"
+	ResolveBothOursFirst   = "both-ours-first"
+	ResolveBothTheirsFirst = "both-theirs-first"
+	ResolveBase            = "base"
+	ResolveCustom          = "custom"
+)
+
+// HunkDecision é a decisão tomada para um bloco em conflito.
+// Text é usado apenas com ResolveCustom e Choices apenas com ResolveInline.
+type HunkDecision struct {
+	Hunk       string         `json:"hunk"`
+	Resolution string         `json:"resolution"`
+	Text       string         `json:"text,omitempty"`
+	Choices    []InlineChoice `json:"choices,omitempty"`
+}
+
+// Apply aplica uma decisão ao bloco indicado e atualiza a contagem de
+// conflitos pendentes. Um bloco já resolvido pode ser decidido de novo.
+// Em arquivos binários o bloco é ignorado e a decisão, ours ou theirs, vale
+// para o arquivo inteiro; em submódulos, ours, theirs ou descendant escolhem
+// o commit. Em conflitos de árvore a decisão é uma das
+// TreeConflict.Choices; no add/add, só quando o bloco não é informado.
+// ResolveModeOurs e ResolveModeTheirs escolhem o modo do arquivo (Mode) e
+// também ignoram o bloco.
+func (m *FileMerge) Apply(decision HunkDecision) error {
+	if decision.Resolution == ResolveModeOurs || decision.Resolution == ResolveModeTheirs {
+		if m.Mode == nil {
+			return fmt.Errorf("arquivo %s tem o mesmo modo nos dois lados", m.Path)
+		}
+		if err := m.Mode.apply(decision.Resolution); err != nil {
+			return fmt.Errorf("arquivo %s: %w", m.Path, err)
+		}
+		m.countPending()
+		return nil
+	}
+
+	if m.Tree != nil && (m.Tree.Kind != TreeAddAdd || decision.Hunk == "") {
+		if err := m.Tree.apply(decision.Resolution); err != nil {
+			return fmt.Errorf("arquivo %s: %w", m.Path, err)
+		}
+		m.countPending()
+		return nil
+	}
+
+	if m.Binary != nil {
+		if err := m.Binary.apply(decision.Resolution); err != nil {
+			return fmt.Errorf("arquivo %s: %w", m.Path, err)
+		}
+		m.countPending()
+		return nil
+	}
+
+	if m.Submodule != nil {
+		if err := m.Submodule.apply(decision.Resolution); err != nil {
+			return fmt.Errorf("submódulo %s: %w", m.Path, err)
+		}
+		m.countPending()
+		return nil
+	}
+
+	chunk, err := m.Hunk(decision.Hunk)
+	if err != nil {
+		return err
+	}
+
+	ours, theirs := sideLines(chunk.Ours), sideLines(chunk.Theirs)
+
+	var lines []string
+	switch decision.Resolution {
+	case ResolveOurs:
+		lines = ours
+	case ResolveTheirs:
+		lines = theirs
+	case ResolveBothOursFirst:
+		lines = append(append([]string(nil), ours...), theirs...)
+	case ResolveBothTheirsFirst:
+		lines = append(append([]string(nil), theirs...), ours...)
+	case ResolveBase:
+		if chunk.Base == nil {
+			return fmt.Errorf("bloco %s não tem versão base: não existe ancestral comum", chunk.ID)
+		}
+		lines = chunk.Base.Lines
+	case ResolveCustom:
+		// Uma quebra de linha final não cria uma linha vazia extra
+		text := strings.TrimSuffix(decision.Text, "\n")
+		if decision.Text != "" {
+			lines = splitLines(text)
+		}
+	case ResolveInline:
+		lines, err = chunk.inlineLines(decision.Choices)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("resolução desconhecida para o bloco %s: %s", chunk.ID, decision.Resolution)
+	}
+
+	chunk.Resolution = decision.Resolution
+	chunk.Lines = append([]string(nil), lines...)
+
+	// Decidir um bloco volta ao merge do conteúdo no lugar da escolha do
+	// arquivo inteiro
+	if m.Tree != nil {
+		m.Tree.Resolution = ""
+	}
+	m.countPending()
+
+	return nil
+}
+
+// DecisionLogEntry é um registro do histórico de decisões de resolução.
+type DecisionLogEntry struct {
+	Time       time.Time `json:"time"`
+	Session    string    `json:"session,omitempty"`
+	YourBranch string    `json:"yourBranch"`
+	BaseBranch string    `json:"baseBranch"`
+	File       string    `json:"file"`
+	HunkDecision
+}
+
+// decisionLogFile é o arquivo, dentro do diretório .git, onde as decisões
+// de resolução são registradas, uma por linha em JSON.
+const decisionLogFile = "gitmerge/decisions.log"
+
+// LogDecisions acrescenta decisões ao histórico gravado no repositório.
+func (e *Control) LogDecisions(entries []DecisionLogEntry) error {
+	path, err := e.gitPath(decisionLogFile)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %w", filepath.Dir(path), err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir histórico de decisões: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("erro ao gravar histórico de decisões: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DecisionLog retorna o histórico de decisões gravado no repositório,
+// do mais antigo para o mais recente. Com fileName preenchido, retorna
+// apenas as decisões desse arquivo.
+func (e *Control) DecisionLog(fileName string) ([]DecisionLogEntry, error) {
+	path, err := e.gitPath(decisionLogFile)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []DecisionLogEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir histórico de decisões: %w", err)
+	}
+	defer file.Close()
+
+	entries := []DecisionLogEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry DecisionLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("erro ao ler histórico de decisões: %w", err)
+		}
+		if fileName == "" || entry.File == fileName {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler histórico de decisões: %w", err)
+	}
+
+	return entries, nil
+}
+
+// errNotOnDisk indica que o repositório aberto não tem um diretório .git,
+// como um repositório em memória.
+var errNotOnDisk = errors.New("o repositório não está gravado em disco")
+
+// gitPath retorna o caminho de um arquivo dentro do diretório .git do
+// repositório aberto.
+func (e *Control) gitPath(name string) (string, error) {
+	if e.repository == nil {
+		return "", fmt.Errorf("repositório não inicializado")
+	}
+
+	storage, ok := e.repository.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", errNotOnDisk
+	}
+
+	return filepath.Join(storage.Filesystem().Root(), filepath.FromSlash(name)), nil
+}
@@ -0,0 +1,147 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// conflictedMerge monta um FileMerge com um único bloco em conflito entre
+// trechos estáveis.
+func conflictedMerge(base *HunkSide) *FileMerge {
+	return newFileMerge("a.txt", ConflictLabels{}, []Chunk{
+		{Lines: []string{"início"}},
+		{
+			Conflict: true,
+			Ours:     &HunkSide{Lines: []string{"o1", "o2"}},
+			Base:     base,
+			Theirs:   &HunkSide{Lines: []string{"t"}},
+		},
+		{Lines: []string{"fim"}},
+	})
+}
+
+func TestApply(t *testing.T) {
+	base := &HunkSide{Lines: []string{"b"}}
+
+	tests := []struct {
+		name     string
+		decision HunkDecision
+		base     *HunkSide
+		want     string
+		fails    bool
+	}{
+		{"ours", HunkDecision{Resolution: ResolveOurs}, base, "início\no1\no2\nfim", false},
+		{"theirs", HunkDecision{Resolution: ResolveTheirs}, base, "início\nt\nfim", false},
+		{"ambos, ours primeiro", HunkDecision{Resolution: ResolveBothOursFirst}, base, "início\no1\no2\nt\nfim", false},
+		{"ambos, theirs primeiro", HunkDecision{Resolution: ResolveBothTheirsFirst}, base, "início\nt\no1\no2\nfim", false},
+		{"base", HunkDecision{Resolution: ResolveBase}, base, "início\nb\nfim", false},
+		{"base ausente", HunkDecision{Resolution: ResolveBase}, nil, "", true},
+		{"personalizada", HunkDecision{Resolution: ResolveCustom, Text: "x\ny\n"}, base, "início\nx\ny\nfim", false},
+		{"personalizada vazia", HunkDecision{Resolution: ResolveCustom}, base, "início\nfim", false},
+		{"resolução desconhecida", HunkDecision{Resolution: "meio"}, base, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := conflictedMerge(tt.base)
+			tt.decision.Hunk = merge.Chunks[1].ID
+
+			err := merge.Apply(tt.decision)
+			if tt.fails {
+				if err == nil {
+					t.Fatalf("Apply não retornou erro")
+				}
+				if merge.Conflicts != 1 || merge.Chunks[1].Resolution != "" {
+					t.Fatalf("uma decisão recusada alterou o bloco: %+v", merge.Chunks[1])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if merge.Conflicts != 0 {
+				t.Fatalf("conflitos = %d, esperado 0", merge.Conflicts)
+			}
+			if got := merge.Render(ConflictMerge); got != tt.want {
+				t.Fatalf("Render = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyAgain(t *testing.T) {
+	merge := conflictedMerge(nil)
+	id := merge.Chunks[1].ID
+
+	if err := merge.Apply(HunkDecision{Hunk: "desconhecido", Resolution: ResolveOurs}); err == nil {
+		t.Fatalf("Apply aceitou um bloco desconhecido")
+	}
+
+	for _, resolution := range []string{ResolveOurs, ResolveTheirs} {
+		if err := merge.Apply(HunkDecision{Hunk: id, Resolution: resolution}); err != nil {
+			t.Fatalf("Apply(%s): %v", resolution, err)
+		}
+	}
+
+	if got := merge.Render(ConflictMerge); got != "início\nt\nfim" {
+		t.Fatalf("a última decisão não prevaleceu: %q", got)
+	}
+
+	// A resolução não altera os lados guardados do bloco
+	if !reflect.DeepEqual(merge.Chunks[1].Ours.Lines, []string{"o1", "o2"}) {
+		t.Fatalf("lado ours alterado: %q", merge.Chunks[1].Ours.Lines)
+	}
+}
+
+func TestDecisionLog(t *testing.T) {
+	repo := newDiskTestRepo(t)
+
+	if entries, err := repo.control.DecisionLog(""); err != nil || len(entries) != 0 {
+		t.Fatalf("DecisionLog sem histórico = %+v, %v", entries, err)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(file, hunk, resolution string) DecisionLogEntry {
+		return DecisionLogEntry{
+			Time:         now,
+			Session:      "s1",
+			YourBranch:   "feature",
+			BaseBranch:   "main",
+			File:         file,
+			HunkDecision: HunkDecision{Hunk: hunk, Resolution: resolution},
+		}
+	}
+
+	first := []DecisionLogEntry{entry("a.txt", "h1", ResolveOurs), entry("b.txt", "h2", ResolveTheirs)}
+	second := []DecisionLogEntry{entry("a.txt", "h3", ResolveBase)}
+	for _, entries := range [][]DecisionLogEntry{first, second} {
+		if err := repo.control.LogDecisions(entries); err != nil {
+			t.Fatalf("LogDecisions: %v", err)
+		}
+	}
+
+	tests := []struct {
+		file string
+		want []DecisionLogEntry
+	}{
+		{"", append(append([]DecisionLogEntry(nil), first...), second...)},
+		{"a.txt", []DecisionLogEntry{first[0], second[0]}},
+		{"c.txt", []DecisionLogEntry{}},
+	}
+
+	for _, tt := range tests {
+		got, err := repo.control.DecisionLog(tt.file)
+		if err != nil {
+			t.Fatalf("DecisionLog(%q): %v", tt.file, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("DecisionLog(%q) = %+v, esperado %+v", tt.file, got, tt.want)
+		}
+	}
+
+	// O histórico só existe em repositórios gravados em disco
+	if err := newTestRepo(t).control.LogDecisions(first); err == nil {
+		t.Fatalf("LogDecisions aceitou um repositório em memória")
+	}
+}
@@ -0,0 +1,263 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// AmbiguousRevisionError indica que um nome de revisão corresponde a mais de
+// um objeto. Candidates lista as formas completas que removem a ambiguidade.
+type AmbiguousRevisionError struct {
+	Revision   string
+	Candidates []string
+}
+
+func (e *AmbiguousRevisionError) Error() string {
+	return fmt.Sprintf("revisão %s é ambígua, use um dos nomes completos: %s", e.Revision, strings.Join(e.Candidates, ", "))
+}
+
+// ResolveRevision retorna o hash do commit indicado por uma revisão.
+// Aceita branches locais (main), branches remotas (origin/release), tags
+// (v1.0), nomes completos (refs/heads/main), HEAD, hashes completos ou
+// abreviados e os sufixos ~N e ^N (HEAD~3, main^2, v1.0~1^2).
+func (e *Control) ResolveRevision(revision string) (string, error) {
+	if e.repository == nil {
+		return "", fmt.Errorf("repositório não inicializado")
+	}
+
+	commit, err := e.resolveCommit(revision)
+	if err != nil {
+		return "", err
+	}
+
+	return commit.Hash.String(), nil
+}
+
+// resolveCommit resolve uma revisão até o commit correspondente.
+func (e *Control) resolveCommit(revision string) (*object.Commit, error) {
+	revision = strings.TrimSpace(revision)
+	if revision == "" {
+		return nil, fmt.Errorf("revisão não informada")
+	}
+
+	// Nomes de referência não podem conter ~ nem ^, então o nome termina
+	// no primeiro desses caracteres e o resto são navegações pelos pais
+	name, suffix := revision, ""
+	if i := strings.IndexAny(revision, "~^"); i >= 0 {
+		name, suffix = revision[:i], revision[i:]
+	}
+
+	commit, err := e.resolveName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return walkSuffix(commit, revision, suffix)
+}
+
+// resolveName resolve um nome sem sufixos: HEAD, referência ou hash. As
+// referências têm prioridade sobre os hashes abreviados.
+func (e *Control) resolveName(name string) (*object.Commit, error) {
+	if name == "HEAD" {
+		head, err := e.repository.Head()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao obter HEAD: %w", err)
+		}
+		return e.peelToCommit(head.Hash(), name)
+	}
+
+	// candidates guarda nome completo -> hash do commit
+	candidates := make(map[string]plumbing.Hash)
+
+	refNames := []string{
+		name,
+		"refs/" + name,
+		"refs/heads/" + name,
+		"refs/tags/" + name,
+		"refs/remotes/" + name,
+		"refs/remotes/" + name + "/HEAD",
+	}
+	if !strings.HasPrefix(name, "refs/") {
+		refNames = refNames[1:]
+	}
+
+	// Referências que não levam a um commit, como tags de árvores ou blobs,
+	// não são candidatas; o erro só aparece se nenhuma outra servir
+	var peelErr error
+	for _, refName := range refNames {
+		ref, err := e.repository.Reference(plumbing.ReferenceName(refName), true)
+		if err != nil {
+			continue
+		}
+
+		commit, err := e.peelToCommit(ref.Hash(), name)
+		if err != nil {
+			peelErr = err
+			continue
+		}
+		candidates[refName] = commit.Hash
+	}
+
+	// Hashes abreviados só são procurados, percorrendo os commits, quando o
+	// nome não é de nenhuma referência, como uma branch chamada cafe
+	if len(candidates) == 0 && isHexPrefix(name) {
+		hashes, err := e.commitsWithPrefix(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, hash := range hashes {
+			candidates[hash.String()] = hash
+		}
+	}
+
+	if len(candidates) == 0 && peelErr != nil {
+		return nil, peelErr
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("revisão %s não encontrada: não é branch, branch remota, tag nem hash de commit", name)
+	}
+
+	// Vários nomes apontando para o mesmo commit não são um problema
+	distinct := make(map[plumbing.Hash]bool)
+	for _, hash := range candidates {
+		distinct[hash] = true
+	}
+
+	if len(distinct) > 1 {
+		list := make([]string, 0, len(candidates))
+		for candidate := range candidates {
+			list = append(list, candidate)
+		}
+		sort.Strings(list)
+		return nil, &AmbiguousRevisionError{Revision: name, Candidates: list}
+	}
+
+	for hash := range distinct {
+		return e.repository.CommitObject(hash)
+	}
+
+	return nil, fmt.Errorf("revisão %s não encontrada", name)
+}
+
+// peelToCommit segue tags anotadas até chegar ao commit.
+func (e *Control) peelToCommit(hash plumbing.Hash, name string) (*object.Commit, error) {
+	for {
+		obj, err := e.repository.Object(plumbing.AnyObject, hash)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler objeto %s da revisão %s: %w", hash, name, err)
+		}
+
+		switch o := obj.(type) {
+		case *object.Commit:
+			return o, nil
+		case *object.Tag:
+			hash = o.Target
+		default:
+			return nil, fmt.Errorf("revisão %s aponta para um %s, não para um commit", name, obj.Type())
+		}
+	}
+}
+
+// commitsWithPrefix retorna os commits cujo hash começa com o prefixo informado.
+func (e *Control) commitsWithPrefix(prefix string) ([]plumbing.Hash, error) {
+	prefix = strings.ToLower(prefix)
+
+	if len(prefix) == 40 {
+		hash := plumbing.NewHash(prefix)
+		if _, err := e.repository.CommitObject(hash); err != nil {
+			return nil, nil
+		}
+		return []plumbing.Hash{hash}, nil
+	}
+
+	iter, err := e.repository.CommitObjects()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar commits: %w", err)
+	}
+	defer iter.Close()
+
+	var hashes []plumbing.Hash
+	for {
+		commit, err := iter.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar commits: %w", err)
+		}
+
+		if strings.HasPrefix(commit.Hash.String(), prefix) {
+			hashes = append(hashes, commit.Hash)
+		}
+	}
+
+	return hashes, nil
+}
+
+// walkSuffix aplica os sufixos ~N (N-ésimo ancestral pelo primeiro pai) e
+// ^N (N-ésimo pai) a partir de um commit.
+func walkSuffix(commit *object.Commit, revision, suffix string) (*object.Commit, error) {
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+
+		// Lê o número opcional depois do operador; ausente vale 1
+		digits := 0
+		for digits < len(suffix) && suffix[digits] >= '0' && suffix[digits] <= '9' {
+			digits++
+		}
+
+		n := 1
+		if digits > 0 {
+			var err error
+			if n, err = strconv.Atoi(suffix[:digits]); err != nil {
+				return nil, fmt.Errorf("revisão %s inválida: %w", revision, err)
+			}
+		}
+		suffix = suffix[digits:]
+
+		switch op {
+		case '~':
+			for i := 0; i < n; i++ {
+				parent, err := commit.Parent(0)
+				if err != nil {
+					return nil, fmt.Errorf("revisão %s não existe: o commit %s não tem pai", revision, commit.Hash)
+				}
+				commit = parent
+			}
+		case '^':
+			if n == 0 {
+				continue
+			}
+			parent, err := commit.Parent(n - 1)
+			if err != nil {
+				return nil, fmt.Errorf("revisão %s não existe: o commit %s não tem o pai %d", revision, commit.Hash, n)
+			}
+			commit = parent
+		default:
+			return nil, fmt.Errorf("revisão %s inválida", revision)
+		}
+	}
+
+	return commit, nil
+}
+
+// isHexPrefix informa se o nome pode ser um hash abreviado (mínimo de 4 dígitos).
+func isHexPrefix(name string) bool {
+	if len(name) < 4 || len(name) > 40 {
+		return false
+	}
+	for _, c := range name {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package git
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestResolveRevision(t *testing.T) {
+	repo := newTestRepo(t)
+
+	c1 := repo.commit("main", map[string]string{"a.txt": "1\n"})
+	c2 := repo.commit("main", map[string]string{"a.txt": "2\n"})
+	c3 := repo.commit("main", map[string]string{"a.txt": "3\n"})
+	c4 := repo.commit("feature", map[string]string{"a.txt": "2\n", "b.txt": "4\n"}, c2)
+	merged := repo.commit("merged", map[string]string{"a.txt": "3\n", "b.txt": "4\n"}, c3, c4)
+
+	setRef := func(name string, hash plumbing.Hash) {
+		if err := repo.repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(name), hash)); err != nil {
+			t.Fatalf("erro ao gravar %s: %v", name, err)
+		}
+	}
+
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main"))
+	if err := repo.repo.Storer.SetReference(head); err != nil {
+		t.Fatalf("erro ao gravar HEAD: %v", err)
+	}
+
+	setRef("refs/tags/v1.0", c1)
+	setRef("refs/remotes/origin/release", c4)
+
+	annotated := repo.store(&object.Tag{
+		Name:       "v2.0",
+		Tagger:     object.Signature{Name: "Teste", Email: "teste@example.com"},
+		Message:    "versão 2",
+		TargetType: plumbing.CommitObject,
+		Target:     c2,
+	})
+	setRef("refs/tags/v2.0", annotated)
+
+	// Uma branch e uma tag com o mesmo nome em commits diferentes
+	setRef("refs/heads/dup", c1)
+	setRef("refs/tags/dup", c3)
+
+	// Uma tag que aponta para uma árvore não é candidata
+	commit, err := repo.repo.CommitObject(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setRef("refs/tags/tree", commit.TreeHash)
+	setRef("refs/tags/both", commit.TreeHash)
+	setRef("refs/heads/both", c2)
+
+	// Uma branch com o nome de um hash abreviado de outro commit
+	prefix := c1.String()[:8]
+	setRef("refs/heads/"+prefix, c3)
+
+	tests := []struct {
+		revision  string
+		want      plumbing.Hash
+		ambiguous bool
+		fails     bool
+	}{
+		{revision: "main", want: c3},
+		{revision: "refs/heads/feature", want: c4},
+		{revision: "HEAD", want: c3},
+		{revision: "HEAD~2", want: c1},
+		{revision: "main^", want: c2},
+		{revision: "merged^2", want: c4},
+		{revision: "merged^2~1", want: c2},
+		{revision: "merged^0", want: merged},
+		{revision: "v1.0", want: c1},
+		{revision: "v2.0", want: c2},
+		{revision: "v2.0~1", want: c1},
+		{revision: "origin/release", want: c4},
+		{revision: c4.String(), want: c4},
+		{revision: c4.String()[:10], want: c4},
+		{revision: prefix, want: c3},
+		{revision: "both", want: c2},
+		{revision: "dup", ambiguous: true},
+		{revision: "refs/tags/dup", want: c3},
+		{revision: "tree", fails: true},
+		{revision: "HEAD~5", fails: true},
+		{revision: "main^3", fails: true},
+		{revision: "inexistente", fails: true},
+		{revision: "", fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.revision, func(t *testing.T) {
+			got, err := repo.control.ResolveRevision(tt.revision)
+
+			var ambiguous *AmbiguousRevisionError
+			switch {
+			case tt.ambiguous:
+				if !errors.As(err, &ambiguous) {
+					t.Fatalf("ResolveRevision(%q) = %s, %v; esperado AmbiguousRevisionError", tt.revision, got, err)
+				}
+			case tt.fails:
+				if err == nil {
+					t.Fatalf("ResolveRevision(%q) = %s; esperado erro", tt.revision, got)
+				}
+			case err != nil:
+				t.Fatalf("ResolveRevision(%q): %v", tt.revision, err)
+			case got != tt.want.String():
+				t.Fatalf("ResolveRevision(%q) = %s, esperado %s", tt.revision, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ResolveDescendant escolhe, para um submódulo, o commit que descende dos
+// dois lados (SubmoduleConflict.Descendant).
+const ResolveDescendant = "descendant"
+
+// SubmoduleConflict descreve o merge de um submódulo (gitlink). O repositório
+// guarda apenas o commit do submódulo em cada lado; o resultado é um desses
+// commits, escolhido em Resolution.
+type SubmoduleConflict struct {
+	// Ours, Base e Theirs são os commits do submódulo em cada lado. Ficam
+	// vazios quando o caminho não é um submódulo no lado.
+	Ours   string `json:"ours,omitempty"`
+	Base   string `json:"base,omitempty"`
+	Theirs string `json:"theirs,omitempty"`
+
+	// Descendant é um commit que descende de Ours e de Theirs, procurado no
+	// repositório do submódulo. Fica vazio se o repositório não existir
+	// localmente ou não tiver esse commit.
+	Descendant string `json:"descendant,omitempty"`
+
+	Resolution string `json:"resolution,omitempty"`
+
+	// ancestor informa se existe ancestral comum, para distinguir um
+	// submódulo ausente na base de uma base desconhecida
+	ancestor bool
+}
+
+// SubmoduleError indica que o caminho é um submódulo e não tem diff de texto.
+// Merge traz os commits dos lados para a escolha de um deles.
+type SubmoduleError struct {
+	Merge *FileMerge
+}
+
+func (e *SubmoduleError) Error() string {
+	return fmt.Sprintf("%s é um submódulo e não tem diff de texto: escolha ours, theirs ou descendant", e.Merge.Path)
+}
+
+// gitlinkCommit retorna o commit do submódulo no caminho, vazio se o caminho
+// não existir na árvore ou não for um submódulo.
+func gitlinkCommit(tree *object.Tree, name string) string {
+	entry, err := tree.FindEntry(name)
+	if err != nil || entry.Mode != filemode.Submodule {
+		return ""
+	}
+	return entry.Hash.String()
+}
+
+// isGitlink informa se o caminho é um submódulo na árvore.
+func isGitlink(tree *object.Tree, name string) bool {
+	return gitlinkCommit(tree, name) != ""
+}
+
+// submoduleMerge monta o merge de um submódulo a partir dos commits dos dois
+// lados e do primeiro ancestral comum.
+func (e *Control) submoduleMerge(cmp *comparison, fileName, oldPath string) (*FileMerge, error) {
+	sub := &SubmoduleConflict{
+		Ours:     gitlinkCommit(cmp.targetTree, fileName),
+		Theirs:   gitlinkCommit(cmp.baseTree, fileName),
+		ancestor: len(cmp.ancestors) > 0,
+	}
+	if sub.Theirs == "" && oldPath != fileName {
+		sub.Theirs = gitlinkCommit(cmp.baseTree, oldPath)
+	}
+
+	if sub.ancestor {
+		tree, err := cmp.ancestors[0].Tree()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao obter árvore do commit %s: %w", cmp.ancestors[0].Hash, err)
+		}
+		sub.Base = gitlinkCommit(tree, oldPath)
+	}
+
+	if sub.Ours != "" && sub.Theirs != "" {
+		sub.Descendant = e.submoduleDescendant(fileName, sub.Ours, sub.Theirs)
+	}
+
+	merge := &FileMerge{Path: fileName, Labels: cmp.labels(), Submodule: sub}
+	merge.countPending()
+
+	return merge, nil
+}
+
+// submoduleRepository abre o repositório do submódulo no caminho informado:
+// o diretório do submódulo no diretório de trabalho ou, se ele não tiver sido
+// extraído, o diretório em .git/modules.
+func (e *Control) submoduleRepository(name string) (*git.Repository, bool) {
+	var dirs []string
+	if worktree, err := e.repository.Worktree(); err == nil {
+		dirs = append(dirs, filepath.Join(worktree.Filesystem.Root(), filepath.FromSlash(name)))
+	}
+	if dir, err := e.gitPath("modules/" + name); err == nil {
+		dirs = append(dirs, dir)
+	}
+
+	for _, dir := range dirs {
+		if repo, err := git.PlainOpen(dir); err == nil {
+			return repo, true
+		}
+	}
+	return nil, false
+}
+
+// submoduleDescendant procura, no repositório local do submódulo, um commit
+// que descende de ours e de theirs: um dos próprios lados, quando o outro é
+// seu ancestral (fast-forward), ou a ponta de uma branch ou tag que contém os
+// dois, a mais antiga delas. Retorna vazio se o repositório não existir
+// localmente, não tiver os commits ou não houver esse commit.
+func (e *Control) submoduleDescendant(name, ours, theirs string) string {
+	repo, ok := e.submoduleRepository(name)
+	if !ok {
+		return ""
+	}
+
+	oursCommit, err := repo.CommitObject(plumbing.NewHash(ours))
+	if err != nil {
+		return ""
+	}
+	theirsCommit, err := repo.CommitObject(plumbing.NewHash(theirs))
+	if err != nil {
+		return ""
+	}
+
+	if ok, err := oursCommit.IsAncestor(theirsCommit); err == nil && ok {
+		return theirs
+	}
+	if ok, err := theirsCommit.IsAncestor(oursCommit); err == nil && ok {
+		return ours
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return ""
+	}
+	defer refs.Close()
+
+	var found *object.Commit
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+
+		commit, err := repo.CommitObject(ref.Hash())
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			// Tags anotadas apontam para o objeto da tag
+			tag, tagErr := repo.TagObject(ref.Hash())
+			if tagErr != nil {
+				return nil
+			}
+			commit, err = tag.Commit()
+		}
+		if err != nil {
+			return nil
+		}
+
+		if found != nil && !commit.Committer.When.Before(found.Committer.When) {
+			return nil
+		}
+		if ok, err := oursCommit.IsAncestor(commit); err != nil || !ok {
+			return nil
+		}
+		if ok, err := theirsCommit.IsAncestor(commit); err != nil || !ok {
+			return nil
+		}
+
+		found = commit
+		return nil
+	})
+
+	if found == nil {
+		return ""
+	}
+	return found.Hash.String()
+}
+
+// pending informa se ainda falta escolher o commit do submódulo.
+func (s *SubmoduleConflict) pending() bool {
+	return s.Resolution == ""
+}
+
+// chosen retorna o commit escolhido, vazio enquanto o conflito estiver pendente.
+func (s *SubmoduleConflict) chosen() string {
+	switch s.Resolution {
+	case ResolveOurs, ResolveAutoOurs, ResolveAutoIdentical, ResolveAutoKeepOurs:
+		return s.Ours
+	case ResolveTheirs, ResolveAutoTheirs:
+		return s.Theirs
+	case ResolveDescendant, ResolveAutoDescendant:
+		return s.Descendant
+	}
+	return ""
+}
+
+// apply escolhe o commit de um dos lados ou o que descende dos dois.
+func (s *SubmoduleConflict) apply(resolution string) error {
+	switch resolution {
+	case ResolveOurs:
+		if s.Ours == "" {
+			return fmt.Errorf("o caminho não é um submódulo na sua branch: escolha theirs")
+		}
+	case ResolveTheirs:
+		if s.Theirs == "" {
+			return fmt.Errorf("o caminho não é um submódulo na branch base: escolha ours")
+		}
+	case ResolveDescendant:
+		if s.Descendant == "" {
+			return fmt.Errorf("nenhum commit do submódulo descende dos dois lados no repositório local")
+		}
+	default:
+		return fmt.Errorf("submódulos aceitam apenas as resoluções ours, theirs e descendant: %s", resolution)
+	}
+
+	s.Resolution = resolution
+	return nil
+}
+
+// autoResolve escolhe o commit quando os dois lados são iguais, apenas um
+// deles alterou o submódulo ou um lado descende do outro. Retorna se resolveu.
+func (s *SubmoduleConflict) autoResolve() bool {
+	if !s.pending() {
+		return false
+	}
+
+	switch {
+	case s.Ours == s.Theirs:
+		s.Resolution = ResolveAutoIdentical
+	case s.Ours != "" && s.Theirs != "" && (s.Descendant == s.Ours || s.Descendant == s.Theirs):
+		s.Resolution = ResolveAutoDescendant
+	case !s.ancestor:
+		// Sem ancestral comum não há como saber qual lado alterou o submódulo
+		return false
+	case s.Ours == s.Base && s.Theirs != "":
+		s.Resolution = ResolveAutoTheirs
+	case s.Theirs == s.Base && s.Ours != "":
+		s.Resolution = ResolveAutoOurs
+	default:
+		return false
+	}
+
+	return true
+}
+
+// SubmoduleCommit retorna o commit escolhido para um submódulo já resolvido.
+func (e *Control) SubmoduleCommit(merge *FileMerge) (string, error) {
+	if merge.Submodule == nil {
+		return "", fmt.Errorf("%s não é um submódulo", merge.Path)
+	}
+
+	commit := merge.Submodule.chosen()
+	if commit == "" {
+		return "", fmt.Errorf("o submódulo %s ainda não foi resolvido", merge.Path)
+	}
+
+	return commit, nil
+}
+
+// mergeGitlinks faz o merge de um submódulo alterado pelos dois lados no
+// commit de merge: só há resultado quando um lado descende do outro no
+// repositório local do submódulo (fast-forward).
+func (e *Control) mergeGitlinks(name string, ours, theirs object.TreeEntry) (object.TreeEntry, bool) {
+	if ours.Mode != filemode.Submodule || theirs.Mode != filemode.Submodule {
+		return ours, false
+	}
+
+	switch e.submoduleDescendant(name, ours.Hash.String(), theirs.Hash.String()) {
+	case ours.Hash.String():
+		return ours, true
+	case theirs.Hash.String():
+		return theirs, true
+	}
+	return ours, false
+}
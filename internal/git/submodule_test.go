@@ -0,0 +1,337 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestSubmoduleAutoResolve(t *testing.T) {
+	tests := []struct {
+		name   string
+		sub    SubmoduleConflict
+		want   string
+		chosen string
+	}{
+		{
+			name:   "mesmo commit nos dois lados",
+			sub:    SubmoduleConflict{Ours: "b", Base: "a", Theirs: "b", ancestor: true},
+			want:   ResolveAutoIdentical,
+			chosen: "b",
+		},
+		{
+			name:   "ours descende de theirs",
+			sub:    SubmoduleConflict{Ours: "c", Base: "a", Theirs: "b", Descendant: "c", ancestor: true},
+			want:   ResolveAutoDescendant,
+			chosen: "c",
+		},
+		{
+			name:   "sem ancestral, theirs descende de ours",
+			sub:    SubmoduleConflict{Ours: "b", Theirs: "c", Descendant: "c"},
+			want:   ResolveAutoDescendant,
+			chosen: "c",
+		},
+		{
+			name:   "só theirs alterou",
+			sub:    SubmoduleConflict{Ours: "a", Base: "a", Theirs: "b", ancestor: true},
+			want:   ResolveAutoTheirs,
+			chosen: "b",
+		},
+		{
+			name:   "só ours alterou",
+			sub:    SubmoduleConflict{Ours: "b", Base: "a", Theirs: "a", ancestor: true},
+			want:   ResolveAutoOurs,
+			chosen: "b",
+		},
+		{
+			name: "os dois alteraram",
+			sub:  SubmoduleConflict{Ours: "b", Base: "a", Theirs: "c", ancestor: true},
+		},
+		{
+			name: "descendente comum de outro commit",
+			sub:  SubmoduleConflict{Ours: "b", Base: "a", Theirs: "c", Descendant: "d", ancestor: true},
+		},
+		{
+			name: "sem ancestral comum",
+			sub:  SubmoduleConflict{Ours: "a", Theirs: "b"},
+		},
+		{
+			name: "removido em ours",
+			sub:  SubmoduleConflict{Base: "a", Theirs: "a", ancestor: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.sub
+			if resolved := sub.autoResolve(); resolved != (tt.want != "") {
+				t.Fatalf("autoResolve = %v, esperado %v", resolved, tt.want != "")
+			}
+			if sub.Resolution != tt.want || sub.chosen() != tt.chosen {
+				t.Fatalf("Resolution = %q, chosen = %q; esperado %q e %q", sub.Resolution, sub.chosen(), tt.want, tt.chosen)
+			}
+
+			// Um submódulo já resolvido não é decidido de novo
+			if sub.autoResolve() {
+				t.Fatalf("autoResolve resolveu de novo")
+			}
+		})
+	}
+}
+
+func TestSubmoduleApply(t *testing.T) {
+	tests := []struct {
+		name       string
+		sub        SubmoduleConflict
+		resolution string
+		chosen     string
+		fails      bool
+	}{
+		{name: "ours", sub: SubmoduleConflict{Ours: "a", Theirs: "b"}, resolution: ResolveOurs, chosen: "a"},
+		{name: "theirs", sub: SubmoduleConflict{Ours: "a", Theirs: "b"}, resolution: ResolveTheirs, chosen: "b"},
+		{name: "descendant", sub: SubmoduleConflict{Ours: "a", Theirs: "b", Descendant: "c"}, resolution: ResolveDescendant, chosen: "c"},
+		{name: "ours ausente", sub: SubmoduleConflict{Theirs: "b"}, resolution: ResolveOurs, fails: true},
+		{name: "theirs ausente", sub: SubmoduleConflict{Ours: "a"}, resolution: ResolveTheirs, fails: true},
+		{name: "sem descendente", sub: SubmoduleConflict{Ours: "a", Theirs: "b"}, resolution: ResolveDescendant, fails: true},
+		{name: "resolução de texto", sub: SubmoduleConflict{Ours: "a", Theirs: "b"}, resolution: ResolveBothOursFirst, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.sub
+			merge := &FileMerge{Path: "sub", Submodule: &sub}
+			merge.countPending()
+
+			err := merge.Apply(HunkDecision{Resolution: tt.resolution})
+			if tt.fails {
+				if err == nil {
+					t.Fatalf("Apply(%s) sem erro", tt.resolution)
+				}
+				if merge.Conflicts != 1 {
+					t.Fatalf("conflitos = %d depois do erro, esperado 1", merge.Conflicts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply(%s): %v", tt.resolution, err)
+			}
+			if merge.Conflicts != 0 {
+				t.Fatalf("conflitos = %d, esperado 0", merge.Conflicts)
+			}
+
+			commit, err := new(Control).SubmoduleCommit(merge)
+			if err != nil || commit != tt.chosen {
+				t.Fatalf("SubmoduleCommit = %q, %v; esperado %q", commit, err, tt.chosen)
+			}
+		})
+	}
+}
+
+func TestSubmoduleCommitErrors(t *testing.T) {
+	control := new(Control)
+
+	if _, err := control.SubmoduleCommit(&FileMerge{Path: "a.txt"}); err == nil {
+		t.Fatalf("SubmoduleCommit de arquivo sem erro")
+	}
+	pending := &FileMerge{Path: "sub", Submodule: &SubmoduleConflict{Ours: "a", Theirs: "b"}}
+	if _, err := control.SubmoduleCommit(pending); err == nil {
+		t.Fatalf("SubmoduleCommit de submódulo pendente sem erro")
+	}
+}
+
+// submoduleRepo monta um repositório com o submódulo sub, cujo repositório
+// local fica em .git/modules/sub. No submódulo, s2 e s3 partem de s1 e s4
+// junta os dois. A branch main aponta o submódulo para s3 e feature para s2;
+// ff parte de feature e avança o submódulo até s4.
+func submoduleRepo(t *testing.T) (repo *testRepo, s1, s2, s3, s4 plumbing.Hash) {
+	repo = newDiskTestRepo(t)
+
+	dir, err := repo.control.gitPath("modules/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("erro ao criar o submódulo: %v", err)
+	}
+	sub := openTestRepo(t, local)
+
+	s1 = sub.commit("main", map[string]string{"lib.txt": "1\n"})
+	s2 = sub.commit("a", map[string]string{"lib.txt": "2\n"}, s1)
+	s3 = sub.commit("b", map[string]string{"lib.txt": "1\n", "extra.txt": "3\n"}, s1)
+	s4 = sub.commit("main", map[string]string{"lib.txt": "2\n", "extra.txt": "3\n"}, s2, s3)
+
+	repo.commit("main", map[string]string{"a.txt": "a\n", "sub": gitlink(s1)})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"a.txt": "feature\n", "sub": gitlink(s2)})
+	repo.commit("main", map[string]string{"a.txt": "a\n", "sub": gitlink(s3)})
+	repo.setBranch("ff", repo.tip("feature"))
+	repo.commit("ff", map[string]string{"a.txt": "feature\n", "sub": gitlink(s4)})
+
+	return repo, s1, s2, s3, s4
+}
+
+func TestMergeFileSubmodule(t *testing.T) {
+	repo, s1, s2, s3, s4 := submoduleRepo(t)
+
+	merge, err := repo.control.MergeFile("feature", "main", "sub", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	want := SubmoduleConflict{Ours: s2.String(), Base: s1.String(), Theirs: s3.String(), Descendant: s4.String(), ancestor: true}
+	if merge.Submodule == nil || *merge.Submodule != want {
+		t.Fatalf("Submodule = %+v, esperado %+v", merge.Submodule, want)
+	}
+	if merge.Conflicts != 1 || len(merge.Chunks) != 0 || merge.Render(ConflictMerge) != "" {
+		t.Fatalf("conflitos = %d, chunks = %d", merge.Conflicts, len(merge.Chunks))
+	}
+
+	if err := merge.Apply(HunkDecision{Resolution: ResolveDescendant}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if commit, err := repo.control.SubmoduleCommit(merge); err != nil || commit != s4.String() {
+		t.Fatalf("SubmoduleCommit = %q, %v; esperado %s", commit, err, s4)
+	}
+
+	// Quando um lado descende do outro o submódulo avança sozinho
+	merge, err = repo.control.MergeFile("ff", "main", "sub", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	if merge.Conflicts != 0 || merge.Submodule.Resolution != ResolveAutoDescendant {
+		t.Fatalf("conflitos = %d, resolução = %q", merge.Conflicts, merge.Submodule.Resolution)
+	}
+
+	var subErr *SubmoduleError
+	if _, err := repo.control.DiffSpecificFile("feature", "main", "sub", CompareMergeBase, RenameOptions{}, ConflictMerge, DiffOptions{}, AutoResolveOptions{}); !errors.As(err, &subErr) {
+		t.Fatalf("DiffSpecificFile = %v, esperado SubmoduleError", err)
+	}
+}
+
+func TestSubmoduleWithoutLocalRepository(t *testing.T) {
+	repo := newDiskTestRepo(t)
+	s1, s2, s3 := plumbing.NewHash("1111111111111111111111111111111111111111"),
+		plumbing.NewHash("2222222222222222222222222222222222222222"),
+		plumbing.NewHash("3333333333333333333333333333333333333333")
+
+	repo.commit("main", map[string]string{"sub": gitlink(s1)})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"sub": gitlink(s2)})
+	repo.commit("main", map[string]string{"sub": gitlink(s3)})
+
+	merge, err := repo.control.MergeFile("feature", "main", "sub", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	if merge.Submodule.Descendant != "" || merge.Conflicts != 1 {
+		t.Fatalf("Submodule = %+v, conflitos = %d", merge.Submodule, merge.Conflicts)
+	}
+
+	ours := object.TreeEntry{Name: "sub", Mode: filemode.Submodule, Hash: s2}
+	theirs := object.TreeEntry{Name: "sub", Mode: filemode.Submodule, Hash: s3}
+	if _, ok := repo.control.mergeGitlinks("sub", ours, theirs); ok {
+		t.Fatalf("mergeGitlinks resolveu sem o repositório do submódulo")
+	}
+	file := object.TreeEntry{Name: "sub", Mode: filemode.Regular, Hash: s3}
+	if _, ok := repo.control.mergeGitlinks("sub", ours, file); ok {
+		t.Fatalf("mergeGitlinks resolveu um arquivo")
+	}
+}
+
+func TestSubmoduleChanges(t *testing.T) {
+	repo, s1, s2, _, _ := submoduleRepo(t)
+
+	changes, err := repo.control.GetFileChanges("feature", "main", CompareMergeBase, RenameOptions{})
+	if err != nil {
+		t.Fatalf("GetFileChanges: %v", err)
+	}
+	want := []FileChange{
+		{Path: "a.txt", Action: "modified"},
+		{Path: "sub", Action: "modified", Submodule: true, OldCommit: s1.String(), NewCommit: s2.String()},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("GetFileChanges = %+v, esperado %+v", changes, want)
+	}
+
+	dest := filepath.Join(t.TempDir(), "saida")
+	files, err := repo.control.DownloadModifiedFiles("feature", "main", dest, CompareMergeBase, RenameOptions{})
+	if err != nil {
+		t.Fatalf("DownloadModifiedFiles: %v", err)
+	}
+	if !reflect.DeepEqual(files, []string{filepath.Join(dest, "a.txt")}) {
+		t.Fatalf("DownloadModifiedFiles = %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "sub")); !os.IsNotExist(err) {
+		t.Fatalf("o submódulo foi gravado no destino: %v", err)
+	}
+}
+
+func TestCommitMergeSubmodule(t *testing.T) {
+	author := Signature{Name: "Teste", Email: "teste@example.com"}
+
+	tests := []struct {
+		name       string
+		target     string
+		submodules map[string]string
+		want       string
+		unresolved bool
+	}{
+		{name: "alterado pelos dois lados", target: "feature", unresolved: true},
+		{name: "commit escolhido", target: "feature", submodules: map[string]string{"sub": "s4"}, want: "s4"},
+		{name: "fast-forward no submódulo", target: "ff", want: "s4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, _, _, _, s4 := submoduleRepo(t)
+			commits := map[string]string{"s4": s4.String()}
+
+			submodules := make(map[string]string)
+			for name, commit := range tt.submodules {
+				submodules[name] = commits[commit]
+			}
+
+			hash, err := repo.control.CommitMerge(MergeCommitOptions{
+				Target:     tt.target,
+				Parents:    []string{tt.target, "main"},
+				Submodules: submodules,
+				Message:    "merge de main",
+				Author:     author,
+			})
+
+			var unresolved *UnresolvedError
+			if tt.unresolved {
+				if !errors.As(err, &unresolved) || !reflect.DeepEqual(unresolved.Files, []string{"sub"}) {
+					t.Fatalf("CommitMerge = %v, esperado UnresolvedError em sub", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CommitMerge: %v", err)
+			}
+
+			commit, err := repo.repo.CommitObject(plumbing.NewHash(hash))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tree, err := commit.Tree()
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry, err := tree.FindEntry("sub")
+			if err != nil {
+				t.Fatalf("sub fora da árvore: %v", err)
+			}
+			if entry.Mode != filemode.Submodule || entry.Hash.String() != commits[tt.want] {
+				t.Fatalf("sub = %v %s, esperado submódulo em %s", entry.Mode, entry.Hash, commits[tt.want])
+			}
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Tipos de conflito de árvore: os dois lados alteraram a existência, o
+// caminho ou o tipo de um arquivo de formas incompatíveis. O primeiro termo é
+// o que a sua branch (ours) fez e o segundo o que a branch base (theirs) fez.
+const (
+	TreeModifyDelete  = "modify/delete"
+	TreeDeleteModify  = "delete/modify"
+	TreeAddAdd        = "add/add"
+	TreeRenameRename  = "rename/rename"
+	TreeRenameDelete  = "rename/delete"
+	TreeFileDirectory = "file/directory"
+)
+
+// Resoluções de conflitos de árvore, além de ours e theirs
+const (
+	// ResolveKeep mantém o arquivo que um dos lados alterou ou renomeou e o
+	// outro removeu.
+	ResolveKeep = "keep"
+
+	// ResolveDelete remove o arquivo do resultado.
+	ResolveDelete = "delete"
+
+	// ResolveKeepBoth mantém as duas versões, cada uma em um caminho
+	// (TreeConflict.KeepBoth).
+	ResolveKeepBoth = "keep-both"
+)
+
+// treeChoices são as resoluções aceitas por tipo de conflito de árvore.
+// Em add/add, ours e theirs escolhem o arquivo inteiro; o conteúdo também
+// pode ser mesclado bloco a bloco.
+var treeChoices = map[string][]string{
+	TreeModifyDelete:  {ResolveKeep, ResolveDelete},
+	TreeDeleteModify:  {ResolveKeep, ResolveDelete},
+	TreeAddAdd:        {ResolveOurs, ResolveTheirs, ResolveKeepBoth},
+	TreeRenameRename:  {ResolveOurs, ResolveTheirs, ResolveKeepBoth},
+	TreeRenameDelete:  {ResolveKeep, ResolveDelete},
+	TreeFileDirectory: {ResolveKeepBoth, ResolveDelete},
+}
+
+// TreeSide é a versão de um lado em um conflito de árvore.
+type TreeSide struct {
+	Path string `json:"path"`
+
+	// Hash fica vazio quando o caminho é um diretório (Dir). Em submódulos
+	// é o commit do submódulo.
+	Hash      string `json:"hash,omitempty"`
+	Dir       bool   `json:"dir,omitempty"`
+	Submodule bool   `json:"submodule,omitempty"`
+
+	// Mode é o modo do arquivo ("regular", "executable" ou "symlink"); fica
+	// vazio em diretórios e submódulos.
+	Mode string `json:"mode,omitempty"`
+}
+
+// TreeConflict descreve um conflito de árvore. Path é o caminho pelo qual o
+// conflito aparece na lista de alterações: o da sua branch quando o arquivo
+// existe nela, senão o da branch base. Ours e Theirs são nil no lado que
+// removeu o arquivo; Base é nil quando o arquivo não existia no ancestral comum.
+type TreeConflict struct {
+	Kind   string    `json:"kind"`
+	Path   string    `json:"path"`
+	Ours   *TreeSide `json:"ours,omitempty"`
+	Base   *TreeSide `json:"base,omitempty"`
+	Theirs *TreeSide `json:"theirs,omitempty"`
+
+	// Choices são as resoluções aceitas pelo tipo do conflito.
+	Choices []string `json:"choices"`
+
+	// KeepBoth são os caminhos usados por ResolveKeepBoth: os das versões de
+	// ours e de theirs, nessa ordem. Em file/directory só o arquivo muda de
+	// caminho e a lista tem um item.
+	KeepBoth []string `json:"keepBoth,omitempty"`
+
+	Resolution string `json:"resolution,omitempty"`
+}
+
+// TreeConflictError indica que o arquivo tem um conflito de árvore e não tem
+// diff de texto. Merge traz o conflito para a escolha de uma das resoluções.
+type TreeConflictError struct {
+	Merge *FileMerge
+}
+
+func (e *TreeConflictError) Error() string {
+	tree := e.Merge.Tree
+	return fmt.Sprintf("o arquivo %s tem um conflito %s: escolha %s", e.Merge.Path, tree.Kind, strings.Join(tree.Choices, ", "))
+}
+
+// TreeConflicts retorna os conflitos de árvore entre as duas branches, em
+// relação ao ancestral comum, ordenados pelo caminho. Renomeações são
+// detectadas conforme renames.
+func (e *Control) TreeConflicts(branchName, branchBase string, renames RenameOptions) ([]TreeConflict, error) {
+	cmp, err := e.compareBranches(branchName, branchBase, CompareMergeBase, renames)
+	if err != nil {
+		return nil, err
+	}
+	return e.comparisonConflicts(cmp)
+}
+
+// comparisonConflicts retorna os conflitos de árvore da comparação.
+func (e *Control) comparisonConflicts(cmp *comparison) ([]TreeConflict, error) {
+	return e.treeConflicts(cmp.targetTree, cmp.baseTree, cmp.ancestors, cmp.targetName, cmp.baseName, cmp.renameOptions, cmp.similarities)
+}
+
+// treeConflicts compara as alterações feitas pelos dois lados desde o
+// primeiro ancestral comum. oursName e theirsName dão nome aos caminhos de
+// ResolveKeepBoth. renames define a detecção de renomeações e sims é o cache
+// de linhas usado por ela.
+func (e *Control) treeConflicts(oursTree, theirsTree *object.Tree, ancestors []*object.Commit, oursName, theirsName string, renames RenameOptions, sims *similarityCache) ([]TreeConflict, error) {
+	ours, err := flattenTree(oursTree)
+	if err != nil {
+		return nil, err
+	}
+
+	theirs, err := flattenTree(theirsTree)
+	if err != nil {
+		return nil, err
+	}
+
+	base := make(map[string]object.TreeEntry)
+	var oursMoved, theirsMoved map[string]string
+
+	if len(ancestors) > 0 {
+		ancestorTree, err := ancestors[0].Tree()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao obter árvore do commit %s: %w", ancestors[0].Hash, err)
+		}
+
+		if base, err = flattenTree(ancestorTree); err != nil {
+			return nil, err
+		}
+		if oursMoved, err = e.renamedPaths(ancestorTree, oursTree, renames, sims); err != nil {
+			return nil, err
+		}
+		if theirsMoved, err = e.renamedPaths(ancestorTree, theirsTree, renames, sims); err != nil {
+			return nil, err
+		}
+	}
+
+	var conflicts []TreeConflict
+	add := func(kind, name string, o, b, t *TreeSide) *TreeConflict {
+		conflicts = append(conflicts, TreeConflict{Kind: kind, Path: name, Ours: o, Base: b, Theirs: t, Choices: treeChoices[kind]})
+		return &conflicts[len(conflicts)-1]
+	}
+
+	// Arquivos do ancestral removidos, renomeados ou alterados
+	for name, entry := range base {
+		o, inOurs := ours[name]
+		t, inTheirs := theirs[name]
+		oursTo, oursRenamed := oursMoved[name]
+		theirsTo, theirsRenamed := theirsMoved[name]
+		b := treeSide(name, entry)
+
+		switch {
+		case oursRenamed && theirsRenamed:
+			if oursTo != theirsTo {
+				c := add(TreeRenameRename, oursTo, treeSide(oursTo, ours[oursTo]), b, treeSide(theirsTo, theirs[theirsTo]))
+				c.KeepBoth = []string{oursTo, theirsTo}
+			}
+		case oursRenamed && !inTheirs:
+			add(TreeRenameDelete, oursTo, treeSide(oursTo, ours[oursTo]), b, nil)
+		case theirsRenamed && !inOurs:
+			add(TreeRenameDelete, theirsTo, nil, b, treeSide(theirsTo, theirs[theirsTo]))
+		case oursRenamed || theirsRenamed:
+			// O outro lado manteve o arquivo: o conteúdo segue a renomeação
+		case inOurs && !inTheirs && !sameEntry(o, entry):
+			add(TreeModifyDelete, name, treeSide(name, o), b, nil)
+		case !inOurs && inTheirs && !sameEntry(t, entry):
+			add(TreeDeleteModify, name, nil, b, treeSide(name, t))
+		}
+	}
+
+	// Arquivos criados nos dois lados com conteúdos diferentes. Renomeações
+	// para o mesmo caminho nos dois lados são mescladas como alterações
+	for name, o := range ours {
+		t, inTheirs := theirs[name]
+		if _, inBase := base[name]; inBase || !inTheirs || sameEntry(o, t) {
+			continue
+		}
+		if sameRename(oursMoved, theirsMoved, name) {
+			continue
+		}
+
+		c := add(TreeAddAdd, name, treeSide(name, o), nil, treeSide(name, t))
+		c.KeepBoth = []string{keepBothPath(name, oursName), keepBothPath(name, theirsName)}
+	}
+
+	// Um lado criou ou alterou um arquivo onde o outro tem um diretório com
+	// arquivos criados ou alterados
+	oursDirs, theirsDirs := treeDirs(ours), treeDirs(theirs)
+	for name, o := range ours {
+		if theirsDirs[name] && changedEntry(base, name, o) && changedUnder(base, theirs, name) {
+			c := add(TreeFileDirectory, name, treeSide(name, o), nil, &TreeSide{Path: name, Dir: true})
+			c.KeepBoth = []string{keepBothPath(name, oursName)}
+		}
+	}
+	for name, t := range theirs {
+		if oursDirs[name] && changedEntry(base, name, t) && changedUnder(base, ours, name) {
+			c := add(TreeFileDirectory, name, &TreeSide{Path: name, Dir: true}, nil, treeSide(name, t))
+			c.KeepBoth = []string{keepBothPath(name, theirsName)}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Path != conflicts[j].Path {
+			return conflicts[i].Path < conflicts[j].Path
+		}
+		return conflicts[i].Kind < conflicts[j].Kind
+	})
+
+	return conflicts, nil
+}
+
+// renamedPaths retorna os arquivos renomeados de from para to, do caminho
+// antigo para o novo. Cópias não entram.
+func (e *Control) renamedPaths(from, to *object.Tree, opts RenameOptions, sims *similarityCache) (map[string]string, error) {
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao comparar árvores: %w", err)
+	}
+
+	_, renames, err := e.detectRenames(changes, from, opts, sims)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]string)
+	for change, info := range renames {
+		if !info.copy {
+			paths[change.From.Name] = change.To.Name
+		}
+	}
+	return paths, nil
+}
+
+// sameRename informa se os dois lados renomearam o mesmo arquivo para name.
+func sameRename(oursMoved, theirsMoved map[string]string, name string) bool {
+	for from, to := range oursMoved {
+		if to == name && theirsMoved[from] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// treeSide identifica a versão de um lado.
+func treeSide(name string, entry object.TreeEntry) *TreeSide {
+	side := &TreeSide{Path: name, Hash: entry.Hash.String(), Submodule: entry.Mode == filemode.Submodule}
+	if !side.Submodule {
+		side.Mode = modeName(entry.Mode)
+	}
+	return side
+}
+
+// treeDirs retorna os diretórios que contêm as entradas.
+func treeDirs(entries map[string]object.TreeEntry) map[string]bool {
+	dirs := make(map[string]bool)
+	for name := range entries {
+		for i := strings.LastIndexByte(name, '/'); i > 0; i = strings.LastIndexByte(name[:i], '/') {
+			if dirs[name[:i]] {
+				break
+			}
+			dirs[name[:i]] = true
+		}
+	}
+	return dirs
+}
+
+// changedEntry informa se a entrada é nova ou diferente da do ancestral.
+func changedEntry(base map[string]object.TreeEntry, name string, entry object.TreeEntry) bool {
+	original, ok := base[name]
+	return !ok || !sameEntry(original, entry)
+}
+
+// changedUnder informa se algum arquivo dentro do diretório dir é novo ou
+// diferente do ancestral.
+func changedUnder(base, entries map[string]object.TreeEntry, dir string) bool {
+	prefix := dir + "/"
+	for name, entry := range entries {
+		if strings.HasPrefix(name, prefix) && changedEntry(base, name, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepBothPath é o caminho de uma das versões mantidas com ResolveKeepBoth,
+// como o git faz: "arquivo~branch", com as barras do nome da branch trocadas
+// por "_".
+func keepBothPath(name, side string) string {
+	return name + "~" + strings.ReplaceAll(side, "/", "_")
+}
+
+// paths retorna os caminhos de arquivo envolvidos no conflito.
+func (c *TreeConflict) paths() []string {
+	paths := []string{c.Path}
+	for _, side := range []*TreeSide{c.Ours, c.Theirs} {
+		if side != nil && !side.Dir && side.Path != c.Path {
+			paths = append(paths, side.Path)
+		}
+	}
+	return paths
+}
+
+// pending informa se ainda falta escolher a resolução. O conflito add/add
+// também é resolvido pelos blocos do conteúdo e não conta como pendente.
+func (c *TreeConflict) pending() bool {
+	return c.Resolution == "" && c.Kind != TreeAddAdd
+}
+
+// apply escolhe uma das resoluções do conflito.
+func (c *TreeConflict) apply(resolution string) error {
+	for _, choice := range c.Choices {
+		if choice == resolution {
+			c.Resolution = resolution
+			return nil
+		}
+	}
+	return fmt.Errorf("conflitos %s aceitam apenas as resoluções %s: %s", c.Kind, strings.Join(c.Choices, ", "), resolution)
+}
+
+// outcome retorna os arquivos que ficam no resultado, indexados pelo caminho
+// com a versão que vai para ele, e os caminhos que saem do resultado.
+func (c *TreeConflict) outcome() (map[string]*TreeSide, []string) {
+	kept := make(map[string]*TreeSide)
+	var removed []string
+
+	// file é o lado que tem o arquivo; nos conflitos com um lado removido ou
+	// com um diretório, é o único
+	file := c.Ours
+	if file == nil || file.Dir {
+		file = c.Theirs
+	}
+
+	switch c.Resolution {
+	case ResolveKeep:
+		kept[c.Path] = file
+	case ResolveDelete:
+		removed = append(removed, c.Path)
+	case ResolveOurs:
+		kept[c.Ours.Path] = c.Ours
+		if c.Theirs.Path != c.Ours.Path {
+			removed = append(removed, c.Theirs.Path)
+		}
+	case ResolveTheirs:
+		kept[c.Theirs.Path] = c.Theirs
+		if c.Ours.Path != c.Theirs.Path {
+			removed = append(removed, c.Ours.Path)
+		}
+	case ResolveKeepBoth:
+		switch c.Kind {
+		case TreeFileDirectory:
+			kept[c.KeepBoth[0]] = file
+		default:
+			kept[c.KeepBoth[0]] = c.Ours
+			kept[c.KeepBoth[1]] = c.Theirs
+		}
+		if _, ok := kept[c.Path]; !ok {
+			removed = append(removed, c.Path)
+		}
+	}
+
+	return kept, removed
+}
+
+// TreeOutcome é o resultado de um conflito de árvore resolvido, nos campos
+// que CommitMerge recebe em MergeCommitOptions.
+type TreeOutcome struct {
+	Files      map[string]string // Conteúdo dos arquivos que ficam, pelo caminho
+	Submodules map[string]string // Commit dos submódulos que ficam, pelo caminho
+	Modes      map[string]string // Modo dos arquivos que ficam, pelo caminho
+	Deleted    []string          // Caminhos que saem do resultado
+}
+
+// TreeResult retorna o resultado de um conflito de árvore já resolvido.
+func (e *Control) TreeResult(merge *FileMerge) (*TreeOutcome, error) {
+	if merge.Tree == nil {
+		return nil, fmt.Errorf("o arquivo %s não tem conflito de árvore", merge.Path)
+	}
+	if merge.Tree.Resolution == "" {
+		return nil, fmt.Errorf("o conflito %s de %s ainda não foi resolvido", merge.Tree.Kind, merge.Path)
+	}
+
+	kept, removed := merge.Tree.outcome()
+
+	result := &TreeOutcome{
+		Files:      make(map[string]string),
+		Submodules: make(map[string]string),
+		Modes:      make(map[string]string),
+		Deleted:    removed,
+	}
+	for name, side := range kept {
+		if side.Submodule {
+			result.Submodules[name] = side.Hash
+			continue
+		}
+
+		content, err := e.blobContent(plumbing.NewHash(side.Hash))
+		if err != nil {
+			return nil, err
+		}
+		result.Files[name] = content
+		result.Modes[name] = side.Mode
+	}
+
+	return result, nil
+}
@@ -0,0 +1,232 @@
+package git
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// longText é um conteúdo grande o bastante para a detecção de renomeações.
+var longText = strings.Repeat("linha de um arquivo renomeado\n", 20)
+
+func TestTreeConflicts(t *testing.T) {
+	tests := []struct {
+		name         string
+		base         map[string]string
+		ours, theirs map[string]string
+		renames      RenameOptions
+		want         []string // tipo e caminho de cada conflito
+	}{
+		{
+			name:   "sem conflitos de árvore",
+			base:   map[string]string{"a.txt": "a\n"},
+			ours:   map[string]string{"a.txt": "b\n", "b.txt": "b\n"},
+			theirs: map[string]string{"a.txt": "c\n", "c.txt": "c\n"},
+			want:   nil,
+		},
+		{
+			name:   "modify/delete",
+			base:   map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+			ours:   map[string]string{"a.txt": "alterado\n", "b.txt": "b\n"},
+			theirs: map[string]string{"b.txt": "b\n"},
+			want:   []string{"modify/delete a.txt"},
+		},
+		{
+			name:   "delete/modify",
+			base:   map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+			ours:   map[string]string{"b.txt": "b\n"},
+			theirs: map[string]string{"a.txt": "alterado\n", "b.txt": "b\n"},
+			want:   []string{"delete/modify a.txt"},
+		},
+		{
+			name:   "removido sem alteração do outro lado",
+			base:   map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+			ours:   map[string]string{"b.txt": "b\n"},
+			theirs: map[string]string{"a.txt": "a\n", "b.txt": "B\n"},
+			want:   nil,
+		},
+		{
+			name:   "add/add",
+			base:   map[string]string{"b.txt": "b\n"},
+			ours:   map[string]string{"b.txt": "b\n", "novo.txt": "ours\n"},
+			theirs: map[string]string{"b.txt": "b\n", "novo.txt": "theirs\n"},
+			want:   []string{"add/add novo.txt"},
+		},
+		{
+			name:   "rename/rename",
+			base:   map[string]string{"a.txt": longText},
+			ours:   map[string]string{"ours.txt": longText},
+			theirs: map[string]string{"theirs.txt": longText},
+			want:   []string{"rename/rename ours.txt"},
+		},
+		{
+			name:   "rename/delete",
+			base:   map[string]string{"a.txt": longText, "b.txt": "b\n"},
+			ours:   map[string]string{"novo.txt": longText, "b.txt": "b\n"},
+			theirs: map[string]string{"b.txt": "b\n"},
+			want:   []string{"rename/delete novo.txt"},
+		},
+		{
+			name:    "rename/delete sem detecção de renomeações",
+			base:    map[string]string{"a.txt": longText, "b.txt": "b\n"},
+			ours:    map[string]string{"novo.txt": longText, "b.txt": "b\n"},
+			theirs:  map[string]string{"b.txt": "b\n"},
+			renames: RenameOptions{Disabled: true},
+			want:    nil,
+		},
+		{
+			name:   "mesma renomeação nos dois lados",
+			base:   map[string]string{"a.txt": longText},
+			ours:   map[string]string{"novo.txt": longText + "ours\n"},
+			theirs: map[string]string{"novo.txt": "theirs\n" + longText},
+			want:   nil,
+		},
+		{
+			name:   "file/directory",
+			base:   map[string]string{"b.txt": "b\n"},
+			ours:   map[string]string{"b.txt": "b\n", "x": "arquivo\n"},
+			theirs: map[string]string{"b.txt": "b\n", "x/y.txt": "y\n"},
+			want:   []string{"file/directory x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepo(t)
+			repo.commit("main", tt.base)
+			repo.setBranch("feature", repo.tip("main"))
+			repo.commit("feature", tt.ours)
+			repo.commit("main", tt.theirs)
+
+			conflicts, err := repo.control.TreeConflicts("feature", "main", tt.renames)
+			if err != nil {
+				t.Fatalf("TreeConflicts: %v", err)
+			}
+
+			var got []string
+			for _, c := range conflicts {
+				got = append(got, c.Kind+" "+c.Path)
+				if !reflect.DeepEqual(c.Choices, treeChoices[c.Kind]) {
+					t.Errorf("conflito %s com resoluções %v", c.Kind, c.Choices)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("TreeConflicts = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTreeConflictOutcome(t *testing.T) {
+	ours := &TreeSide{Path: "ours.txt"}
+	theirs := &TreeSide{Path: "theirs.txt"}
+
+	tests := []struct {
+		name        string
+		conflict    TreeConflict
+		kept        []string
+		removed     []string
+		invalidWith string
+	}{
+		{
+			name:        "keep em modify/delete",
+			conflict:    TreeConflict{Kind: TreeModifyDelete, Path: "a.txt", Ours: &TreeSide{Path: "a.txt"}, Resolution: ResolveKeep},
+			kept:        []string{"a.txt"},
+			invalidWith: ResolveOurs,
+		},
+		{
+			name:     "delete em delete/modify",
+			conflict: TreeConflict{Kind: TreeDeleteModify, Path: "a.txt", Theirs: &TreeSide{Path: "a.txt"}, Resolution: ResolveDelete},
+			removed:  []string{"a.txt"},
+		},
+		{
+			name:     "ours em rename/rename",
+			conflict: TreeConflict{Kind: TreeRenameRename, Path: "ours.txt", Ours: ours, Theirs: theirs, Resolution: ResolveOurs},
+			kept:     []string{"ours.txt"},
+			removed:  []string{"theirs.txt"},
+		},
+		{
+			name: "keep-both em add/add",
+			conflict: TreeConflict{
+				Kind: TreeAddAdd, Path: "a.txt", Ours: &TreeSide{Path: "a.txt"}, Theirs: &TreeSide{Path: "a.txt"},
+				KeepBoth: []string{"a.txt~feature", "a.txt~main"}, Resolution: ResolveKeepBoth,
+			},
+			kept:        []string{"a.txt~feature", "a.txt~main"},
+			removed:     []string{"a.txt"},
+			invalidWith: ResolveKeep,
+		},
+		{
+			name: "keep-both em file/directory",
+			conflict: TreeConflict{
+				Kind: TreeFileDirectory, Path: "x", Ours: &TreeSide{Path: "x", Dir: true}, Theirs: &TreeSide{Path: "x"},
+				KeepBoth: []string{"x~main"}, Resolution: ResolveKeepBoth,
+			},
+			kept:    []string{"x~main"},
+			removed: []string{"x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conflict.Choices = treeChoices[tt.conflict.Kind]
+			if err := tt.conflict.apply(tt.conflict.Resolution); err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if tt.invalidWith != "" && tt.conflict.apply(tt.invalidWith) == nil {
+				t.Fatalf("apply(%s) aceito em %s", tt.invalidWith, tt.conflict.Kind)
+			}
+
+			kept, removed := tt.conflict.outcome()
+
+			var keptPaths []string
+			for path := range kept {
+				keptPaths = append(keptPaths, path)
+			}
+			sort.Strings(keptPaths)
+
+			if !reflect.DeepEqual(keptPaths, tt.kept) || !reflect.DeepEqual(removed, tt.removed) {
+				t.Fatalf("outcome = %q e %q, esperado %q e %q", keptPaths, removed, tt.kept, tt.removed)
+			}
+		})
+	}
+}
+
+func TestTreeResult(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"a.txt": "alterado\n", "b.txt": "b\n"})
+	repo.commit("main", map[string]string{"b.txt": "b\n"})
+
+	_, err := repo.control.DiffSpecificFile("feature", "main", "a.txt", CompareMergeBase, RenameOptions{}, ConflictMerge, DiffOptions{}, AutoResolveOptions{})
+	if _, ok := err.(*TreeConflictError); !ok {
+		t.Fatalf("DiffSpecificFile = %v, esperado TreeConflictError", err)
+	}
+
+	merge, err := repo.control.MergeFile("feature", "main", "a.txt", CompareMergeBase, RenameOptions{}, DiffOptions{}, AutoResolveOptions{})
+	if err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	if merge.Tree == nil || merge.Tree.Kind != TreeModifyDelete || merge.Conflicts != 1 {
+		t.Fatalf("MergeFile = %+v, esperado um conflito modify/delete", merge.Tree)
+	}
+	if _, err := repo.control.TreeResult(merge); err == nil {
+		t.Fatalf("TreeResult de conflito pendente não retornou erro")
+	}
+
+	if err := merge.Apply(HunkDecision{Resolution: ResolveKeep}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if merge.Conflicts != 0 {
+		t.Fatalf("conflitos depois de resolver = %d, esperado 0", merge.Conflicts)
+	}
+
+	result, err := repo.control.TreeResult(merge)
+	if err != nil {
+		t.Fatalf("TreeResult: %v", err)
+	}
+	if result.Files["a.txt"] != "alterado\n" || result.Modes["a.txt"] != ModeRegular || len(result.Submodules) != 0 || len(result.Deleted) != 0 {
+		t.Fatalf("TreeResult = %+v", result)
+	}
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOptions define quais diferenças são ignoradas ao comparar linhas, como
+// as opções --ignore-* do `git diff`. Linhas iguais pelas regras escolhidas não
+// geram diferença nem conflito; o resultado mantém o texto original de ours.
+type DiffOptions struct {
+	// IgnoreCRAtEOL ignora o \r do fim das linhas (CRLF e LF são iguais).
+	IgnoreCRAtEOL bool
+
+	// IgnoreSpaceAtEOL ignora espaços no fim das linhas.
+	IgnoreSpaceAtEOL bool
+
+	// IgnoreAllSpace ignora todos os espaços das linhas.
+	IgnoreAllSpace bool
+
+	// IgnoreBlankLines ignora alterações em que todas as linhas são vazias.
+	IgnoreBlankLines bool
+}
+
+// ParseDiffOptions converte a lista separada por vírgulas usada na API em
+// DiffOptions. Aceita "cr-at-eol", "space-at-eol", "all-space" e "blank-lines";
+// vazio não ignora nada.
+func ParseDiffOptions(names string) (DiffOptions, error) {
+	var opts DiffOptions
+	if names == "" {
+		return opts, nil
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "cr-at-eol":
+			opts.IgnoreCRAtEOL = true
+		case "space-at-eol":
+			opts.IgnoreSpaceAtEOL = true
+		case "all-space":
+			opts.IgnoreAllSpace = true
+		case "blank-lines":
+			opts.IgnoreBlankLines = true
+		default:
+			return DiffOptions{}, fmt.Errorf("opção de comparação desconhecida: %s", name)
+		}
+	}
+
+	return opts, nil
+}
+
+// normalizeLine aplica a uma linha as regras de comparação.
+func (o DiffOptions) normalizeLine(line string) string {
+	switch {
+	case o.IgnoreAllSpace:
+		return strings.Join(strings.Fields(line), "")
+	case o.IgnoreSpaceAtEOL:
+		return strings.TrimRight(line, " \t\r")
+	case o.IgnoreCRAtEOL:
+		return strings.TrimSuffix(line, "\r")
+	}
+	return line
+}
+
+// normalize retorna as linhas como são comparadas. Sem regras, retorna as
+// próprias linhas.
+func (o DiffOptions) normalize(lines []string) []string {
+	if !o.IgnoreCRAtEOL && !o.IgnoreSpaceAtEOL && !o.IgnoreAllSpace {
+		return lines
+	}
+
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = o.normalizeLine(line)
+	}
+	return normalized
+}
+
+// significant retorna as linhas normalizadas sem as vazias, quando linhas
+// vazias são ignoradas.
+func (o DiffOptions) significant(lines []string) []string {
+	normalized := o.normalize(lines)
+	if !o.IgnoreBlankLines {
+		return normalized
+	}
+
+	var kept []string
+	for _, line := range normalized {
+		if strings.TrimSpace(line) != "" {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// equal informa se dois trechos são iguais pelas regras de comparação.
+func (o DiffOptions) equal(a, b []string) bool {
+	return equalLines(o.significant(a), o.significant(b))
+}
+
+// ignored informa se um trecho alterado entre as linhas normalizadas base e
+// side deve ser ignorado: com IgnoreBlankLines, quando só tem linhas vazias.
+func (o DiffOptions) ignored(h diffHunk, base, side []string) bool {
+	if !o.IgnoreBlankLines {
+		return false
+	}
+	return blankLines(base[h.baseStart:h.baseEnd]) && blankLines(side[h.sideStart:h.sideEnd])
+}
+
+// blankLines informa se todas as linhas são vazias ou só têm espaços.
+func blankLines(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return false
+		}
+	}
+	return true
+}
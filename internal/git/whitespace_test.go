@@ -0,0 +1,158 @@
+package git
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestMergeLinesDiffOptions(t *testing.T) {
+	base := "a\nb\nc"
+	theirs := "a\nB\nc"
+
+	tests := []struct {
+		name string
+		ours string
+		opts DiffOptions
+	}{
+		{"CRLF", "a\r\nb\r\nc\r", DiffOptions{IgnoreCRAtEOL: true}},
+		{"espaços no fim", "a \nb\t\nc ", DiffOptions{IgnoreSpaceAtEOL: true}},
+		{"indentação", "  a\n  b\n  c", DiffOptions{IgnoreAllSpace: true}},
+		{"linhas vazias", "a\n\nb\n\nc", DiffOptions{IgnoreBlankLines: true}},
+	}
+
+	conflicts := func(chunks []Chunk) int {
+		n := 0
+		for _, chunk := range chunks {
+			if chunk.Conflict {
+				n++
+			}
+		}
+		return n
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, o, th := splitLines(base), splitLines(tt.ours), splitLines(theirs)
+
+			if n := conflicts(mergeLines(b, o, th, DiffOptions{})); n == 0 {
+				t.Fatalf("merge sem opções não teve conflito")
+			}
+
+			chunks := mergeLines(b, o, th, tt.opts)
+			if n := conflicts(chunks); n != 0 {
+				t.Fatalf("merge com %+v teve %d conflitos", tt.opts, n)
+			}
+
+			// A linha alterada por theirs entra no resultado
+			found := false
+			for _, chunk := range chunks {
+				for _, line := range chunk.Lines {
+					found = found || line == "B"
+				}
+			}
+			if !found {
+				t.Fatalf("resultado sem a alteração de theirs: %+v", chunks)
+			}
+		})
+	}
+}
+
+func TestDiffOptionsEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		opts DiffOptions
+		want bool
+	}{
+		{"x\r", "x", DiffOptions{}, false},
+		{"x\r", "x", DiffOptions{IgnoreCRAtEOL: true}, true},
+		{"x \r", "x", DiffOptions{IgnoreCRAtEOL: true}, false},
+		{"x \r", "x", DiffOptions{IgnoreSpaceAtEOL: true}, true},
+		{" x", "x", DiffOptions{IgnoreSpaceAtEOL: true}, false},
+		{"a b", " ab\t", DiffOptions{IgnoreAllSpace: true}, true},
+		{"x\n\ny", "x\ny\n", DiffOptions{IgnoreBlankLines: true}, true},
+		{"x\n\ny", "x\ny", DiffOptions{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.opts.equal(splitLines(tt.a), splitLines(tt.b)); got != tt.want {
+			t.Errorf("equal(%q, %q) com %+v = %v, esperado %v", tt.a, tt.b, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestParseDiffOptions(t *testing.T) {
+	tests := []struct {
+		names string
+		want  DiffOptions
+		fails bool
+	}{
+		{names: "", want: DiffOptions{}},
+		{names: "cr-at-eol", want: DiffOptions{IgnoreCRAtEOL: true}},
+		{names: "space-at-eol, blank-lines", want: DiffOptions{IgnoreSpaceAtEOL: true, IgnoreBlankLines: true}},
+		{names: "all-space", want: DiffOptions{IgnoreAllSpace: true}},
+		{names: "all-space,tabs", fails: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDiffOptions(tt.names)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("ParseDiffOptions(%q) não retornou erro", tt.names)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDiffOptions(%q) = %+v, %v; esperado %+v", tt.names, got, err, tt.want)
+		}
+	}
+}
+
+func TestDiffOptionsPerCall(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"a.txt": "a\nb\nc\n"})
+	repo.setBranch("feature", repo.tip("main"))
+	repo.commit("feature", map[string]string{"a.txt": "a\nb  \nc\n"})
+	repo.commit("main", map[string]string{"a.txt": "a\nB\nc\n"})
+
+	ignore := DiffOptions{IgnoreSpaceAtEOL: true}
+
+	// As opções de uma chamada não valem para as seguintes
+	for _, tt := range []struct {
+		opts      DiffOptions
+		conflicts int
+	}{
+		{ignore, 0},
+		{DiffOptions{}, 1},
+		{ignore, 0},
+	} {
+		merge, err := repo.control.MergeFile("feature", "main", "a.txt", CompareMergeBase, RenameOptions{}, tt.opts, AutoResolveOptions{Disabled: true})
+		if err != nil {
+			t.Fatalf("MergeFile: %v", err)
+		}
+		if merge.Conflicts != tt.conflicts {
+			t.Fatalf("MergeFile com %+v: conflitos = %d, esperado %d", tt.opts, merge.Conflicts, tt.conflicts)
+		}
+	}
+
+	opts := MergeCommitOptions{
+		Target:      "feature",
+		Parents:     []string{"feature", "main"},
+		Message:     "merge de main",
+		Author:      Signature{Name: "Teste", Email: "teste@example.com"},
+		AutoResolve: AutoResolveOptions{Disabled: true},
+	}
+	var unresolved *UnresolvedError
+	if _, err := repo.control.CommitMerge(opts); !errors.As(err, &unresolved) {
+		t.Fatalf("CommitMerge sem opções = %v, esperado UnresolvedError", err)
+	}
+	opts.Diff = ignore
+	hash, err := repo.control.CommitMerge(opts)
+	if err != nil {
+		t.Fatalf("CommitMerge com %+v: %v", ignore, err)
+	}
+	if got := repo.files(plumbing.NewHash(hash))["a.txt"]; got != "a\nB\nc\n" {
+		t.Fatalf("a.txt = %q", got)
+	}
+}
@@ -0,0 +1,642 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLDriver é o nome do driver de merge de arquivos YAML.
+const YAMLDriver = "yaml"
+
+// yamlIdentityKeys são as chaves que identificam os itens de uma sequência de
+// mapas, como os containers e as portas de um manifesto Kubernetes ou os
+// passos de um pipeline de CI. Vale a primeira presente em todos os itens.
+var yamlIdentityKeys = []string{"name", "id", "key"}
+
+func init() {
+	RegisterMergeDriver(YAMLDriver, MergeDriverFunc(yamlMerge))
+	_ = RegisterMergeDriverPattern("*.yaml", YAMLDriver)
+	_ = RegisterMergeDriverPattern("*.yml", YAMLDriver)
+}
+
+// yamlMerge é o driver de arquivos YAML. O merge é feito na árvore de nós:
+// mapas chave a chave e sequências de mapas pelo item, identificado por uma
+// das yamlIdentityKeys, mantendo os comentários e a ordem das chaves de ours.
+// Os valores que os dois lados alteraram de formas diferentes ficam com as
+// três versões e o resultado passa pelo merge de linhas, que só deixa em
+// conflito as linhas realmente divergentes. Se algum lado não for YAML
+// válido, o merge é o de texto.
+func yamlMerge(input MergeInput) ([]Chunk, error) {
+	ours, errOurs := parseYAML(input.Ours)
+	theirs, errTheirs := parseYAML(input.Theirs)
+
+	var base []*yaml.Node
+	var errBase error
+	if input.HasBase && strings.TrimSpace(input.Base) != "" {
+		base, errBase = parseYAML(input.Base)
+	}
+
+	if errOurs != nil || errTheirs != nil || errBase != nil ||
+		len(ours) != len(theirs) || (base != nil && len(base) != len(ours)) {
+		return textMerge(input)
+	}
+
+	// Sem alteração de conteúdo em um dos lados, o texto do outro é mantido
+	// como está, sem reformatar
+	switch {
+	case sameYAMLDocuments(ours, theirs), base != nil && sameYAMLDocuments(base, theirs):
+		return []Chunk{{Lines: splitLines(input.Ours)}}, nil
+	case base != nil && sameYAMLDocuments(base, ours):
+		return []Chunk{{Lines: splitLines(input.Theirs)}}, nil
+	}
+
+	mergedOurs := make([]*yaml.Node, len(ours))
+	mergedBase := make([]*yaml.Node, len(ours))
+	mergedTheirs := make([]*yaml.Node, len(ours))
+	for i := range ours {
+		var b *yaml.Node
+		if base != nil {
+			b = base[i]
+		}
+		mergedOurs[i], mergedBase[i], mergedTheirs[i] = mergeYAML(b, ours[i], theirs[i])
+	}
+
+	text := newYAMLText(input.Ours)
+	text.index(input.Ours, ours, true)
+	text.index(input.Theirs, theirs, false)
+	if base != nil {
+		text.index(input.Base, base, false)
+	}
+
+	oursText, err := text.render(mergedOurs)
+	if err != nil {
+		return textMerge(input)
+	}
+	baseText, err := text.render(mergedBase)
+	if err != nil {
+		return textMerge(input)
+	}
+	theirsText, err := text.render(mergedTheirs)
+	if err != nil {
+		return textMerge(input)
+	}
+
+	return mergeLines(splitLines(baseText), splitLines(oursText), splitLines(theirsText), input.Options), nil
+}
+
+// parseYAML lê os documentos de um arquivo YAML.
+func parseYAML(content string) ([]*yaml.Node, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+
+	var docs []*yaml.Node
+	for {
+		doc := new(yaml.Node)
+		err := decoder.Decode(doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	if len(docs) == 0 {
+		return nil, errors.New("arquivo YAML vazio")
+	}
+
+	return docs, nil
+}
+
+// encodeYAML escreve os documentos com a indentação informada.
+func encodeYAML(docs []*yaml.Node, indent int) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			return "", err
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// yamlSpan é o trecho do texto de um lado ocupado por uma entrada (um
+// documento, uma chave de mapa com o valor ou um item de sequência), com os
+// comentários acima dela e as linhas em branco depois.
+type yamlSpan struct {
+	lines      []string
+	start, end int
+}
+
+// yamlPosition identifica um nó pela posição no texto.
+type yamlPosition struct {
+	line, column int
+	kind         yaml.Kind
+}
+
+// yamlEntry é uma entrada de um documento, mapa ou sequência. key é nil
+// fora dos mapas.
+type yamlEntry struct {
+	key, value *yaml.Node
+}
+
+// yamlText guarda onde fica cada nó no texto dos três lados, para que o
+// resultado do merge copie o texto original dos nós que nenhum lado alterou
+// e só os nós mesclados sejam escritos de novo.
+type yamlText struct {
+	indent  int
+	newline bool
+	spans   map[*yaml.Node]yamlSpan
+
+	// containers são os documentos, mapas e sequências de ours pela
+	// posição: os nós mesclados são cópias deles (withContent)
+	containers map[yamlPosition]*yaml.Node
+}
+
+// newYAMLText cria o índice com a indentação e a quebra de linha final de ours.
+func newYAMLText(ours string) *yamlText {
+	return &yamlText{
+		indent:     yamlIndent(ours),
+		newline:    strings.HasSuffix(ours, "\n"),
+		spans:      make(map[*yaml.Node]yamlSpan),
+		containers: make(map[yamlPosition]*yaml.Node),
+	}
+}
+
+// index registra os trechos das entradas dos documentos de um lado.
+func (t *yamlText) index(content string, docs []*yaml.Node, ours bool) {
+	lines := splitLines(content)
+	for i, doc := range docs {
+		// Cada documento, depois do primeiro, começa no seu ---
+		start, end := 0, len(lines)
+		if i > 0 {
+			start = doc.Line - 1
+		}
+		if i+1 < len(docs) {
+			end = docs[i+1].Line - 1
+		}
+
+		t.spans[doc] = yamlSpan{lines: lines, start: start, end: end}
+		t.indexNode(doc, lines, end, ours)
+	}
+}
+
+// indexNode registra os trechos das entradas de um nó, que termina antes da
+// linha end. Entradas de nós no estilo de fluxo, como {a: 1}, dividem as
+// linhas e não são registradas.
+func (t *yamlText) indexNode(n *yaml.Node, lines []string, end int, ours bool) {
+	entries := nodeEntries(n)
+	if entries == nil {
+		return
+	}
+	if ours {
+		t.containers[yamlPosition{n.Line, n.Column, n.Kind}] = n
+	}
+	if n.Style&yaml.FlowStyle != 0 {
+		return
+	}
+
+	for i, entry := range entries {
+		// As linhas em branco depois da última entrada ficam com o nó, que
+		// as mantém mesmo quando ela sai do resultado
+		next := end
+		if i+1 < len(entries) {
+			next = entryStart(lines, entries[i+1])
+		} else {
+			for next > entry.value.Line && strings.TrimSpace(lines[next-1]) == "" {
+				next--
+			}
+		}
+
+		t.spans[entry.value] = yamlSpan{lines: lines, start: entryStart(lines, entry), end: next}
+		t.indexNode(entry.value, lines, next, ours)
+	}
+}
+
+// nodeEntries retorna as entradas de um documento, mapa ou sequência; nil
+// para os outros nós.
+func nodeEntries(n *yaml.Node) []yamlEntry {
+	var entries []yamlEntry
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			entries = append(entries, yamlEntry{value: c})
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			entries = append(entries, yamlEntry{key: n.Content[i], value: n.Content[i+1]})
+		}
+	default:
+		return nil
+	}
+	return entries
+}
+
+// entryStart retorna a linha, a partir de zero, onde começa uma entrada: a
+// da chave ou do item, ou a do comentário logo acima dela, na mesma coluna
+// ou antes.
+func entryStart(lines []string, entry yamlEntry) int {
+	n := entry.value
+	if entry.key != nil {
+		n = entry.key
+	}
+
+	start := n.Line - 1
+	for start > 0 {
+		line := lines[start-1]
+		trimmed := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(trimmed, "#") || len(line)-len(trimmed) >= n.Column {
+			break
+		}
+		start--
+	}
+	return start
+}
+
+// render escreve os documentos do resultado do merge.
+func (t *yamlText) render(docs []*yaml.Node) (string, error) {
+	var lines []string
+	for i, doc := range docs {
+		docLines, err := t.entry(yamlEntry{value: doc}, false)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 && (len(docLines) == 0 || !strings.HasPrefix(docLines[0], "---")) {
+			lines = append(lines, "---")
+		}
+		lines = append(lines, docLines...)
+	}
+
+	text := joinLines(lines)
+	if t.newline && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text, nil
+}
+
+// entry retorna as linhas de uma entrada do resultado; item indica um item
+// de sequência. Um nó original é copiado do texto do seu lado. Um nó mesclado
+// é uma cópia de um nó de ours: tem as linhas de ours antes da primeira
+// entrada e depois da última, com as próprias entradas entre elas. O que não está no texto, como as
+// entradas de {a: 1}, é escrito de novo.
+func (t *yamlText) entry(entry yamlEntry, item bool) ([]string, error) {
+	n := entry.value
+	if span, ok := t.spans[n]; ok {
+		return append([]string(nil), span.lines[span.start:span.end]...), nil
+	}
+
+	original := t.containers[yamlPosition{n.Line, n.Column, n.Kind}]
+	if original == nil {
+		return t.encode(entry, item)
+	}
+	span, ok := t.spans[original]
+	originalEntries := nodeEntries(original)
+	if !ok || len(originalEntries) == 0 {
+		return t.encode(entry, item)
+	}
+	first, ok := t.spans[originalEntries[0].value]
+	last, lastOK := t.spans[originalEntries[len(originalEntries)-1].value]
+	if !ok || !lastOK {
+		return t.encode(entry, item)
+	}
+
+	lines := append([]string(nil), span.lines[span.start:first.start]...)
+	for i, e := range nodeEntries(n) {
+		entryLines, err := t.entry(e, n.Kind == yaml.SequenceNode)
+		if err != nil {
+			return nil, err
+		}
+		if item && n.Kind == yaml.MappingNode {
+			setItemDash(entryLines, n.Column-3, i == 0)
+		}
+		lines = append(lines, entryLines...)
+	}
+
+	return append(lines, span.lines[last.end:span.end]...), nil
+}
+
+// setItemDash acerta o traço das chaves de um item de sequência mesclado, que
+// podem vir de outra posição no item: só a primeira chave fica com "- " na
+// coluna dash.
+func setItemDash(lines []string, dash int, first bool) {
+	if dash < 0 {
+		return
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if len(line) < dash+2 || strings.TrimSpace(line[:dash]) != "" {
+			return
+		}
+
+		switch {
+		case first && line[dash:dash+2] == "  ":
+			lines[i] = line[:dash] + "- " + line[dash+2:]
+		case !first && line[dash:dash+2] == "- ":
+			lines[i] = line[:dash] + "  " + line[dash+2:]
+		}
+		return
+	}
+}
+
+// encode escreve de novo uma entrada, na coluna em que ela estava.
+func (t *yamlText) encode(entry yamlEntry, item bool) ([]string, error) {
+	node, column := entry.value, entry.value.Column-1
+	switch {
+	case entry.key != nil:
+		node = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{entry.key, entry.value}}
+		column = entry.key.Column - 1
+	case item:
+		node = &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{entry.value}}
+		column = entry.value.Column - 3
+	}
+
+	text, err := encodeYAML([]*yaml.Node{node}, t.indent)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := splitLines(strings.TrimSuffix(text, "\n"))
+	pad := strings.Repeat(" ", max(column, 0))
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return lines, nil
+}
+
+// yamlIndent retorna quantos espaços o documento usa por nível: os da
+// primeira linha indentada, ou dois.
+func yamlIndent(content string) int {
+	for _, line := range splitLines(content) {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if n := len(line) - len(trimmed); n > 0 {
+			return n
+		}
+	}
+	return 2
+}
+
+// sameYAMLDocuments informa se os documentos têm o mesmo conteúdo.
+func sameYAMLDocuments(a, b []*yaml.Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameYAML(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameYAML informa se dois nós têm o mesmo conteúdo; comentários, estilo e a
+// ordem das chaves dos mapas não importam. Dois nós ausentes são iguais.
+func sameYAML(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind {
+		return false
+	}
+
+	switch a.Kind {
+	case yaml.ScalarNode:
+		return a.ShortTag() == b.ShortTag() && a.Value == b.Value
+	case yaml.AliasNode:
+		return a.Value == b.Value
+	case yaml.MappingNode:
+		if len(a.Content) != len(b.Content) {
+			return false
+		}
+		for i := 0; i+1 < len(a.Content); i += 2 {
+			if !sameYAML(a.Content[i+1], yamlValue(b, a.Content[i].Value)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Documentos e sequências
+	if len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !sameYAML(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// yamlValue retorna o valor de uma chave do mapa, nil se não existir.
+func yamlValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mergeYAML faz o merge de um nó. Retorna o resultado em três versões, que
+// só diferem nos valores em conflito: nelas fica o valor de ours, da base e
+// de theirs, nessa ordem. Um valor ausente, como uma chave removida, é nil.
+func mergeYAML(base, ours, theirs *yaml.Node) (*yaml.Node, *yaml.Node, *yaml.Node) {
+	switch {
+	case sameYAML(ours, theirs), sameYAML(base, theirs):
+		return ours, ours, ours
+	case sameYAML(base, ours):
+		return theirs, theirs, theirs
+	case ours == nil || theirs == nil || ours.Kind != theirs.Kind || (base != nil && base.Kind != ours.Kind):
+		return ours, base, theirs
+	}
+
+	switch ours.Kind {
+	case yaml.DocumentNode:
+		if len(ours.Content) == 1 && len(theirs.Content) == 1 && (base == nil || len(base.Content) == 1) {
+			var b *yaml.Node
+			if base != nil {
+				b = base.Content[0]
+			}
+			o, mb, t := mergeYAML(b, ours.Content[0], theirs.Content[0])
+			return withContent(ours, o), withContent(ours, mb), withContent(ours, t)
+		}
+	case yaml.MappingNode:
+		return mergeYAMLMapping(base, ours, theirs)
+	case yaml.SequenceNode:
+		if key := identityKey(base, ours, theirs); key != "" {
+			return mergeYAMLSequence(key, base, ours, theirs)
+		}
+	}
+
+	return ours, base, theirs
+}
+
+// withContent copia o nó trocando o conteúdo pelos nós informados, sem os nil.
+func withContent(n *yaml.Node, content ...*yaml.Node) *yaml.Node {
+	node := *n
+	node.Content = nil
+	for _, c := range content {
+		if c != nil {
+			node.Content = append(node.Content, c)
+		}
+	}
+	return &node
+}
+
+// yamlEntries são as entradas de um mapa ou os itens de uma sequência,
+// identificados pela chave ou pelo valor da chave de identidade.
+type yamlEntries struct {
+	ids    []string
+	keys   map[string]*yaml.Node
+	values map[string]*yaml.Node
+}
+
+// mappingEntries lê as entradas de um mapa.
+func mappingEntries(n *yaml.Node) *yamlEntries {
+	entries := &yamlEntries{keys: make(map[string]*yaml.Node), values: make(map[string]*yaml.Node)}
+	if n == nil {
+		return entries
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		id := n.Content[i].Value
+		entries.ids = append(entries.ids, id)
+		entries.keys[id] = n.Content[i]
+		entries.values[id] = n.Content[i+1]
+	}
+	return entries
+}
+
+// sequenceEntries lê os itens de uma sequência pela chave de identidade.
+func sequenceEntries(n *yaml.Node, key string) *yamlEntries {
+	entries := &yamlEntries{values: make(map[string]*yaml.Node)}
+	if n == nil {
+		return entries
+	}
+	for _, item := range n.Content {
+		id := yamlValue(item, key).Value
+		entries.ids = append(entries.ids, id)
+		entries.values[id] = item
+	}
+	return entries
+}
+
+// mergedIDs retorna as entradas do resultado: as de ours na ordem de ours e
+// depois as que só theirs acrescentou, na ordem de theirs. Entradas que um
+// lado removeu e o outro não alterou saem do resultado.
+func mergedIDs(base, ours, theirs *yamlEntries) []string {
+	var ids []string
+	seen := make(map[string]bool)
+
+	for _, list := range [][]string{ours.ids, theirs.ids} {
+		for _, id := range list {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			b, o, t := base.values[id], ours.values[id], theirs.values[id]
+			if (o == nil && sameYAML(b, t)) || (t == nil && sameYAML(b, o)) {
+				continue
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// mergeYAMLMapping faz o merge de um mapa chave a chave.
+func mergeYAMLMapping(base, ours, theirs *yaml.Node) (*yaml.Node, *yaml.Node, *yaml.Node) {
+	b, o, t := mappingEntries(base), mappingEntries(ours), mappingEntries(theirs)
+
+	var mergedOurs, mergedBase, mergedTheirs []*yaml.Node
+	for _, id := range mergedIDs(b, o, t) {
+		key := o.keys[id]
+		if key == nil {
+			key = t.keys[id]
+		}
+
+		vo, vb, vt := mergeYAML(b.values[id], o.values[id], t.values[id])
+		if vo != nil {
+			mergedOurs = append(mergedOurs, key, vo)
+		}
+		if vb != nil {
+			mergedBase = append(mergedBase, key, vb)
+		}
+		if vt != nil {
+			mergedTheirs = append(mergedTheirs, key, vt)
+		}
+	}
+
+	return withContent(ours, mergedOurs...), withContent(ours, mergedBase...), withContent(ours, mergedTheirs...)
+}
+
+// mergeYAMLSequence faz o merge de uma sequência de mapas item a item, pela
+// chave de identidade.
+func mergeYAMLSequence(key string, base, ours, theirs *yaml.Node) (*yaml.Node, *yaml.Node, *yaml.Node) {
+	b, o, t := sequenceEntries(base, key), sequenceEntries(ours, key), sequenceEntries(theirs, key)
+
+	var mergedOurs, mergedBase, mergedTheirs []*yaml.Node
+	for _, id := range mergedIDs(b, o, t) {
+		vo, vb, vt := mergeYAML(b.values[id], o.values[id], t.values[id])
+		mergedOurs = append(mergedOurs, vo)
+		mergedBase = append(mergedBase, vb)
+		mergedTheirs = append(mergedTheirs, vt)
+	}
+
+	return withContent(ours, mergedOurs...), withContent(ours, mergedBase...), withContent(ours, mergedTheirs...)
+}
+
+// identityKey retorna a chave de identidade das sequências: a primeira das
+// yamlIdentityKeys presente, como escalar e sem repetição, em todos os itens
+// dos três lados. Vazio se nenhuma servir ou algum item não for um mapa.
+func identityKey(sequences ...*yaml.Node) string {
+	for _, key := range yamlIdentityKeys {
+		if identifies(key, sequences) {
+			return key
+		}
+	}
+	return ""
+}
+
+// identifies informa se a chave identifica os itens das sequências.
+func identifies(key string, sequences []*yaml.Node) bool {
+	for _, seq := range sequences {
+		if seq == nil {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, item := range seq.Content {
+			value := yamlValue(item, key)
+			if value == nil || value.Kind != yaml.ScalarNode || seen[value.Value] {
+				return false
+			}
+			seen[value.Value] = true
+		}
+	}
+	return true
+}
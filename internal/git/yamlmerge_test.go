@@ -0,0 +1,115 @@
+package git
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAMLMerge(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflicts          int
+		invalid            bool
+	}{
+		{
+			name:   "comentários e linhas em branco",
+			base:   "# config\na: 1\n\nb: 2\n",
+			ours:   "# config\na: 10\n\nb: 2\n",
+			theirs: "# config\na: 1\n\nb: 20 # novo\n",
+			want:   "# config\na: 10\n\nb: 20 # novo\n",
+		},
+		{
+			name:   "vários documentos",
+			base:   "a: 1\n---\nb: 1\n",
+			ours:   "a: 2\n---\nb: 1\n",
+			theirs: "a: 1\n---\n# doc 2\nb: 2\n",
+			want:   "a: 2\n---\n# doc 2\nb: 2\n",
+		},
+		{
+			name:   "itens de sequência pelo nome",
+			base:   "steps:\n  - name: build\n    run: make\n  - name: test\n    run: go test\n",
+			ours:   "steps:\n  - name: lint\n    run: vet\n  - name: build\n    run: make\n  - name: test\n    run: go test ./...\n",
+			theirs: "steps:\n  - name: build\n    run: make all\n  - name: test\n    run: go test\n",
+			want:   "steps:\n  - name: lint\n    run: vet\n  - name: build\n    run: make all\n  - name: test\n    run: go test ./...\n",
+		},
+		{
+			name:   "estilo flow",
+			base:   "a: [1, 2]\nb: 1\n",
+			ours:   "a: [1, 2]\nb: 2\n",
+			theirs: "a: [1, 3]\nb: 1\n",
+			want:   "a: [1, 3]\nb: 2\n",
+		},
+		{
+			name:      "mesma chave alterada nos dois lados",
+			base:      "a: 1\nb: 2\n",
+			ours:      "a: 5\nb: 2\n",
+			theirs:    "a: 6\nb: 2\n",
+			want:      "<<<<<<< ours\na: 5\n=======\na: 6\n>>>>>>> theirs\nb: 2\n",
+			conflicts: 1,
+		},
+		{
+			name:      "YAML inválido usa o merge de texto",
+			base:      "a: 1\n",
+			ours:      "a: [1\n",
+			theirs:    "a: 2\n",
+			want:      "<<<<<<< ours\na: [1\n=======\na: 2\n>>>>>>> theirs\n",
+			conflicts: 1,
+			invalid:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := driverMerge(t, yamlMerge, "x.yaml", tt.base, tt.ours, tt.theirs)
+			if got := merge.Render(ConflictMerge); got != tt.want {
+				t.Errorf("Render =\n%s\nesperado\n%s", got, tt.want)
+			}
+			if merge.Conflicts != tt.conflicts {
+				t.Errorf("conflitos = %d, esperado %d", merge.Conflicts, tt.conflicts)
+			}
+			if tt.invalid {
+				return
+			}
+
+			// Qualquer lado escolhido nos blocos resulta em YAML válido
+			for _, resolution := range []string{ResolveOurs, ResolveTheirs} {
+				content := resolveAll(t, merge, resolution)
+				if _, err := parseYAML(content); err != nil {
+					t.Errorf("resolução %s não é YAML válido: %v\n%s", resolution, err, content)
+				}
+			}
+		})
+	}
+}
+
+func TestSameYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"comentários e espaços", "a: 1 # um\nb:   2\n", "a: 1\nb: 2\n", true},
+		{"ordem das chaves", "a: 1\nb: 2\n", "b: 2\na: 1\n", true},
+		{"estilo flow", "a: [1, 2]\n", "a:\n  - 1\n  - 2\n", true},
+		{"valor diferente", "a: 1\n", "a: 2\n", false},
+		{"ordem da sequência", "a: [1, 2]\n", "a: [2, 1]\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a, b yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.a), &a); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.b), &b); err != nil {
+				t.Fatal(err)
+			}
+			if same := sameYAML(&a, &b); same != tt.same {
+				t.Fatalf("sameYAML = %v, esperado %v", same, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gitmerge/internal/git"
+)
+
+// Session guarda o estado de quem está fazendo um merge: o repositório aberto,
+// as branches selecionadas e os arquivos já resolvidos que aguardam o commit.
+type Session struct {
+	ID string
+
+	mutex       sync.Mutex
+	dir         string
+	control     *git.Control
+	baseBranch  string
+	yourBranch  string
+	resolutions map[string]string
+	submodules  map[string]string
+	deletions   map[string]bool
+	modes       map[string]string
+	merges      map[string]fileMerge
+	lastAccess  time.Time
+}
+
+// fileMerge é o merge de um arquivo em resolução, com a chave da comparação
+// (branches e modo) que o gerou.
+type fileMerge struct {
+	key   string
+	merge *git.FileMerge
+}
+
+// Info é o resumo de uma sessão exposto pela API.
+type Info struct {
+	ID         string    `json:"id"`
+	Dir        string    `json:"dir"`
+	BaseBranch string    `json:"baseBranch"`
+	YourBranch string    `json:"yourBranch"`
+	Pending    []string  `json:"pending"`
+	Deleted    []string  `json:"deleted"`
+	LastAccess time.Time `json:"lastAccess"`
+}
+
+// Open abre o repositório local da sessão. Abrir outro repositório descarta as
+// branches selecionadas e as resoluções pendentes do anterior.
+func (s *Session) Open(dir string) error {
+	control := new(git.Control)
+	control.Init()
+
+	if err := control.NewRepoLocal(dir); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.dir = dir
+	s.control = control
+	s.baseBranch = ""
+	s.yourBranch = ""
+	s.resolutions = make(map[string]string)
+	s.submodules = make(map[string]string)
+	s.deletions = make(map[string]bool)
+	s.modes = make(map[string]string)
+	s.merges = make(map[string]fileMerge)
+
+	return nil
+}
+
+// Control retorna o controle do repositório da sessão, ou nil se nenhum
+// repositório foi aberto.
+func (s *Session) Control() *git.Control {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.control == nil || !s.control.IsInitialized() {
+		return nil
+	}
+	return s.control
+}
+
+// SelectBranches registra as branches que estão sendo comparadas.
+func (s *Session) SelectBranches(baseBranch, yourBranch string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.baseBranch = baseBranch
+	s.yourBranch = yourBranch
+}
+
+// FileMerge retorna o merge em resolução de um arquivo. key identifica a
+// comparação (branches e modo); um merge guardado com outra chave é descartado
+// e load é chamado para gerar um novo. load roda fora do lock da sessão.
+// O merge retornado não deve ser alterado; use UpdateFileMerge.
+func (s *Session) FileMerge(file, key string, load func() (*git.FileMerge, error)) (*git.FileMerge, error) {
+	s.mutex.Lock()
+	previous, cached := s.merges[file]
+	s.mutex.Unlock()
+
+	if cached && previous.key == key {
+		return previous.merge, nil
+	}
+
+	merge, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.merges[file]
+	switch {
+	case ok && current.key == key:
+		// Outra requisição gerou o mesmo merge enquanto load rodava
+		return current.merge, nil
+	case ok != cached || current.merge != previous.merge:
+		// Outra comparação tomou o lugar do merge: o resultado não é guardado
+		return merge, nil
+	}
+
+	if s.merges == nil {
+		s.merges = make(map[string]fileMerge)
+	}
+	s.merges[file] = fileMerge{key: key, merge: merge}
+
+	return merge, nil
+}
+
+// UpdateFileMerge aplica update a uma cópia do merge em resolução de um
+// arquivo, obtido como em FileMerge, e guarda a cópia no lugar dele. update
+// roda com o lock da sessão, então as alterações de requisições simultâneas
+// não se perdem. Retorna a cópia, que não é mais alterada.
+func (s *Session) UpdateFileMerge(file, key string, load func() (*git.FileMerge, error), update func(*git.FileMerge)) (*git.FileMerge, error) {
+	merge, err := s.FileMerge(file, key, load)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.merges[file]
+	if ok && current.key == key {
+		merge = current.merge
+	}
+
+	updated := merge.Clone()
+	update(updated)
+
+	// Só substitui o merge da mesma comparação, ou a falta dele
+	if !ok || current.key == key {
+		if s.merges == nil {
+			s.merges = make(map[string]fileMerge)
+		}
+		s.merges[file] = fileMerge{key: key, merge: updated}
+	}
+
+	return updated, nil
+}
+
+// SaveResolution guarda o conteúdo resolvido de um arquivo e retorna quantos
+// arquivos aguardam o commit. Desfaz a remoção do arquivo, se houver.
+func (s *Session) SaveResolution(file, content string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.resolutions == nil {
+		s.resolutions = make(map[string]string)
+	}
+	s.resolutions[file] = content
+	delete(s.submodules, file)
+	delete(s.deletions, file)
+
+	return s.waiting()
+}
+
+// SaveSubmodule guarda o commit escolhido para um submódulo e retorna quantos
+// arquivos aguardam o commit. Desfaz o conteúdo resolvido ou a remoção do
+// caminho, se houver.
+func (s *Session) SaveSubmodule(file, commit string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.submodules == nil {
+		s.submodules = make(map[string]string)
+	}
+	s.submodules[file] = commit
+	delete(s.resolutions, file)
+	delete(s.deletions, file)
+	delete(s.modes, file)
+
+	return s.waiting()
+}
+
+// SaveDeletion marca um arquivo para sair do resultado no commit, como um
+// conflito de árvore resolvido com delete, e retorna quantos arquivos
+// aguardam o commit. Descarta o conteúdo resolvido do arquivo, se houver.
+func (s *Session) SaveDeletion(file string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.deletions == nil {
+		s.deletions = make(map[string]bool)
+	}
+	s.deletions[file] = true
+	delete(s.resolutions, file)
+	delete(s.submodules, file)
+	delete(s.modes, file)
+
+	return s.waiting()
+}
+
+// SaveMode guarda o modo escolhido para um arquivo com modos diferentes nos
+// dois lados. O modo vale junto com o conteúdo do arquivo, salvo à parte.
+func (s *Session) SaveMode(file, mode string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.modes == nil {
+		s.modes = make(map[string]string)
+	}
+	s.modes[file] = mode
+	delete(s.deletions, file)
+}
+
+// waiting retorna quantos arquivos aguardam o commit.
+func (s *Session) waiting() int {
+	return len(s.resolutions) + len(s.submodules) + len(s.deletions)
+}
+
+// Submodules retorna uma cópia dos commits escolhidos para os submódulos.
+func (s *Session) Submodules() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	commits := make(map[string]string, len(s.submodules))
+	for name, commit := range s.submodules {
+		commits[name] = commit
+	}
+	return commits
+}
+
+// Modes retorna uma cópia dos modos escolhidos para os arquivos.
+func (s *Session) Modes() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	modes := make(map[string]string, len(s.modes))
+	for name, mode := range s.modes {
+		modes[name] = mode
+	}
+	return modes
+}
+
+// Deletions retorna os arquivos marcados para sair do resultado no commit.
+func (s *Session) Deletions() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files := make([]string, 0, len(s.deletions))
+	for name := range s.deletions {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Resolutions retorna uma cópia dos arquivos resolvidos que aguardam o commit.
+func (s *Session) Resolutions() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files := make(map[string]string, len(s.resolutions))
+	for name, content := range s.resolutions {
+		files[name] = content
+	}
+	return files
+}
+
+// ForgetResolutions remove as resoluções já gravadas no commit de opts.
+// Arquivos salvos de novo depois da cópia usada no commit são mantidos. Os
+// merges em resolução são descartados, porque a branch alvo avançou.
+func (s *Session) ForgetResolutions(committed git.MergeCommitOptions) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.merges = make(map[string]fileMerge)
+
+	for name, content := range committed.Files {
+		if s.resolutions[name] == content {
+			delete(s.resolutions, name)
+		}
+	}
+	for name, commit := range committed.Submodules {
+		if s.submodules[name] == commit {
+			delete(s.submodules, name)
+		}
+	}
+	for name, mode := range committed.Modes {
+		if s.modes[name] == mode {
+			delete(s.modes, name)
+		}
+	}
+	for _, name := range committed.Deleted {
+		delete(s.deletions, name)
+	}
+}
+
+// Info retorna o resumo da sessão.
+func (s *Session) Info() Info {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	info := Info{
+		ID:         s.ID,
+		Dir:        s.dir,
+		BaseBranch: s.baseBranch,
+		YourBranch: s.yourBranch,
+		Pending:    []string{},
+		Deleted:    []string{},
+		LastAccess: s.lastAccess,
+	}
+	for name := range s.resolutions {
+		info.Pending = append(info.Pending, name)
+	}
+	for name := range s.submodules {
+		info.Pending = append(info.Pending, name)
+	}
+	sort.Strings(info.Pending)
+
+	for name := range s.deletions {
+		info.Deleted = append(info.Deleted, name)
+	}
+	sort.Strings(info.Deleted)
+
+	return info
+}
+
+func (s *Session) touch(now time.Time) {
+	s.mutex.Lock()
+	s.lastAccess = now
+	s.mutex.Unlock()
+}
+
+func (s *Session) idleSince() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastAccess
+}
+
+// Registry mantém as sessões ativas, indexadas pelo id, e descarta as que
+// ficam ociosas por mais tempo que o ttl.
+type Registry struct {
+	mutex    sync.Mutex
+	sessions map[string]*Session
+	ttl      time.Duration
+}
+
+// NewRegistry cria um registro de sessões que expiram após ttl sem uso.
+func NewRegistry(ttl time.Duration) *Registry {
+	return &Registry{
+		sessions: make(map[string]*Session),
+		ttl:      ttl,
+	}
+}
+
+// Get retorna a sessão do id informado e atualiza seu último acesso.
+func (r *Registry) Get(id string) (*Session, bool) {
+	r.mutex.Lock()
+	s, ok := r.sessions[id]
+	r.mutex.Unlock()
+
+	if ok {
+		s.touch(time.Now())
+	}
+	return s, ok
+}
+
+// Create cria uma sessão nova com um id aleatório. Os ids são sempre gerados
+// aqui; quem chama não escolhe o id de uma sessão.
+func (r *Registry) Create() (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{ID: id, resolutions: make(map[string]string)}
+	s.touch(time.Now())
+
+	r.mutex.Lock()
+	r.sessions[id] = s
+	r.mutex.Unlock()
+
+	return s, nil
+}
+
+// Delete encerra uma sessão.
+func (r *Registry) Delete(id string) {
+	r.mutex.Lock()
+	delete(r.sessions, id)
+	r.mutex.Unlock()
+}
+
+// Expire remove as sessões ociosas há mais tempo que o ttl e retorna quantas
+// foram removidas.
+func (r *Registry) Expire() int {
+	limit := time.Now().Add(-r.ttl)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	removed := 0
+	for id, s := range r.sessions {
+		if s.idleSince().Before(limit) {
+			delete(r.sessions, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// StartExpiration executa Expire periodicamente até stop ser fechado.
+func (r *Registry) StartExpiration(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.Expire()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// newID gera um id de sessão aleatório.
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("erro ao gerar id de sessão: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
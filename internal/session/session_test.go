@@ -0,0 +1,224 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+
+	"gitmerge/internal/git"
+)
+
+func TestCreate(t *testing.T) {
+	registry := NewRegistry(time.Hour)
+
+	created, err := registry.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(created.ID) != 32 {
+		t.Fatalf("id gerado = %q, esperado 32 caracteres hexadecimais", created.ID)
+	}
+
+	other, err := registry.Create()
+	if err != nil || other.ID == created.ID {
+		t.Fatalf("Create = %q, %v; esperado um id diferente de %q", other.ID, err, created.ID)
+	}
+
+	if got, ok := registry.Get(created.ID); !ok || got != created {
+		t.Fatalf("Get(%q) = %p, %v", created.ID, got, ok)
+	}
+	if _, ok := registry.Get("outra"); ok {
+		t.Fatalf("Get encontrou uma sessão inexistente")
+	}
+
+	registry.Delete(created.ID)
+	if _, ok := registry.Get(created.ID); ok {
+		t.Fatalf("sessão encontrada depois de Delete")
+	}
+}
+
+func TestExpire(t *testing.T) {
+	ttl := time.Minute
+	registry := NewRegistry(ttl)
+
+	idle, _ := registry.Create()
+	active, _ := registry.Create()
+	idle.touch(time.Now().Add(-2 * ttl))
+
+	if removed := registry.Expire(); removed != 1 {
+		t.Fatalf("Expire removeu %d sessões, esperado 1", removed)
+	}
+	if _, ok := registry.Get(idle.ID); ok {
+		t.Fatalf("a sessão ociosa não expirou")
+	}
+	if _, ok := registry.Get(active.ID); !ok {
+		t.Fatalf("a sessão ativa expirou")
+	}
+}
+
+func TestResolutions(t *testing.T) {
+	s := &Session{}
+
+	if pending := s.SaveResolution("b.txt", "b\n"); pending != 1 {
+		t.Fatalf("SaveResolution = %d, esperado 1", pending)
+	}
+	if pending := s.SaveResolution("a.txt", "a\n"); pending != 2 {
+		t.Fatalf("SaveResolution = %d, esperado 2", pending)
+	}
+
+	committed := s.Resolutions()
+	committed["c.txt"] = "não salvo\n"
+
+	// Uma remoção desfaz o conteúdo salvo e um conteúdo salvo desfaz a remoção
+	if pending := s.SaveDeletion("b.txt"); pending != 2 {
+		t.Fatalf("SaveDeletion = %d, esperado 2", pending)
+	}
+	s.SaveDeletion("c.txt")
+	s.SaveResolution("c.txt", "c\n")
+	s.SaveDeletion("d.txt")
+	if deleted := s.Deletions(); !reflect.DeepEqual(deleted, []string{"b.txt", "d.txt"}) {
+		t.Fatalf("Deletions = %v", deleted)
+	}
+
+	// Um submódulo conta como arquivo pendente até o commit
+	if pending := s.SaveSubmodule("sub", "abc"); pending != 5 {
+		t.Fatalf("SaveSubmodule = %d, esperado 5", pending)
+	}
+	s.SaveSubmodule("d.txt", "def")
+
+	// a.txt foi salvo de novo depois da cópia usada no commit
+	s.SaveResolution("a.txt", "a2\n")
+	s.ForgetResolutions(git.MergeCommitOptions{
+		Files:      committed,
+		Submodules: map[string]string{"sub": "abc", "d.txt": "outro"},
+		Deleted:    []string{"b.txt"},
+	})
+
+	// c.txt foi salvo com outro conteúdo e também fica
+	if want := map[string]string{"a.txt": "a2\n", "c.txt": "c\n"}; !reflect.DeepEqual(s.Resolutions(), want) {
+		t.Fatalf("Resolutions = %v, esperado %v", s.Resolutions(), want)
+	}
+	if info := s.Info(); !reflect.DeepEqual(info.Pending, []string{"a.txt", "c.txt", "d.txt"}) || len(info.Deleted) != 0 {
+		t.Fatalf("Pending = %v, Deleted = %v", info.Pending, info.Deleted)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := gogit.PlainInit(dir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Session{}
+	if s.Control() != nil {
+		t.Fatalf("Control sem repositório aberto")
+	}
+
+	s.SelectBranches("main", "feature")
+	s.SaveResolution("a.txt", "a\n")
+
+	if err := s.Open(dir); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if s.Control() == nil {
+		t.Fatalf("Control nil depois de Open")
+	}
+
+	info := s.Info()
+	if info.Dir != dir || info.BaseBranch != "" || info.YourBranch != "" || len(info.Pending) != 0 {
+		t.Fatalf("Info depois de Open = %+v", info)
+	}
+
+	if err := s.Open(t.TempDir()); err == nil {
+		t.Fatalf("Open aceitou um diretório sem repositório")
+	}
+}
+
+func TestFileMerge(t *testing.T) {
+	s := &Session{}
+
+	loads := 0
+	load := func() (*git.FileMerge, error) {
+		loads++
+		return &git.FileMerge{Path: "a.txt"}, nil
+	}
+
+	first, err := s.FileMerge("a.txt", "main feature", load)
+	if err != nil {
+		t.Fatalf("FileMerge: %v", err)
+	}
+	if again, _ := s.FileMerge("a.txt", "main feature", load); again != first || loads != 1 {
+		t.Fatalf("a mesma chave gerou um novo merge (%d cargas)", loads)
+	}
+	if other, _ := s.FileMerge("a.txt", "main outra", load); other == first || loads != 2 {
+		t.Fatalf("outra chave reutilizou o merge guardado (%d cargas)", loads)
+	}
+
+	// Depois do commit, a branch alvo avançou e o merge guardado é descartado
+	s.ForgetResolutions(git.MergeCommitOptions{})
+	if again, _ := s.FileMerge("a.txt", "main outra", load); loads != 3 {
+		t.Fatalf("merge reutilizado depois do commit: %+v (%d cargas)", again, loads)
+	}
+
+	failed := errors.New("falha")
+	if _, err := s.FileMerge("b.txt", "main feature", func() (*git.FileMerge, error) { return nil, failed }); err != failed {
+		t.Fatalf("FileMerge = %v, esperado o erro de load", err)
+	}
+}
+
+func TestUpdateFileMerge(t *testing.T) {
+	s := &Session{}
+
+	// load roda fora do lock: chamar a sessão dentro dele não trava
+	load := func() (*git.FileMerge, error) {
+		s.Info()
+		return &git.FileMerge{Path: "a.txt"}, nil
+	}
+
+	original, err := s.FileMerge("a.txt", "main feature", load)
+	if err != nil {
+		t.Fatalf("FileMerge: %v", err)
+	}
+
+	// Requisições simultâneas: nenhuma alteração se perde
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.UpdateFileMerge("a.txt", "main feature", load, func(m *git.FileMerge) { m.Conflicts++ })
+			if err != nil {
+				t.Errorf("UpdateFileMerge: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if original.Conflicts != 0 {
+		t.Fatalf("o merge original foi alterado: %d", original.Conflicts)
+	}
+	current, _ := s.FileMerge("a.txt", "main feature", load)
+	if current == original || current.Conflicts != 20 {
+		t.Fatalf("merge guardado com %d alterações, esperado 20", current.Conflicts)
+	}
+
+	// Outra comparação gera um merge novo e toma o lugar do guardado
+	updated, err := s.UpdateFileMerge("a.txt", "main outra", load, func(m *git.FileMerge) { m.Conflicts = -1 })
+	if err != nil || updated.Conflicts != -1 {
+		t.Fatalf("UpdateFileMerge = %+v, %v", updated, err)
+	}
+	if again, _ := s.FileMerge("a.txt", "main outra", load); again != updated {
+		t.Fatalf("o merge atualizado não foi guardado")
+	}
+
+	failed := errors.New("falha")
+	if _, err := s.UpdateFileMerge("b.txt", "main feature", func() (*git.FileMerge, error) { return nil, failed }, func(*git.FileMerge) {
+		t.Fatalf("update chamado depois do erro de load")
+	}); err != failed {
+		t.Fatalf("UpdateFileMerge = %v, esperado o erro de load", err)
+	}
+}